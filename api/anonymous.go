@@ -1,67 +1,80 @@
 package api
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/supplyon/gremcos/interfaces"
-	"reflect"
 )
 
 // WithinInt adds .within([<value_1>,<value_1>,..,<value_n>]), to the query. Where values are of type int.
 func WithinInt(values ...int) interfaces.QueryBuilder {
-	return multiParamQueryInt("within", values...)
+	if len(values) == 0 {
+		return NewSimpleQB("within()")
+	}
+
+	literals := make([]string, 0, len(values))
+	for _, value := range values {
+		literals = append(literals, fmt.Sprintf("%d", value))
+	}
+	return NewSimpleQB("within(%s)", bindValue(values, strings.Join(literals, ",")))
 }
 
 // Within adds .within([<value_1>,<value_1>,..,<value_n>]), to the query. Where values are of type string.
 func Within(values ...string) interfaces.QueryBuilder {
-	return multiParamQuery("within", values...)
+	if len(values) == 0 {
+		return NewSimpleQB("within()")
+	}
+
+	escaped := make([]string, 0, len(values))
+	literals := make([]string, 0, len(values))
+	for _, value := range values {
+		escapedValue := Escape(value)
+		escaped = append(escaped, escapedValue)
+		literals = append(literals, fmt.Sprintf("\"%s\"", escapedValue))
+	}
+	return NewSimpleQB("within(%s)", bindValue(escaped, strings.Join(literals, ",")))
 }
 
 // Eq adds .eq(<T>) to the query. (equal)
 func Eq[T any](v T) interfaces.QueryBuilder {
-	if t := reflect.TypeOf(v).String(); t == "string" {
-		return NewSimpleQB("eq(\"%v\")", v)
-	}
-	return NewSimpleQB("eq(%v)", v)
+	return predicate("eq", v)
 }
 
 // Neq adds .neq(<T>) to the query. (not equal)
 func Neq[T Ordered](v T) interfaces.QueryBuilder {
-	if t := reflect.TypeOf(v).String(); t == "string" {
-		return NewSimpleQB("neq(\"%v\")", v)
-	}
-	return NewSimpleQB("neq(%v)", v)
+	return predicate("neq", v)
 }
 
 // Lt adds .lt(<T>) to the query. (less than)
 func Lt[T Ordered](v T) interfaces.QueryBuilder {
-	if t := reflect.TypeOf(v).String(); t == "string" {
-		return NewSimpleQB("lt(\"%v\")", v)
-	}
-	return NewSimpleQB("lt(%v)", v)
+	return predicate("lt", v)
 }
 
 // Lte adds .lte(<T>) to the query. (less than equal)
 func Lte[T Ordered](v T) interfaces.QueryBuilder {
-	if t := reflect.TypeOf(v).String(); t == "string" {
-		return NewSimpleQB("lte(\"%v\")", v)
-	}
-	return NewSimpleQB("lte(%v)", v)
+	return predicate("lte", v)
 }
 
 // Gt adds .gt(<T>) to the query. (greater than)
 func Gt[T Ordered](v T) interfaces.QueryBuilder {
-	if t := reflect.TypeOf(v).String(); t == "string" {
-		return NewSimpleQB("gt(\"%v\")", v)
-	}
-	return NewSimpleQB("gt(%v)", v)
+	return predicate("gt", v)
 }
 
 // Gte adds .gte(<T>) to the query. (greater than equal)
 func Gte[T Ordered](v T) interfaces.QueryBuilder {
+	return predicate("gte", v)
+}
+
+// predicate creates the predicate with the given name, e.g. eq("abc") or gt(123).
+// Strings are escaped and quoted, all other values are inlined as they are.
+func predicate(name string, v interface{}) interfaces.QueryBuilder {
 	if t := reflect.TypeOf(v).String(); t == "string" {
-		return NewSimpleQB("gte(\"%v\")", v)
+		return NewSimpleQB("%s(%s)", name, bindString(v.(string)))
 	}
-	return NewSimpleQB("gte(%v)", v)
+	return NewSimpleQB("%s(%s)", name, bindValue(v, fmt.Sprintf("%v", v)))
 }
 
 // InE adds .inE([<label_1>,<label_2>,..,<label_n>]), to the query. The query call returns all incoming edges of the Vertex
@@ -128,7 +141,7 @@ func Has(key string, value ...interface{}) interfaces.QueryBuilder {
 		return NewSimpleQB("__.has(\"%s\")", key)
 	}
 
-	keyVal, err := toKeyValueString(key, value[0])
+	keyVal, err := toKeyValue(key, value[0])
 	if err != nil {
 		panic(errors.Wrapf(err, "cast has value %T to string failed (You could either implement the Stringer interface for this type or cast it to string beforehand)", value))
 	}
@@ -25,6 +25,16 @@ func TestAnonymousWithin(t *testing.T) {
 	assert.Equal(t, `within("a","b","c")`, e3.String())
 }
 
+func TestAnonymousWithinEscaped(t *testing.T) {
+	// GIVEN
+
+	// WHEN
+	result := Within(`a"b`, "c")
+
+	// THEN
+	assert.Equal(t, `within("a%22b","c")`, result.String())
+}
+
 func TestAnonymousWithinInt(t *testing.T) {
 	// GIVEN
 
@@ -56,6 +66,10 @@ func TestAnonymousEq(t *testing.T) {
 	stringInput := Eq("abc")
 	assert.NotNil(t, stringInput)
 	assert.Equal(t, `eq("abc")`, stringInput.String())
+
+	escapedInput := Eq(`a"),drop("`)
+	assert.NotNil(t, escapedInput)
+	assert.Equal(t, `eq("a%22%29%2Cdrop%28%22")`, escapedInput.String())
 }
 
 func TestAnonymousNeq(t *testing.T) {
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// bindingPrefix is the prefix of the generated binding names (_b0, _b1, ...)
+const bindingPrefix = "_b"
+
+// IsGeneratedBinding returns true in case the given name was generated for a value while rendering a query with bindings (e.g. _b0).
+// Generated bindings carry the values the query builders would otherwise inline, e.g. the value of a property.
+func IsGeneratedBinding(name string) bool {
+	index := strings.TrimPrefix(name, bindingPrefix)
+	if len(index) == 0 || len(index) == len(name) {
+		return false
+	}
+	for _, digit := range index {
+		if digit < '0' || digit > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// queryBindings collects the values that are bound while rendering a query
+type queryBindings struct {
+	values map[string]interface{}
+}
+
+// add adds the given value to the bindings and returns the generated name of the binding
+func (b *queryBindings) add(value interface{}) string {
+	name := fmt.Sprintf("%s%d", bindingPrefix, len(b.values))
+	b.values[name] = value
+	return name
+}
+
+// bindable is implemented by all query builders that are able to render their query
+// with bindings instead of inlining the values.
+type bindable interface {
+	bindTo(bindings *queryBindings) string
+}
+
+// boundValue is a value of a query. Per default it is inlined into the query,
+// but it can be replaced by a binding as well.
+type boundValue struct {
+	// value is the value that is bound
+	value interface{}
+	// literal is the representation of the value when it is inlined into the query
+	literal string
+}
+
+// bindValue creates a value that is inlined as the given literal or bound as the given value
+func bindValue(value interface{}, literal string) *boundValue {
+	return &boundValue{value: value, literal: literal}
+}
+
+// bindString creates a string value that is inlined (escaped and quoted) or bound (escaped).
+// The value is escaped in both cases to ensure that the same data is stored independent of the rendering.
+func bindString(value string) *boundValue {
+	escaped := Escape(value)
+	return bindValue(escaped, fmt.Sprintf("\"%s\"", escaped))
+}
+
+// bindQuoted creates a string value that is inlined (quoted) or bound as given, e.g. an id, a label or a property key.
+// In contrast to bindString the value is not escaped, hence ids, labels and keys are kept as given.
+func bindQuoted(value string) *boundValue {
+	return bindValue(value, fmt.Sprintf("\"%s\"", value))
+}
+
+func (bv *boundValue) String() string {
+	return bv.literal
+}
+
+func (bv *boundValue) bindTo(bindings *queryBindings) string {
+	return bindings.add(bv.value)
+}
+
+// bindTo renders the given query builder with bindings if supported, otherwise the values are inlined
+func bindTo(builder interfaces.QueryBuilder, bindings *queryBindings) string {
+	if bindableBuilder, ok := builder.(bindable); ok {
+		return bindableBuilder.bindTo(bindings)
+	}
+	return builder.String()
+}
+
+// bindAllTo renders the given query builders with bindings and concatenates the results
+func bindAllTo(builders []interfaces.QueryBuilder, bindings *queryBindings) string {
+	queryString := ""
+	for _, queryBuilder := range builders {
+		queryString += bindTo(queryBuilder, bindings)
+	}
+	return queryString
+}
+
+// stringWithBindings renders the given query builder with bindings and returns the query together with the bindings
+func stringWithBindings(builder bindable) (string, map[string]interface{}) {
+	bindings := &queryBindings{values: make(map[string]interface{})}
+	query := builder.bindTo(bindings)
+	return query, bindings.values
+}
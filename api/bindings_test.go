@@ -0,0 +1,138 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+func TestStringWithBindingsVertex(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g")
+	query := g.V().HasLabel("user").Has("name", "Max").Has("age", Gt(18)).Has("active", true).Limit(10)
+
+	// WHEN
+	queryStr, bindings := query.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+
+	// THEN
+	assert.Equal(t, `g.V().hasLabel("user").has(_b0,_b1).has(_b2,gt(_b3)).has(_b4,_b5).limit(10)`, queryStr)
+	assert.Equal(t, map[string]interface{}{"_b0": "name", "_b1": "Max", "_b2": "age", "_b3": 18, "_b4": "active", "_b5": true}, bindings)
+	assert.Equal(t, `g.V().hasLabel("user").has("name","Max").has("age",gt(18)).has("active",true).limit(10)`, query.String())
+}
+
+func TestStringWithBindingsNested(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g")
+	id := uuid.Must(uuid.FromString("8fff9259-09e6-4ea5-aaf8-250b31cc7f44"))
+	query := g.VByUUID(id).AddE("knows").To(g.VByStr("abc")).Property("since", 2015)
+
+	// WHEN
+	queryStr, bindings := query.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+
+	// THEN
+	assert.Equal(t, `g.V(_b0).addE(_b1).to(g.V(_b2)).property(_b3,_b4)`, queryStr)
+	assert.Equal(t, map[string]interface{}{"_b0": id.String(), "_b1": "knows", "_b2": "abc", "_b3": "since", "_b4": 2015}, bindings)
+}
+
+func TestStringWithBindingsAddV(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g")
+	query := g.AddV("user").Property("name", "Max")
+
+	// WHEN
+	queryStr, bindings := query.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+
+	// THEN
+	assert.Equal(t, `g.addV(_b0).property(_b1,_b2)`, queryStr)
+	assert.Equal(t, map[string]interface{}{"_b0": "user", "_b1": "name", "_b2": "Max"}, bindings)
+	assert.Equal(t, `g.addV("user").property("name","Max")`, query.String())
+}
+
+func TestStringWithBindingsIdsAreNotEscaped(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g")
+	id := "user$1 2"
+	vertex := g.VByStr(id)
+	vertexByID := g.V().HasId(id)
+	edge := g.E().HasId(id)
+
+	// WHEN
+	vertexStr, vertexBindings := vertex.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+	vertexByIDStr, vertexByIDBindings := vertexByID.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+	edgeStr, edgeBindings := edge.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+
+	// THEN
+	assert.Equal(t, `g.V("user$1 2")`, vertex.String())
+	assert.Equal(t, `g.V().hasId("user$1 2")`, vertexByID.String())
+	assert.Equal(t, `g.E().hasId("user$1 2")`, edge.String())
+	assert.Equal(t, `g.V(_b0)`, vertexStr)
+	assert.Equal(t, `g.V().hasId(_b0)`, vertexByIDStr)
+	assert.Equal(t, `g.E().hasId(_b0)`, edgeStr)
+	for _, bindings := range []map[string]interface{}{vertexBindings, vertexByIDBindings, edgeBindings} {
+		assert.Equal(t, map[string]interface{}{"_b0": id}, bindings)
+	}
+}
+
+func TestStringWithBindingsEscaping(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g")
+	injection := `x").drop().V().has("name","y`
+	query := g.V().Has("name", injection).Has("status", Within("a$b", "c")).Property("created", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	// WHEN
+	queryStr, bindings := query.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+
+	// THEN
+	assert.Equal(t, `g.V().has(_b0,_b1).has(_b2,within(_b3)).property(_b4,_b5)`, queryStr)
+	require.Len(t, bindings, 6)
+	assert.Equal(t, Escape(injection), bindings["_b1"])
+	assert.Equal(t, []string{"a%24b", "c"}, bindings["_b3"])
+	assert.Equal(t, "2020-01-02 03:04:05 +0000 UTC", bindings["_b5"])
+	assert.Equal(t, `g.V().has("name","`+Escape(injection)+`").has("status",within("a%24b","c")).property("created","2020-01-02 03:04:05 +0000 UTC")`, query.String())
+}
+
+func TestStringWithBindingsWithoutValues(t *testing.T) {
+	// GIVEN
+	query := NewSimpleQB("g.V().hasLabel(\"user\").count()")
+
+	// WHEN
+	queryStr, bindings := query.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+
+	// THEN
+	assert.Equal(t, `g.V().hasLabel("user").count()`, queryStr)
+	assert.Empty(t, bindings)
+}
+
+func TestStringWithBindingsEdgeAndProperty(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g")
+	edge := g.E().HasId("e1").Has("weight", 0.5)
+	properties := g.V().PropertyList("tags", "go").Properties("tags")
+	values := g.VBy(42).ValuesBy("name").Fold()
+
+	// WHEN
+	edgeStr, edgeBindings := edge.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+	propertiesStr, propertiesBindings := properties.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+	valuesStr, valuesBindings := values.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+
+	// THEN
+	assert.Equal(t, `g.E().hasId(_b0).has(_b1,_b2)`, edgeStr)
+	assert.Equal(t, map[string]interface{}{"_b0": "e1", "_b1": "weight", "_b2": 0.5}, edgeBindings)
+	assert.Equal(t, `g.V().property(list,_b0,_b1).properties("tags")`, propertiesStr)
+	assert.Equal(t, map[string]interface{}{"_b0": "tags", "_b1": "go"}, propertiesBindings)
+	assert.Equal(t, `g.V(_b0).values("name").fold()`, valuesStr)
+	assert.Equal(t, map[string]interface{}{"_b0": "42"}, valuesBindings)
+}
+
+func TestIsGeneratedBinding(t *testing.T) {
+	assert.True(t, IsGeneratedBinding("_b0"))
+	assert.True(t, IsGeneratedBinding("_b12"))
+	assert.False(t, IsGeneratedBinding("_b"))
+	assert.False(t, IsGeneratedBinding("_bx"))
+	assert.False(t, IsGeneratedBinding("name"))
+	assert.False(t, IsGeneratedBinding("b0"))
+}
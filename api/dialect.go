@@ -0,0 +1,55 @@
+package api
+
+import (
+	"sync/atomic"
+
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// defaultQueryLanguage is the query language of graphs that were created without WithDialect
+var defaultQueryLanguage atomic.Value
+
+func init() {
+	defaultQueryLanguage.Store(QueryLanguageCosmosDB)
+}
+
+// SetQueryLanguageTo sets the query language that shall be used for all graphs that were created without WithDialect.
+// Per default QueryLanguageCosmosDB is in use.
+//
+// Deprecated: This setting is global for the whole process. Use NewGraph(name, WithDialect(ql)) instead.
+func SetQueryLanguageTo(ql QueryLanguage) {
+	defaultQueryLanguage.Store(ql)
+}
+
+// WithDialect sets the query language (dialect) of the gremlin server the queries of the graph are sent to.
+// This allows to build queries for cosmos and e.g. a tinkerpop gremlin server within one process.
+//
+//	cosmosGraph := api.NewGraph("g")
+//	tinkerpopGraph := api.NewGraph("g", api.WithDialect(api.QueryLanguageTinkerpopGremlin))
+func WithDialect(ql QueryLanguage) GraphOption {
+	return func(g *graph) {
+		g.dialect = ql
+	}
+}
+
+// dialectOf returns the query language of the graph the traversal represented by the given builders belongs to.
+// For traversals without graph (e.g. anonymous traversals) or graphs without dialect the default query language is returned.
+func dialectOf(builders []interfaces.QueryBuilder) QueryLanguage {
+	if g := graphOf(builders); g != nil && len(g.dialect) > 0 {
+		return g.dialect
+	}
+	return defaultQueryLanguage.Load().(QueryLanguage)
+}
+
+// usesCosmosDialect returns true in case the query language differs from the tinkerpop gremlin language like the one of cosmos
+func (ql QueryLanguage) usesCosmosDialect() bool {
+	return ql == QueryLanguageCosmosDB
+}
+
+// profileStep returns the step that returns profiling information of the executed query
+func (ql QueryLanguage) profileStep() string {
+	if ql.usesCosmosDialect() {
+		return ".executionProfile()"
+	}
+	return ".profile()"
+}
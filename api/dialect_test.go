@@ -0,0 +1,119 @@
+package api
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+func TestNewGraphWithDialect(t *testing.T) {
+	// GIVEN
+	graphName := "mygraph"
+
+	// WHEN
+	g := NewGraph(graphName, WithDialect(QueryLanguageTinkerpopGremlin))
+
+	// THEN
+	require.NotNil(t, g)
+	assert.Equal(t, graphName, g.String())
+	assert.Equal(t, QueryLanguageTinkerpopGremlin, g.(*graph).dialect)
+}
+
+func TestDialect_Profile(t *testing.T) {
+	tests := []struct {
+		dialect  QueryLanguage
+		expected string
+	}{
+		{"", "g.V().executionProfile()"},
+		{QueryLanguageCosmosDB, "g.V().executionProfile()"},
+		{QueryLanguageTinkerpopGremlin, "g.V().profile()"},
+		{QueryLanguageJanusGraph, "g.V().profile()"},
+		{QueryLanguageNeptune, "g.V().profile()"},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.dialect), func(t *testing.T) {
+			// GIVEN
+			g := NewGraph("g", WithDialect(test.dialect))
+
+			// WHEN
+			vertexProfile := g.V().Profile()
+			edgeProfile := g.E().Profile()
+			propertyProfile := g.V().Properties().Profile()
+
+			// THEN
+			assert.Equal(t, test.expected, vertexProfile.String())
+			assert.Equal(t, "g.E()"+test.expected[len("g.V()"):], edgeProfile.String())
+			assert.Equal(t, "g.V().properties()"+test.expected[len("g.V()"):], propertyProfile.String())
+		})
+	}
+}
+
+func TestDialect_ByOrder(t *testing.T) {
+	// GIVEN
+	cosmosGraph := NewGraph("g")
+	tinkerpopGraph := NewGraph("g", WithDialect(QueryLanguageTinkerpopGremlin))
+
+	// WHEN
+	cosmosQuery := cosmosGraph.V().Order().ByOrder("name", interfaces.OrderDescending)
+	tinkerpopQuery := tinkerpopGraph.V().Order().ByOrder("name", interfaces.OrderDescending)
+	tinkerpopEdgeQuery := tinkerpopGraph.E().Order().ByOrder("name")
+
+	// THEN
+	assert.Equal(t, `g.V().order().by("name",decr)`, cosmosQuery.String())
+	assert.Equal(t, `g.V().order().by("name",desc)`, tinkerpopQuery.String())
+	assert.Equal(t, `g.E().order().by("name",asc)`, tinkerpopEdgeQuery.String())
+}
+
+func TestDialect_InheritedByDerivedBuilders(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g", WithDialect(QueryLanguageJanusGraph))
+
+	// WHEN
+	query := g.V().OutE().InV().Order().ByOrder("name").Profile()
+
+	// THEN
+	assert.Equal(t, `g.V().outE().inV().order().by("name",asc).profile()`, query.String())
+}
+
+func TestDialect_ConcurrentGraphs(t *testing.T) {
+	// GIVEN
+	cosmosGraph := NewGraph("g", WithDialect(QueryLanguageCosmosDB))
+	tinkerpopGraph := NewGraph("g", WithDialect(QueryLanguageTinkerpopGremlin))
+
+	// WHEN
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "g.V().executionProfile()", cosmosGraph.V().Profile().String())
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "g.V().profile()", tinkerpopGraph.V().Profile().String())
+		}()
+	}
+
+	// THEN
+	wg.Wait()
+}
+
+func TestSetQueryLanguageTo_OnlyAffectsGraphsWithoutDialect(t *testing.T) {
+	// GIVEN
+	defaultGraph := NewGraph("g")
+	cosmosGraph := NewGraph("g", WithDialect(QueryLanguageCosmosDB))
+
+	// WHEN
+	SetQueryLanguageTo(QueryLanguageTinkerpopGremlin)
+	defaultQuery := defaultGraph.V().Profile()
+	cosmosQuery := cosmosGraph.V().Profile()
+	SetQueryLanguageTo(QueryLanguageCosmosDB)
+
+	// THEN
+	assert.Equal(t, "g.V().profile()", defaultQuery.String())
+	assert.Equal(t, "g.V().executionProfile()", cosmosQuery.String())
+}
@@ -35,6 +35,30 @@ func (e *edge) String() string {
 	return queryString
 }
 
+// StringWithBindings returns the query where the values are replaced by generated binding names (e.g. _b0)
+// together with the according bindings.
+func (e *edge) StringWithBindings() (string, map[string]interface{}) {
+	return stringWithBindings(e)
+}
+
+func (e *edge) bindTo(bindings *queryBindings) string {
+	return bindAllTo(e.builders, bindings)
+}
+
+// ValidatePartitionFilter returns ErrNoPartitionFilter in case the traversal is not scoped to a partition.
+// The validation is only done if the partition key of the graph is configured (see WithPartitionKey).
+func (e *edge) ValidatePartitionFilter() error {
+	return validatePartitionFilter(e.builders, e.String())
+}
+
+func (e *edge) graphOf() *graph {
+	return graphOf(e.builders)
+}
+
+func (e *edge) steps() []interfaces.QueryBuilder {
+	return stepsOf(e.builders)
+}
+
 // ByV adds .by([<traversal>]) to the query.
 func (e *edge) By(traversals ...interfaces.QueryBuilder) interfaces.Edge {
 	query := multitraversalQuery(".by", traversals...)
@@ -51,10 +75,10 @@ func (e *edge) Project(labels ...string) interfaces.Edge {
 // Sort order is ascending per default.
 func (e *edge) ByOrder(propertyName string, order ...interfaces.Order) interfaces.Edge {
 	if len(order) == 0 {
-		return e.Add(NewSimpleQB(`.by("%s",%s)`, propertyName, toSortOrder(gUSE_COSMOS_DB_QUERY_LANGUAGE, interfaces.OrderAscending)))
+		return e.Add(NewSimpleQB(`.by("%s",%s)`, propertyName, toSortOrder(dialectOf(e.builders), interfaces.OrderAscending)))
 	}
 
-	return e.Add(NewSimpleQB(`.by("%s",%s)`, propertyName, toSortOrder(gUSE_COSMOS_DB_QUERY_LANGUAGE, order[0])))
+	return e.Add(NewSimpleQB(`.by("%s",%s)`, propertyName, toSortOrder(dialectOf(e.builders), order[0])))
 }
 
 // Dedup adds .dedup() to the query.
@@ -102,12 +126,12 @@ func (e *edge) Has(key string, value ...interface{}) interfaces.Edge {
 		return e.Add(NewSimpleQB(".has(\"%s\")", key))
 	}
 
-	keyVal, err := toKeyValueString(key, value[0])
+	keyVal, err := toKeyValue(key, value[0])
 	if err != nil {
 		panic(errors.Wrapf(err, "cast has value %T to string failed (You could either implement the Stringer interface for this type or cast it to string beforehand)", value))
 	}
 
-	return e.Add(NewSimpleQB(".has%s", keyVal))
+	return e.Add(newFilterStep(NewSimpleQB(".has%s", keyVal), key))
 }
 
 //  Not adds .not(<traversal>) to the query.
@@ -196,10 +220,7 @@ func (e *edge) InV() interfaces.Vertex {
 
 // Profile adds ..executionProfile(), to the query. The query call will return profiling information of the executed query
 func (e *edge) Profile() interfaces.QueryBuilder {
-	if !gUSE_COSMOS_DB_QUERY_LANGUAGE {
-		return e.Add(NewSimpleQB(".profile()"))
-	}
-	return e.Add(NewSimpleQB(".executionProfile()"))
+	return e.Add(NewSimpleQB(dialectOf(e.builders).profileStep()))
 }
 
 // HasLabel adds .hasLabel([<label_1>,<label_2>,..,<label_n>]), e.g. .hasLabel('user','name'), to the query. The query call returns all edges with the given label.
@@ -216,7 +237,7 @@ func (e *edge) Id() interfaces.QueryBuilder {
 // HasId adds .hasId('<id>'), e.g. .hasId('8aaaa410-dae1-4f33-8dd7-0217e69df10c'), to the query. The query call returns all edges
 // with the given id.
 func (e *edge) HasId(id string) interfaces.Edge {
-	return e.Add(NewSimpleQB(".hasId(\"%s\")", id))
+	return e.Add(NewSimpleQB(".hasId(%s)", bindQuoted(id)))
 }
 
 // Cap adds .cap(<label>) to the query.
@@ -232,7 +253,7 @@ func (e *edge) Count() interfaces.QueryBuilder {
 // Property adds .property("<key>","<value>"), e.g. .property("name","hans") depending on the given type the quotes for the value are omitted.
 // e.g. .property("temperature",23.02) or .property("available",true)
 func (e *edge) Property(key, value interface{}) interfaces.Edge {
-	keyVal, err := toKeyValueString(key, value)
+	keyVal, err := toKeyValue(key, value)
 	if err != nil {
 		panic(errors.Wrapf(err, "cast property value %T to string failed (You could either implement the Stringer interface for this type or cast it to string beforehand)", value))
 	}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// ExecutionProfile is the parsed result of a query that was profiled using Profile().
+// It supports both, the output of .executionProfile() (cosmos) and the output of .profile() (tinkerpop).
+type ExecutionProfile struct {
+	// Query is the profiled query (cosmos only).
+	Query string
+	// TotalTime is the time it took to execute the query on the server.
+	TotalTime time.Duration
+	// RequestCharge is the amount of request units (RU) consumed by the query (cosmos only, x-ms-total-request-charge).
+	RequestCharge float32
+	// Operators are the steps/ operators of the query in order of execution.
+	Operators []ProfileOperator
+}
+
+// ProfileOperator contains the metrics of one step/ operator of a profiled query.
+type ProfileOperator struct {
+	// Name is the name of the operator (cosmos, e.g. GetVertices) or of the step (tinkerpop, e.g. TinkerGraphStep(vertex,[])).
+	Name string
+	// Time is the time spent in this operator.
+	Time time.Duration
+	// PercentTime is the share of the total time spent in this operator.
+	PercentTime float64
+	// ResultCount is the number of results/ elements emitted by this operator.
+	ResultCount int64
+	// TraverserCount is the number of traversers emitted by this step (tinkerpop only).
+	TraverserCount int64
+	// RequestCharge is the estimated amount of request units (RU) consumed by this operator.
+	// Cosmos does not report the RU per operator, hence they are estimated based on the share of the total time.
+	RequestCharge float32
+	// FanoutFactor is the maximum number of partitions accessed by one store operation of this operator (cosmos only).
+	FanoutFactor int
+	// IndexLookups is the number of store operations (index lookups) done by this operator (cosmos only).
+	IndexLookups int
+	// StoreOps are the store operations done by this operator (cosmos only).
+	StoreOps []ProfileStoreOp
+	// Children are the metrics of nested traversals (tinkerpop only).
+	Children []ProfileOperator
+}
+
+// ProfileStoreOp contains the metrics of one store operation (index lookup) of a cosmos operator.
+type ProfileStoreOp struct {
+	// FanoutFactor is the number of partitions accessed by the operation.
+	FanoutFactor int
+	// Count is the number of results returned by the operation.
+	Count int64
+	// Size is the size in bytes of the results returned by the operation.
+	Size int64
+	// Time is the time spent for the operation.
+	Time time.Duration
+}
+
+// ToExecutionProfile converts the given responses of a profiled query (see Profile()) into an ExecutionProfile.
+// In case the responses contain the profiles of multiple traversals, only the first one is returned.
+func ToExecutionProfile(responses []interfaces.Response) (ExecutionProfile, error) {
+	profiles := make([]interface{}, 0)
+	var requestCharge float32
+	for _, response := range responses {
+		// only take the largest value since cosmos already accumulates this value
+		if charge := cast.ToFloat32(response.Status.Attributes["x-ms-total-request-charge"]); charge > requestCharge {
+			requestCharge = charge
+		}
+
+		if response.IsEmpty() {
+			continue
+		}
+
+		parsed := make([]interface{}, 0)
+		if err := json.Unmarshal(response.Result.Data, &parsed); err != nil {
+			return ExecutionProfile{}, errors.Wrap(err, "parsing execution profile")
+		}
+		profiles = append(profiles, parsed...)
+	}
+
+	if len(profiles) == 0 {
+		return ExecutionProfile{}, fmt.Errorf("responses do not contain an execution profile")
+	}
+
+	profileMap, ok := untype(profiles[0]).(map[string]interface{})
+	if !ok {
+		return ExecutionProfile{}, fmt.Errorf("failed to cast %v (%T) into map[string]interface{}", profiles[0], profiles[0])
+	}
+
+	var profile ExecutionProfile
+	if _, ok := profileMap["dur"]; ok {
+		profile = toTinkerpopProfile(profileMap)
+	} else {
+		profile = toCosmosProfile(profileMap)
+	}
+
+	profile.RequestCharge = requestCharge
+	profile.estimateRequestCharge()
+	return profile, nil
+}
+
+// ToExecutionProfile converts the given ResponseArray of a profiled query (see Profile()) into an ExecutionProfile.
+func (responses ResponseArray) ToExecutionProfile() (ExecutionProfile, error) {
+	return ToExecutionProfile(responses)
+}
+
+// toCosmosProfile converts the output of .executionProfile()
+//
+//	{"gremlin":"g.V()","totalTime":28,"metrics":[{"name":"GetVertices","time":24,"annotations":{"percentTime":85.71},"counts":{"resultCount":2},"storeOps":[{"fanoutFactor":1,"count":2,"size":696,"time":0.4}]}]}
+func toCosmosProfile(profileMap map[string]interface{}) ExecutionProfile {
+	profile := ExecutionProfile{
+		Query:     cast.ToString(profileMap["gremlin"]),
+		TotalTime: toMilliseconds(profileMap["totalTime"]),
+	}
+
+	for _, metric := range toMaps(profileMap["metrics"]) {
+		operator := ProfileOperator{
+			Name:        cast.ToString(metric["name"]),
+			Time:        toMilliseconds(metric["time"]),
+			PercentTime: cast.ToFloat64(cast.ToStringMap(metric["annotations"])["percentTime"]),
+			ResultCount: cast.ToInt64(cast.ToStringMap(metric["counts"])["resultCount"]),
+		}
+
+		for _, storeOp := range toMaps(metric["storeOps"]) {
+			op := ProfileStoreOp{
+				FanoutFactor: cast.ToInt(storeOp["fanoutFactor"]),
+				Count:        cast.ToInt64(storeOp["count"]),
+				Size:         cast.ToInt64(storeOp["size"]),
+				Time:         toMilliseconds(storeOp["time"]),
+			}
+			if op.FanoutFactor > operator.FanoutFactor {
+				operator.FanoutFactor = op.FanoutFactor
+			}
+			operator.StoreOps = append(operator.StoreOps, op)
+		}
+		operator.IndexLookups = len(operator.StoreOps)
+
+		profile.Operators = append(profile.Operators, operator)
+	}
+	return profile
+}
+
+// toTinkerpopProfile converts the output of .profile()
+//
+//	{"dur":0.5,"metrics":[{"id":"0.0.0()","name":"TinkerGraphStep(vertex,[])","dur":0.1,"counts":{"traverserCount":4,"elementCount":4},"annotations":{"percentDur":20.0}}]}
+func toTinkerpopProfile(profileMap map[string]interface{}) ExecutionProfile {
+	return ExecutionProfile{
+		TotalTime: toMilliseconds(profileMap["dur"]),
+		Operators: toTinkerpopOperators(profileMap["metrics"]),
+	}
+}
+
+func toTinkerpopOperators(metrics interface{}) []ProfileOperator {
+	var operators []ProfileOperator
+	for _, metric := range toMaps(metrics) {
+		counts := cast.ToStringMap(metric["counts"])
+		operators = append(operators, ProfileOperator{
+			Name:           cast.ToString(metric["name"]),
+			Time:           toMilliseconds(metric["dur"]),
+			PercentTime:    cast.ToFloat64(cast.ToStringMap(metric["annotations"])["percentDur"]),
+			ResultCount:    cast.ToInt64(counts["elementCount"]),
+			TraverserCount: cast.ToInt64(counts["traverserCount"]),
+			Children:       toTinkerpopOperators(metric["metrics"]),
+		})
+	}
+	return operators
+}
+
+// estimateRequestCharge distributes the request charge of the profile among the operators based on their share of the total time
+func (p *ExecutionProfile) estimateRequestCharge() {
+	if p.RequestCharge == 0 {
+		return
+	}
+
+	for i := range p.Operators {
+		p.Operators[i].RequestCharge = p.RequestCharge * float32(p.Operators[i].PercentTime/100)
+	}
+}
+
+// untype removes the type information of GraphSON 2.0/3.0 values, e.g. {"@type":"g:Int64","@value":4} --> 4
+func untype(value interface{}) interface{} {
+	switch casted := value.(type) {
+	case map[string]interface{}:
+		if typedValue, ok := casted["@value"]; ok {
+			if _, ok := casted["@type"]; ok {
+				return untype(typedValue)
+			}
+		}
+
+		result := make(map[string]interface{}, len(casted))
+		for key, element := range casted {
+			result[key] = untype(element)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, 0, len(casted))
+		for _, element := range casted {
+			result = append(result, untype(element))
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// toMaps converts the given value into a slice of maps, elements that are no maps are skipped
+func toMaps(value interface{}) []map[string]interface{} {
+	elements, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make([]map[string]interface{}, 0, len(elements))
+	for _, element := range elements {
+		if mapped, ok := element.(map[string]interface{}); ok {
+			result = append(result, mapped)
+		}
+	}
+	return result
+}
+
+// toMilliseconds converts the given value in milliseconds (e.g. 0.5) into a duration
+func toMilliseconds(value interface{}) time.Duration {
+	return time.Duration(cast.ToFloat64(value) * float64(time.Millisecond))
+}
+
+// HotSpotKind is the kind of a potential performance problem of a profiled query
+type HotSpotKind string
+
+const (
+	// HotSpotFullScan the operator scans all vertices/ edges instead of using a filter or an index
+	HotSpotFullScan HotSpotKind = "FullScan"
+	// HotSpotHighFanout the operator accesses many partitions (cosmos) or multiplies the number of results
+	HotSpotHighFanout HotSpotKind = "HighFanout"
+)
+
+// HotSpot is a potential performance problem of a profiled query
+type HotSpot struct {
+	Kind HotSpotKind
+	// Operator is the name of the affected operator/ step.
+	Operator string
+	// Description explains why the operator was flagged.
+	Description string
+}
+
+// HotSpotThresholds defines the limits above which an operator is regarded as hot spot
+type HotSpotThresholds struct {
+	// MaxScanCount is the maximum number of results a cosmos operator that reads vertices/ edges may fetch from the store.
+	MaxScanCount int64
+	// MaxFanoutFactor is the maximum number of partitions a cosmos operator may access.
+	MaxFanoutFactor int
+	// MaxExpansion is the maximum factor an operator may multiply the number of results of its predecessor by.
+	MaxExpansion float64
+}
+
+// DefaultHotSpotThresholds are the thresholds used by HotSpots
+var DefaultHotSpotThresholds = HotSpotThresholds{
+	MaxScanCount:    1000,
+	MaxFanoutFactor: 1,
+	MaxExpansion:    100,
+}
+
+// HotSpots returns the potential performance problems of the profiled query using the DefaultHotSpotThresholds.
+// This can be used to review query plans in tests.
+//
+//	profile, _ := api.ToExecutionProfile(responses)
+//	assert.Empty(t, profile.HotSpots())
+func (p ExecutionProfile) HotSpots() []HotSpot {
+	return p.HotSpotsWith(DefaultHotSpotThresholds)
+}
+
+// HotSpotsWith returns the potential performance problems of the profiled query using the given thresholds.
+// The following problems are detected:
+//   - full scans: tinkerpop graph steps without any filter (e.g. TinkerGraphStep(vertex,[])) or cosmos operators
+//     that fetch more than MaxScanCount vertices/ edges from the store
+//   - high fan-out: cosmos operators that access more than MaxFanoutFactor partitions or operators that multiply
+//     the number of results of their predecessor by more than MaxExpansion
+func (p ExecutionProfile) HotSpotsWith(thresholds HotSpotThresholds) []HotSpot {
+	var hotSpots []HotSpot
+	var previousResultCount int64 = -1
+	for _, operator := range p.Operators {
+		if isUnfilteredGraphStep(operator.Name) {
+			hotSpots = append(hotSpots, HotSpot{Kind: HotSpotFullScan, Operator: operator.Name, Description: "graph step without any filter scans all elements"})
+		}
+
+		if isCosmosReadOperator(operator.Name) {
+			var scanned int64
+			for _, storeOp := range operator.StoreOps {
+				scanned += storeOp.Count
+			}
+			if scanned > thresholds.MaxScanCount {
+				hotSpots = append(hotSpots, HotSpot{Kind: HotSpotFullScan, Operator: operator.Name, Description: fmt.Sprintf("fetched %d elements from the store (> %d)", scanned, thresholds.MaxScanCount)})
+			}
+		}
+
+		if operator.FanoutFactor > thresholds.MaxFanoutFactor {
+			hotSpots = append(hotSpots, HotSpot{Kind: HotSpotHighFanout, Operator: operator.Name, Description: fmt.Sprintf("accessed %d partitions (> %d)", operator.FanoutFactor, thresholds.MaxFanoutFactor)})
+		}
+
+		if previousResultCount > 0 && float64(operator.ResultCount)/float64(previousResultCount) > thresholds.MaxExpansion {
+			hotSpots = append(hotSpots, HotSpot{Kind: HotSpotHighFanout, Operator: operator.Name, Description: fmt.Sprintf("expanded %d results to %d (> factor %.0f)", previousResultCount, operator.ResultCount, thresholds.MaxExpansion)})
+		}
+		previousResultCount = operator.ResultCount
+	}
+	return hotSpots
+}
+
+// isUnfilteredGraphStep returns true in case the given step is a tinkerpop graph step without ids and filters,
+// e.g. TinkerGraphStep(vertex,[]) or JanusGraphStep([],[])
+func isUnfilteredGraphStep(name string) bool {
+	index := strings.Index(name, "GraphStep(")
+	if index < 0 {
+		return false
+	}
+
+	switch strings.TrimSuffix(name[index+len("GraphStep("):], ")") {
+	case "vertex,[]", "edge,[]", "[],[]":
+		return true
+	default:
+		return false
+	}
+}
+
+// isCosmosReadOperator returns true in case the given cosmos operator reads vertices or edges from the store
+func isCosmosReadOperator(name string) bool {
+	switch name {
+	case "GetVertices", "GetEdges", "GetNeighborVertices", "GetNeighborEdges":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+func TestToExecutionProfile_Cosmos(t *testing.T) {
+	// GIVEN
+	data := `[{"gremlin":"g.V().hasLabel('user').out()","totalTime":20,"metrics":[
+		{"name":"GetVertices","time":15,"annotations":{"percentTime":75},"counts":{"resultCount":2},"storeOps":[{"fanoutFactor":3,"count":2,"size":696,"time":0.5}]},
+		{"name":"GetEdges","time":5,"annotations":{"percentTime":25},"counts":{"resultCount":1},"storeOps":[{"fanoutFactor":1,"count":1,"size":419,"time":0.25},{"fanoutFactor":1,"count":0,"size":0,"time":0.25}]},
+		{"name":"ProjectOperator","time":0,"annotations":{"percentTime":0},"counts":{"resultCount":1}}
+	]}]`
+	responses := []interfaces.Response{
+		{
+			Status: interfaces.Status{Code: interfaces.StatusSuccess, Attributes: map[string]interface{}{"x-ms-total-request-charge": 10.0}},
+			Result: interfaces.Result{Data: []byte(data)},
+		},
+	}
+
+	// WHEN
+	profile, err := ToExecutionProfile(responses)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, "g.V().hasLabel('user').out()", profile.Query)
+	assert.Equal(t, time.Millisecond*20, profile.TotalTime)
+	assert.Equal(t, float32(10), profile.RequestCharge)
+	require.Len(t, profile.Operators, 3)
+
+	getVertices := profile.Operators[0]
+	assert.Equal(t, "GetVertices", getVertices.Name)
+	assert.Equal(t, time.Millisecond*15, getVertices.Time)
+	assert.Equal(t, 75.0, getVertices.PercentTime)
+	assert.Equal(t, int64(2), getVertices.ResultCount)
+	assert.Equal(t, float32(7.5), getVertices.RequestCharge)
+	assert.Equal(t, 3, getVertices.FanoutFactor)
+	assert.Equal(t, 1, getVertices.IndexLookups)
+	assert.Equal(t, []ProfileStoreOp{{FanoutFactor: 3, Count: 2, Size: 696, Time: time.Microsecond * 500}}, getVertices.StoreOps)
+
+	getEdges := profile.Operators[1]
+	assert.Equal(t, "GetEdges", getEdges.Name)
+	assert.Equal(t, float32(2.5), getEdges.RequestCharge)
+	assert.Equal(t, 1, getEdges.FanoutFactor)
+	assert.Equal(t, 2, getEdges.IndexLookups)
+
+	assert.Equal(t, "ProjectOperator", profile.Operators[2].Name)
+	assert.Empty(t, profile.Operators[2].StoreOps)
+}
+
+func TestToExecutionProfile_Tinkerpop(t *testing.T) {
+	// GIVEN
+	data := `[{"@type":"g:TraversalMetrics","@value":{"dur":{"@type":"g:Double","@value":0.5},"metrics":[
+		{"@type":"g:Metrics","@value":{"id":"0.0.0()","name":"TinkerGraphStep(vertex,[~label.eq(user)])","dur":{"@type":"g:Double","@value":0.2},
+			"counts":{"traverserCount":{"@type":"g:Int64","@value":4},"elementCount":{"@type":"g:Int64","@value":4}},
+			"annotations":{"percentDur":{"@type":"g:Double","@value":40.0}}}},
+		{"@type":"g:Metrics","@value":{"id":"1.0.0()","name":"VertexStep(OUT,vertex)","dur":{"@type":"g:Double","@value":0.3},
+			"counts":{"traverserCount":{"@type":"g:Int64","@value":3},"elementCount":{"@type":"g:Int64","@value":6}},
+			"annotations":{"percentDur":{"@type":"g:Double","@value":60.0}},
+			"metrics":[{"@type":"g:Metrics","@value":{"id":"1.1.0()","name":"PropertiesStep([name],value)","dur":{"@type":"g:Double","@value":0.1},"counts":{},"annotations":{}}}]}}
+	]}}]`
+	responses := ResponseArray{{Status: interfaces.Status{Code: interfaces.StatusSuccess}, Result: interfaces.Result{Data: []byte(data)}}}
+
+	// WHEN
+	profile, err := responses.ToExecutionProfile()
+
+	// THEN
+	require.NoError(t, err)
+	assert.Empty(t, profile.Query)
+	assert.Equal(t, time.Microsecond*500, profile.TotalTime)
+	assert.Equal(t, float32(0), profile.RequestCharge)
+	require.Len(t, profile.Operators, 2)
+
+	graphStep := profile.Operators[0]
+	assert.Equal(t, "TinkerGraphStep(vertex,[~label.eq(user)])", graphStep.Name)
+	assert.Equal(t, time.Microsecond*200, graphStep.Time)
+	assert.Equal(t, 40.0, graphStep.PercentTime)
+	assert.Equal(t, int64(4), graphStep.ResultCount)
+	assert.Equal(t, int64(4), graphStep.TraverserCount)
+	assert.Empty(t, graphStep.Children)
+
+	vertexStep := profile.Operators[1]
+	assert.Equal(t, "VertexStep(OUT,vertex)", vertexStep.Name)
+	assert.Equal(t, int64(6), vertexStep.ResultCount)
+	assert.Equal(t, int64(3), vertexStep.TraverserCount)
+	require.Len(t, vertexStep.Children, 1)
+	assert.Equal(t, "PropertiesStep([name],value)", vertexStep.Children[0].Name)
+}
+
+func TestToExecutionProfile_Invalid(t *testing.T) {
+	// GIVEN
+	empty := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusNoContent}}}
+	invalid := []interfaces.Response{{Result: interfaces.Result{Data: []byte(`{"invalid"`)}}}
+	noMap := []interfaces.Response{{Result: interfaces.Result{Data: []byte(`[1,2]`)}}}
+
+	// WHEN
+	_, errEmpty := ToExecutionProfile(empty)
+	_, errInvalid := ToExecutionProfile(invalid)
+	_, errNoMap := ToExecutionProfile(noMap)
+
+	// THEN
+	assert.EqualError(t, errEmpty, "responses do not contain an execution profile")
+	assert.Error(t, errInvalid)
+	assert.Error(t, errNoMap)
+}
+
+func TestExecutionProfile_HotSpots(t *testing.T) {
+	// GIVEN
+	profile := ExecutionProfile{
+		Operators: []ProfileOperator{
+			{Name: "TinkerGraphStep(vertex,[])", ResultCount: 10},
+			{Name: "VertexStep(OUT,vertex)", ResultCount: 2000},
+			{Name: "GetVertices", ResultCount: 1500, FanoutFactor: 4, StoreOps: []ProfileStoreOp{{FanoutFactor: 4, Count: 1500}}},
+			{Name: "GetEdges", ResultCount: 10, FanoutFactor: 1, StoreOps: []ProfileStoreOp{{FanoutFactor: 1, Count: 10}}},
+		},
+	}
+
+	// WHEN
+	hotSpots := profile.HotSpots()
+
+	// THEN
+	require.Len(t, hotSpots, 4)
+	assert.Equal(t, HotSpotFullScan, hotSpots[0].Kind)
+	assert.Equal(t, "TinkerGraphStep(vertex,[])", hotSpots[0].Operator)
+	assert.Equal(t, HotSpotHighFanout, hotSpots[1].Kind)
+	assert.Equal(t, "VertexStep(OUT,vertex)", hotSpots[1].Operator)
+	assert.Equal(t, HotSpotFullScan, hotSpots[2].Kind)
+	assert.Equal(t, "GetVertices", hotSpots[2].Operator)
+	assert.Equal(t, HotSpotHighFanout, hotSpots[3].Kind)
+	assert.Equal(t, "GetVertices", hotSpots[3].Operator)
+	assert.Equal(t, "accessed 4 partitions (> 1)", hotSpots[3].Description)
+}
+
+func TestExecutionProfile_HotSpotsWith(t *testing.T) {
+	// GIVEN
+	profile := ExecutionProfile{
+		Operators: []ProfileOperator{
+			{Name: "GetVertices", ResultCount: 1500, FanoutFactor: 4, StoreOps: []ProfileStoreOp{{FanoutFactor: 4, Count: 1500}}},
+		},
+	}
+
+	// WHEN
+	hotSpots := profile.HotSpotsWith(HotSpotThresholds{MaxScanCount: 2000, MaxFanoutFactor: 10, MaxExpansion: 100})
+
+	// THEN
+	assert.Empty(t, hotSpots)
+}
+
+func TestIsUnfilteredGraphStep(t *testing.T) {
+	assert.True(t, isUnfilteredGraphStep("TinkerGraphStep(vertex,[])"))
+	assert.True(t, isUnfilteredGraphStep("GraphStep(edge,[])"))
+	assert.True(t, isUnfilteredGraphStep("JanusGraphStep([],[])"))
+	assert.False(t, isUnfilteredGraphStep("TinkerGraphStep(vertex,[~label.eq(user)])"))
+	assert.False(t, isUnfilteredGraphStep("JanusGraphStep([1],[])"))
+	assert.False(t, isUnfilteredGraphStep("JanusGraphStep([],[~label.eq(user)])"))
+	assert.False(t, isUnfilteredGraphStep("VertexStep(OUT,vertex)"))
+}
@@ -2,38 +2,47 @@ package api
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/gofrs/uuid"
 	"github.com/supplyon/gremcos/interfaces"
 )
 
+// QueryLanguage is the dialect of the gremlin language a gremlin server understands
 type QueryLanguage string
 
 const (
 	QueryLanguageCosmosDB         QueryLanguage = "cosmos"
 	QueryLanguageTinkerpopGremlin QueryLanguage = "tinkerpop"
+	QueryLanguageJanusGraph       QueryLanguage = "janusgraph"
+	QueryLanguageNeptune          QueryLanguage = "neptune"
 )
 
-var gUSE_COSMOS_DB_QUERY_LANGUAGE = true
-
-// SetQueryLanguageTo sets the query language that shall be used.
-// Per default QueryLanguageCosmosDB is in use.
-func SetQueryLanguageTo(ql QueryLanguage) {
-	gUSE_COSMOS_DB_QUERY_LANGUAGE = (ql == QueryLanguageCosmosDB)
-}
-
 // NewGraph creates a new graph query with the given name
 // Hint: The actual graph has to exist on the server in order to execute the
 // query that will be generated with this query builder
-func NewGraph(name string) interfaces.Graph {
-	return &graph{
+//
+//	g := api.NewGraph("g", api.WithPartitionKey("pk"))
+func NewGraph(name string, options ...GraphOption) interfaces.Graph {
+	g := &graph{
 		name: name,
 	}
+
+	for _, opt := range options {
+		opt(g)
+	}
+	return g
 }
 
 type graph struct {
 	name string
+
+	// partitionKeyName is the name of the property that is used as partition key (empty if not partitioned)
+	partitionKeyName string
+
+	// dialect is the query language of the gremlin server (empty if the default query language is used)
+	dialect QueryLanguage
 }
 
 // V adds .V()
@@ -46,31 +55,58 @@ func (g *graph) V() interfaces.Vertex {
 // VBy adds .V(<id>), e.g. .V(123)
 func (g *graph) VBy(id int) interfaces.Vertex {
 	vertex := NewVertexG(g)
-	vertex.Add(NewSimpleQB(".V(\"%d\")", id))
+	vertex.Add(NewSimpleQB(".V(%s)", bindValue(strconv.Itoa(id), fmt.Sprintf("\"%d\"", id))))
 	return vertex
 }
 
 // VByUUID adds .V(<id>), e.g. .V("8fff9259-09e6-4ea5-aaf8-250b31cc7f44"), to the query. The query call returns the vertex with the given id.
 func (g *graph) VByUUID(id uuid.UUID) interfaces.Vertex {
 	vertex := NewVertexG(g)
-	vertex.Add(NewSimpleQB(".V(\"%s\")", id))
+	vertex.Add(NewSimpleQB(".V(%s)", bindValue(id.String(), fmt.Sprintf("\"%s\"", id))))
 	return vertex
 }
 
 // VByStr adds .V(<id>), e.g. .V("123a"), to the query.  The query call returns the vertex with the given id.
 func (g *graph) VByStr(id string) interfaces.Vertex {
 	vertex := NewVertexG(g)
-	vertex.Add(NewSimpleQB(".V(\"%s\")", id))
+	vertex.Add(NewSimpleQB(".V(%s)", bindQuoted(id)))
+	return vertex
+}
+
+// VByPartitionedID adds .V([<partition key>,<id>]), e.g. .V(["pk1","8fff9259-09e6-4ea5-aaf8-250b31cc7f44"]), to the query.
+// The query call returns the vertex with the given id within the given partition. In a partitioned graph
+// this point read is much cheaper than a lookup by id only, which has to be done on all partitions.
+func (g *graph) VByPartitionedID(partitionKey, id string) interfaces.Vertex {
+	vertex := NewVertexG(g)
+	vertex.Add(newScopedStep(NewSimpleQB(".V(%s)", partitionedID(partitionKey, id))))
 	return vertex
 }
 
 // AddV adds .addV("<label>"), e.g. .addV("user")
 func (g *graph) AddV(label string) interfaces.Vertex {
 	vertex := NewVertexG(g)
-	vertex.Add(NewSimpleQB(".addV(\"%s\")", label))
+	vertex.Add(newScopedStep(NewSimpleQB(".addV(%s)", bindQuoted(label))))
 	return vertex
 }
 
+// AddVWithPartition adds .addV("<label>").property("<partition key name>","<partition key>"),
+// e.g. .addV("user").property("pk","pk1"), to the query. The query call adds a vertex with the given label to the given partition.
+// Hint: The name of the partition key property has to be configured using WithPartitionKey, otherwise ErrNoPartitionKey is returned.
+func (g *graph) AddVWithPartition(label, partitionKey string) (interfaces.Vertex, error) {
+	if len(g.partitionKeyName) == 0 {
+		return nil, ErrNoPartitionKey
+	}
+
+	return g.AddV(label).Property(g.partitionKeyName, partitionKey), nil
+}
+
+// AddEWithPartition adds .V([<partition key>,<id>]).addE("<label>").to(<graph>.V([<partition key>,<id>])), e.g.
+// .V(["pk1","id1"]).addE("knows").to(g.V(["pk2","id2"])), to the query. The query call adds an edge with the given label
+// between the given vertices. Both vertices are addressed by point reads, hence no partition is scanned.
+func (g *graph) AddEWithPartition(label, fromPartitionKey, fromID, toPartitionKey, toID string) interfaces.Edge {
+	return g.VByPartitionedID(fromPartitionKey, fromID).AddE(label).To(g.VByPartitionedID(toPartitionKey, toID))
+}
+
 // E adds .E()
 func (g *graph) E() interfaces.Edge {
 	edge := NewEdgeG(g)
@@ -82,6 +118,14 @@ func (g *graph) String() string {
 	return g.name
 }
 
+func (g *graph) graphOf() *graph {
+	return g
+}
+
+func (g *graph) steps() []interfaces.QueryBuilder {
+	return nil
+}
+
 // multiParamQuery creates a query based on the given (optional) parameters.
 // The query is the name of the query method that supports 0..* parameters.
 // Examples:
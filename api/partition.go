@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// ErrNoPartitionFilter is returned by ValidatePartitionFilter in case a traversal is not scoped to a partition.
+// Such queries fan out across all partitions of the graph and consume a lot of request units (RU).
+var ErrNoPartitionFilter = errors.New("traversal does not filter by partition key")
+
+// ErrNoPartitionKey is returned by AddVWithPartition in case the partition key of the graph is not configured (see WithPartitionKey).
+var ErrNoPartitionKey = errors.New("the partition key property of the graph is not configured (see WithPartitionKey)")
+
+// GraphOption can be used to configure the graph query builder
+type GraphOption func(g *graph)
+
+// WithPartitionKey sets the name of the property that is used as partition key of a partitioned cosmos graph (e.g. "pk").
+// This is needed for AddVWithPartition and enables the validation of traversals (see ValidatePartitionFilter).
+func WithPartitionKey(propertyName string) GraphOption {
+	return func(g *graph) {
+		g.partitionKeyName = propertyName
+	}
+}
+
+// traversal is implemented by all query builders that know the graph they belong to
+type traversal interface {
+	graphOf() *graph
+	// steps returns the steps of the traversal, including the ones of the traversal it continues (e.g. the vertex steps of an edge traversal)
+	steps() []interfaces.QueryBuilder
+}
+
+// graphOf returns the graph the traversal represented by the given builders belongs to, nil if there is none (e.g. anonymous traversals)
+func graphOf(builders []interfaces.QueryBuilder) *graph {
+	if len(builders) == 0 {
+		return nil
+	}
+
+	if t, ok := builders[0].(traversal); ok {
+		return t.graphOf()
+	}
+	return nil
+}
+
+// stepsOf returns the steps of the traversal represented by the given builders (see traversal.steps)
+func stepsOf(builders []interfaces.QueryBuilder) []interfaces.QueryBuilder {
+	if len(builders) == 0 {
+		return nil
+	}
+
+	if t, ok := builders[0].(traversal); ok {
+		return append(t.steps(), builders[1:]...)
+	}
+	return builders
+}
+
+// partitionStep is a step that is relevant for the validation of partition filters (see validatePartitionFilter)
+type partitionStep struct {
+	interfaces.QueryBuilder
+	// scoped is true for steps that scope a traversal to a partition if they start it, e.g. a point read by partition key and id
+	scoped bool
+	// filterKey is the name of the property the step filters by, e.g. the key of a has step
+	filterKey string
+}
+
+// newScopedStep marks the given step as one that scopes a traversal to a partition if it starts the traversal
+func newScopedStep(step interfaces.QueryBuilder) interfaces.QueryBuilder {
+	return &partitionStep{QueryBuilder: step, scoped: true}
+}
+
+// newFilterStep marks the given step as one that filters by the property with the given key
+func newFilterStep(step interfaces.QueryBuilder, key string) interfaces.QueryBuilder {
+	return &partitionStep{QueryBuilder: step, filterKey: key}
+}
+
+func (s *partitionStep) bindTo(bindings *queryBindings) string {
+	return bindTo(s.QueryBuilder, bindings)
+}
+
+// partitionedID returns the value that identifies a vertex in a partitioned graph, e.g. ["pk","id"]
+func partitionedID(partitionKey, id string) *boundValue {
+	escapedPK := Escape(partitionKey)
+	escapedID := Escape(id)
+	return bindValue([]string{escapedPK, escapedID}, fmt.Sprintf("[\"%s\",\"%s\"]", escapedPK, escapedID))
+}
+
+// validatePartitionFilter checks if the given traversal is scoped to one partition.
+// This is the case if
+//   - it starts with a point read that contains the partition key, e.g. g.V(["pk","id"])
+//   - it adds a vertex, e.g. g.addV("user")
+//   - it filters by the partition key, e.g. g.V().has("pk","abc")
+//
+// Only the steps of the traversal itself are checked, filters within nested traversals (e.g. where(...)) don't scope it.
+// In case no partition key is configured, all traversals are regarded as valid.
+func validatePartitionFilter(builders []interfaces.QueryBuilder, query string) error {
+	g := graphOf(builders)
+	if g == nil || len(g.partitionKeyName) == 0 {
+		return nil
+	}
+
+	for i, step := range stepsOf(builders) {
+		partitionStep, ok := step.(*partitionStep)
+		if !ok {
+			continue
+		}
+
+		if (i == 0 && partitionStep.scoped) || partitionStep.filterKey == g.partitionKeyName {
+			return nil
+		}
+	}
+	return errors.Wrapf(ErrNoPartitionFilter, "query '%s' (partition key '%s')", query, g.partitionKeyName)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+func TestNewGraphWithPartitionKey(t *testing.T) {
+	// GIVEN
+	graphName := "mygraph"
+
+	// WHEN
+	g := NewGraph(graphName, WithPartitionKey("pk"))
+
+	// THEN
+	require.NotNil(t, g)
+	assert.Equal(t, graphName, g.String())
+	assert.Equal(t, "pk", g.(*graph).partitionKeyName)
+}
+
+func TestVByPartitionedID(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g", WithPartitionKey("pk"))
+
+	// WHEN
+	result := g.VByPartitionedID("pk1", "8fff9259-09e6-4ea5-aaf8-250b31cc7f44")
+	query, bindings := result.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+
+	// THEN
+	assert.Equal(t, `g.V(["pk1","8fff9259-09e6-4ea5-aaf8-250b31cc7f44"])`, result.String())
+	assert.Equal(t, `g.V(_b0)`, query)
+	assert.Equal(t, map[string]interface{}{"_b0": []string{"pk1", "8fff9259-09e6-4ea5-aaf8-250b31cc7f44"}}, bindings)
+}
+
+func TestAddVWithPartition(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g", WithPartitionKey("pk"))
+
+	// WHEN
+	vertex, err := g.AddVWithPartition("user", "pk1")
+	require.NoError(t, err)
+	result := vertex.Property("name", "Max")
+
+	// THEN
+	assert.Equal(t, `g.addV("user").property("pk","pk1").property("name","Max")`, result.String())
+}
+
+func TestAddVWithPartition_NoPartitionKey(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g")
+
+	// WHEN
+	vertex, err := g.AddVWithPartition("user", "pk1")
+
+	// THEN
+	assert.ErrorIs(t, err, ErrNoPartitionKey)
+	assert.Nil(t, vertex)
+}
+
+func TestAddEWithPartition(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g", WithPartitionKey("pk"))
+
+	// WHEN
+	result := g.AddEWithPartition("knows", "pk1", "id1", "pk2", "id2")
+
+	// THEN
+	assert.Equal(t, `g.V(["pk1","id1"]).addE("knows").to(g.V(["pk2","id2"]))`, result.String())
+}
+
+func TestValidatePartitionFilter(t *testing.T) {
+	// GIVEN
+	g := NewGraph("g", WithPartitionKey("pk"))
+	unpartitioned := NewGraph("g")
+	addV, err := g.AddVWithPartition("user", "pk1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		query interfaces.PartitionFilterValidator
+		valid bool
+	}{
+		{name: "point read", query: g.VByPartitionedID("pk1", "id1").Out("knows").(interfaces.PartitionFilterValidator), valid: true},
+		{name: "add vertex", query: addV.(interfaces.PartitionFilterValidator), valid: true},
+		{name: "add edge", query: g.AddEWithPartition("knows", "pk1", "id1", "pk2", "id2").(interfaces.PartitionFilterValidator), valid: true},
+		{name: "has partition key", query: g.V().HasLabel("user").Has("pk", "pk1").(interfaces.PartitionFilterValidator), valid: true},
+		{name: "edges with partition key", query: g.E().Has("pk", "pk1").(interfaces.PartitionFilterValidator), valid: true},
+		{name: "values", query: g.VByPartitionedID("pk1", "id1").ValuesBy("name").(interfaces.PartitionFilterValidator), valid: true},
+		{name: "full scan", query: g.V().HasLabel("user").(interfaces.PartitionFilterValidator), valid: false},
+		{name: "lookup by id", query: g.VByStr("id1").Properties().(interfaces.PartitionFilterValidator), valid: false},
+		{name: "filter on other property", query: g.V().Has("name", "pk").(interfaces.PartitionFilterValidator), valid: false},
+		{name: "existence of partition key", query: g.V().Has("pk").(interfaces.PartitionFilterValidator), valid: false},
+		{name: "nested filter by partition key", query: g.V().Where(Has("pk", "pk1")).(interfaces.PartitionFilterValidator), valid: false},
+		{name: "point read not at start", query: g.V().HasLabel("user").Add(NewSimpleQB(`.V(["pk1","id1"])`)).(interfaces.PartitionFilterValidator), valid: false},
+		{name: "edge of point read", query: g.VByPartitionedID("pk1", "id1").OutE("knows").(interfaces.PartitionFilterValidator), valid: true},
+		{name: "filter by partition key with bindings", query: g.V().Has("pk", "pk1").Has("name", "Max").(interfaces.PartitionFilterValidator), valid: true},
+		{name: "edges", query: g.E().(interfaces.PartitionFilterValidator), valid: false},
+		{name: "not partitioned", query: unpartitioned.V().(interfaces.PartitionFilterValidator), valid: true},
+		{name: "anonymous", query: OutV().(interfaces.PartitionFilterValidator), valid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// WHEN
+			err := test.query.ValidatePartitionFilter()
+
+			// THEN
+			if test.valid {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrNoPartitionFilter)
+		})
+	}
+}
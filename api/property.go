@@ -25,6 +25,30 @@ func (p *property) String() string {
 	return queryString
 }
 
+// StringWithBindings returns the query where the values are replaced by generated binding names (e.g. _b0)
+// together with the according bindings.
+func (p *property) StringWithBindings() (string, map[string]interface{}) {
+	return stringWithBindings(p)
+}
+
+func (p *property) bindTo(bindings *queryBindings) string {
+	return bindAllTo(p.builders, bindings)
+}
+
+// ValidatePartitionFilter returns ErrNoPartitionFilter in case the traversal is not scoped to a partition.
+// The validation is only done if the partition key of the graph is configured (see WithPartitionKey).
+func (p *property) ValidatePartitionFilter() error {
+	return validatePartitionFilter(p.builders, p.String())
+}
+
+func (p *property) graphOf() *graph {
+	return graphOf(p.builders)
+}
+
+func (p *property) steps() []interfaces.QueryBuilder {
+	return stepsOf(p.builders)
+}
+
 // Add can be used to add a custom QueryBuilder
 // e.g. g.V().Add(NewSimpleQB(".myCustomCall("%s")",label))
 func (p *property) Add(builder interfaces.QueryBuilder) interfaces.Property {
@@ -39,10 +63,7 @@ func (p *property) Drop() interfaces.QueryBuilder {
 
 // Profile adds .executionProfile(), to the query. The query call will return profiling information of the executed query
 func (p *property) Profile() interfaces.QueryBuilder {
-	if !gUSE_COSMOS_DB_QUERY_LANGUAGE {
-		return p.Add(NewSimpleQB(".profile()"))
-	}
-	return p.Add(NewSimpleQB(".executionProfile()"))
+	return p.Add(NewSimpleQB(dialectOf(p.builders).profileStep()))
 }
 
 // Count adds .count(), to the query. The query call will return the number of entities found in the query.
@@ -8,14 +8,42 @@ import (
 
 type simpleQueryBuilder struct {
 	value string
+
+	// format and args are kept to be able to render the query with bindings
+	format string
+	args   []interface{}
 }
 
 func NewSimpleQB(format string, a ...interface{}) interfaces.QueryBuilder {
 	return &simpleQueryBuilder{
-		value: fmt.Sprintf(format, a...),
+		value:  fmt.Sprintf(format, a...),
+		format: format,
+		args:   a,
 	}
 }
 
 func (sqb *simpleQueryBuilder) String() string {
 	return sqb.value
 }
+
+// StringWithBindings returns the query where the values are replaced by generated binding names (e.g. _b0)
+// together with the according bindings.
+func (sqb *simpleQueryBuilder) StringWithBindings() (string, map[string]interface{}) {
+	return stringWithBindings(sqb)
+}
+
+func (sqb *simpleQueryBuilder) bindTo(bindings *queryBindings) string {
+	if len(sqb.args) == 0 {
+		return sqb.value
+	}
+
+	args := make([]interface{}, 0, len(sqb.args))
+	for _, arg := range sqb.args {
+		if builder, ok := arg.(bindable); ok {
+			args = append(args, builder.bindTo(bindings))
+			continue
+		}
+		args = append(args, arg)
+	}
+	return fmt.Sprintf(sqb.format, args...)
+}
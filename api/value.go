@@ -16,6 +16,30 @@ func (v *value) String() string {
 	return queryString
 }
 
+// StringWithBindings returns the query where the values are replaced by generated binding names (e.g. _b0)
+// together with the according bindings.
+func (v *value) StringWithBindings() (string, map[string]interface{}) {
+	return stringWithBindings(v)
+}
+
+func (v *value) bindTo(bindings *queryBindings) string {
+	return bindAllTo(v.builders, bindings)
+}
+
+// ValidatePartitionFilter returns ErrNoPartitionFilter in case the traversal is not scoped to a partition.
+// The validation is only done if the partition key of the graph is configured (see WithPartitionKey).
+func (v *value) ValidatePartitionFilter() error {
+	return validatePartitionFilter(v.builders, v.String())
+}
+
+func (v *value) graphOf() *graph {
+	return graphOf(v.builders)
+}
+
+func (v *value) steps() []interfaces.QueryBuilder {
+	return stepsOf(v.builders)
+}
+
 func NewValueV(e interfaces.Vertex) interfaces.Value {
 	queryBuilders := make([]interfaces.QueryBuilder, 0)
 	queryBuilders = append(queryBuilders, e)
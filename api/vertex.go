@@ -24,6 +24,30 @@ func (v *vertex) String() string {
 	return queryString
 }
 
+// StringWithBindings returns the query where the values are replaced by generated binding names (e.g. _b0)
+// together with the according bindings.
+func (v *vertex) StringWithBindings() (string, map[string]interface{}) {
+	return stringWithBindings(v)
+}
+
+func (v *vertex) bindTo(bindings *queryBindings) string {
+	return bindAllTo(v.builders, bindings)
+}
+
+// ValidatePartitionFilter returns ErrNoPartitionFilter in case the traversal is not scoped to a partition.
+// The validation is only done if the partition key of the graph is configured (see WithPartitionKey).
+func (v *vertex) ValidatePartitionFilter() error {
+	return validatePartitionFilter(v.builders, v.String())
+}
+
+func (v *vertex) graphOf() *graph {
+	return graphOf(v.builders)
+}
+
+func (v *vertex) steps() []interfaces.QueryBuilder {
+	return stepsOf(v.builders)
+}
+
 func NewVertexG(g interfaces.Graph) interfaces.Vertex {
 	queryBuilders := make([]interfaces.QueryBuilder, 0)
 	queryBuilders = append(queryBuilders, g)
@@ -88,20 +112,20 @@ func (v *vertex) Order() interfaces.Vertex {
 // Sort order is ascending per default.
 func (v *vertex) ByOrder(propertyName string, order ...interfaces.Order) interfaces.Vertex {
 	if len(order) == 0 {
-		return v.Add(NewSimpleQB(`.by("%s",%s)`, propertyName, toSortOrder(gUSE_COSMOS_DB_QUERY_LANGUAGE, interfaces.OrderAscending)))
+		return v.Add(NewSimpleQB(`.by("%s",%s)`, propertyName, toSortOrder(dialectOf(v.builders), interfaces.OrderAscending)))
 	}
 
-	return v.Add(NewSimpleQB(`.by("%s",%s)`, propertyName, toSortOrder(gUSE_COSMOS_DB_QUERY_LANGUAGE, order[0])))
+	return v.Add(NewSimpleQB(`.by("%s",%s)`, propertyName, toSortOrder(dialectOf(v.builders), order[0])))
 }
 
 // toSortOrder returns the sort order respecting the language differences between cosmos and tinkerpop gremlin dialect
-func toSortOrder(useCosmosDialect bool, order ...interfaces.Order) string {
+func toSortOrder(dialect QueryLanguage, order ...interfaces.Order) string {
 	sortOrder := interfaces.OrderAscending
 	if len(order) > 0 {
 		sortOrder = order[0]
 	}
 
-	if !useCosmosDialect {
+	if !dialect.usesCosmosDialect() {
 		return sortOrder.String()
 	}
 
@@ -202,12 +226,12 @@ func (v *vertex) Has(key string, value ...interface{}) interfaces.Vertex {
 		return v.Add(NewSimpleQB(".has(\"%s\")", key))
 	}
 
-	keyVal, err := toKeyValueString(key, value[0])
+	keyVal, err := toKeyValue(key, value[0])
 	if err != nil {
 		panic(errors.Wrapf(err, "cast has value %T to string failed (You could either implement the Stringer interface for this type or cast it to string beforehand)", value))
 	}
 
-	return v.Add(NewSimpleQB(".has%s", keyVal))
+	return v.Add(newFilterStep(NewSimpleQB(".has%s", keyVal), key))
 }
 
 // HasLabel adds .hasLabel([<label_1>,<label_2>,..,<label_n>]), e.g. .hasLabel('user','name'), to the query. The query call returns all vertices with the given label.
@@ -262,7 +286,7 @@ func (v *vertex) Drop() interfaces.QueryBuilder {
 
 // AddE adds .addE(<label>), to the query. The query call will be the first step to add an edge
 func (v *vertex) AddE(label string) interfaces.Edge {
-	v.Add(NewSimpleQB(".addE(\"%s\")", label))
+	v.Add(NewSimpleQB(".addE(%s)", bindQuoted(label)))
 	return NewEdgeV(v)
 }
 
@@ -273,16 +297,13 @@ func (v *vertex) BothE() interfaces.Edge {
 }
 
 func (v *vertex) Profile() interfaces.QueryBuilder {
-	if !gUSE_COSMOS_DB_QUERY_LANGUAGE {
-		return v.Add(NewSimpleQB(".profile()"))
-	}
-	return v.Add(NewSimpleQB(".executionProfile()"))
+	return v.Add(NewSimpleQB(dialectOf(v.builders).profileStep()))
 }
 
 // HasId adds .hasId('<id>'), e.g. .hasId('8aaaa410-dae1-4f33-8dd7-0217e69df10c'), to the query. The query call returns all vertices
 // with the given id.
 func (v *vertex) HasId(id string) interfaces.Vertex {
-	return v.Add(NewSimpleQB(".hasId(\"%s\")", id))
+	return v.Add(NewSimpleQB(".hasId(%s)", bindQuoted(id)))
 }
 
 // Cap adds .cap(<label>) to the query.
@@ -311,13 +332,13 @@ func (v *vertex) Count() interfaces.QueryBuilder {
 
 // PropertyList adds .property(list,"<key>","<value>"), e.g. .property(list, "name","hans"), to the query. The query call will add the given property.
 func (v *vertex) PropertyList(key, value string) interfaces.Vertex {
-	return v.Add(NewSimpleQB(".property(list,\"%s\",\"%s\")", key, Escape(value)))
+	return v.Add(NewSimpleQB(".property(list,%s,%s)", bindQuoted(key), bindString(value)))
 }
 
 // Property adds .property("<key>","<value>"), e.g. .property("name","hans") depending on the given type the quotes for the value are omitted.
 // e.g. .property("temperature",23.02) or .property("available",true)
 func (v *vertex) Property(key, value interface{}) interfaces.Vertex {
-	keyVal, err := toKeyValueString(key, value)
+	keyVal, err := toKeyValue(key, value)
 	if err != nil {
 		panic(errors.Wrapf(err, "cast property value %T to string failed (You could either implement the Stringer interface for this type or cast it to string beforehand)", value))
 	}
@@ -332,27 +353,38 @@ func (v *vertex) Property(key, value interface{}) interfaces.Vertex {
 // Depending on the given type of the value the quotes for the value are omitted.
 // e.g. ("temperature",23.02) or ("available",true)
 func toKeyValueString(key, value interface{}) (string, error) {
+	keyVal, err := toKeyValue(key, value)
+	if err != nil {
+		return "", err
+	}
+	return keyVal.String(), nil
+}
+
+// toKeyValue creates a query builder for the given key and value as a key/value pair (see toKeyValueString).
+// The key and the value can be replaced by bindings when the query is rendered using StringWithBindings.
+func toKeyValue(key, value interface{}) (interfaces.QueryBuilder, error) {
+	boundKey := bindQuoted(fmt.Sprintf("%s", key))
 	switch casted := value.(type) {
 	case *simpleQueryBuilder:
-		return fmt.Sprintf("(\"%s\",%s)", key, casted.String()), nil
+		return NewSimpleQB("(%s,%s)", boundKey, casted), nil
 	case string:
-		return fmt.Sprintf("(\"%s\",\"%s\")", key, Escape(casted)), nil
+		return NewSimpleQB("(%s,%s)", boundKey, bindString(casted)), nil
 	case bool:
-		return fmt.Sprintf("(\"%s\",%t)", key, casted), nil
+		return NewSimpleQB("(%s,%s)", boundKey, bindValue(casted, fmt.Sprintf("%t", casted))), nil
 	case int, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-		return fmt.Sprintf("(\"%s\",%d)", key, casted), nil
+		return NewSimpleQB("(%s,%s)", boundKey, bindValue(casted, fmt.Sprintf("%d", casted))), nil
 	case float64:
-		return fmt.Sprintf("(\"%s\",%f)", key, casted), nil
+		return NewSimpleQB("(%s,%s)", boundKey, bindValue(casted, fmt.Sprintf("%f", casted))), nil
 	case time.Time:
-		return fmt.Sprintf("(\"%s\",\"%s\")", key, casted.String()), nil
+		return NewSimpleQB("(%s,%s)", boundKey, bindValue(casted.String(), fmt.Sprintf("\"%s\"", casted.String()))), nil
 	case fmt.Stringer:
-		return fmt.Sprintf("(\"%s\",\"%s\")", key, Escape(casted.String())), nil
+		return NewSimpleQB("(%s,%s)", boundKey, bindString(casted.String())), nil
 	default:
 		fmt.Printf("[warn] Type %T is not supported in v.toKeyValueString() will try to cast to string\n", casted)
 		asStr, err := cast.ToStringE(casted)
 		if err != nil {
-			return "", errors.Wrapf(err, "cast %T to string failed (You could either implement the Stringer interface for this type or cast it to string beforehand)", casted)
+			return nil, errors.Wrapf(err, "cast %T to string failed (You could either implement the Stringer interface for this type or cast it to string beforehand)", casted)
 		}
-		return fmt.Sprintf("(\"%s\",\"%s\")", key, Escape(asStr)), nil
+		return NewSimpleQB("(%s,%s)", boundKey, bindString(asStr)), nil
 	}
 }
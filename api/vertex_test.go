@@ -973,12 +973,12 @@ func TestVertexAsMulti(t *testing.T) {
 }
 
 func TestToSortOrder(t *testing.T) {
-	assert.Equal(t, "asc", toSortOrder(false))
-	assert.Equal(t, "asc", toSortOrder(false, interfaces.OrderAscending))
-	assert.Equal(t, "desc", toSortOrder(false, interfaces.OrderDescending))
-	assert.Equal(t, "incr", toSortOrder(true))
-	assert.Equal(t, "incr", toSortOrder(true, interfaces.OrderAscending))
-	assert.Equal(t, "decr", toSortOrder(true, interfaces.OrderDescending))
+	assert.Equal(t, "asc", toSortOrder(QueryLanguageTinkerpopGremlin))
+	assert.Equal(t, "asc", toSortOrder(QueryLanguageTinkerpopGremlin, interfaces.OrderAscending))
+	assert.Equal(t, "desc", toSortOrder(QueryLanguageTinkerpopGremlin, interfaces.OrderDescending))
+	assert.Equal(t, "incr", toSortOrder(QueryLanguageCosmosDB))
+	assert.Equal(t, "incr", toSortOrder(QueryLanguageCosmosDB, interfaces.OrderAscending))
+	assert.Equal(t, "decr", toSortOrder(QueryLanguageCosmosDB, interfaces.OrderDescending))
 }
 
 func TestVertexByOrder(t *testing.T) {
@@ -0,0 +1,99 @@
+package gremcos
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// asyncStream forwards the responses of an asynchronous request to the caller as soon as they arrive.
+// Responses that might lead to a retry (e.g. throttled or failed ones) are held back until it is clear
+// that the request won't be retried. As soon as the first response was delivered, no further retry is done.
+type asyncStream struct {
+	responseChannel chan<- interfaces.AsyncResponse
+
+	// started is true as soon as the first attempt was started successfully
+	started bool
+	// delivered is true as soon as the first response was handed over to the caller
+	delivered bool
+	// pending are the responses of the current attempt that are held back
+	pending []interfaces.AsyncResponse
+}
+
+// push forwards the given response to the caller or holds it back in case it might lead to a retry.
+// To keep the order, all responses following a held back one are held back as well.
+func (s *asyncStream) push(response interfaces.AsyncResponse) {
+	if len(s.pending) > 0 || suggestsRetry(response) {
+		s.pending = append(s.pending, response)
+		return
+	}
+	s.deliver(response)
+}
+
+// reset drops the held back responses of the previous attempt
+func (s *asyncStream) reset() {
+	s.pending = nil
+}
+
+// flush delivers all held back responses
+func (s *asyncStream) flush() {
+	for _, response := range s.pending {
+		s.deliver(response)
+	}
+	s.pending = nil
+}
+
+// fail delivers the given error as final response
+func (s *asyncStream) fail(err error) {
+	s.pending = nil
+	s.deliver(interfaces.AsyncResponse{ErrorMessage: err.Error(), Err: err})
+}
+
+func (s *asyncStream) deliver(response interfaces.AsyncResponse) {
+	s.responseChannel <- response
+	s.delivered = true
+}
+
+// suggestsRetry returns true in case the given response might lead to a retry of the request
+func suggestsRetry(response interfaces.AsyncResponse) bool {
+	if response.Err != nil {
+		return true
+	}
+	retryInformation := extractRetryConditions([]interfaces.Response{response.Response})
+	return retryInformation.retry || retryInformation.retryOnNewConnection
+}
+
+// asyncResponseError returns the typed error of the given response, nil if there is none.
+// A cosmos error contained in the response takes precedence over the error message.
+func asyncResponseError(response interfaces.AsyncResponse) error {
+	if err := responseStatusError(response.Response); err != nil {
+		return err
+	}
+	if response.ErrorMessage != "" {
+		return errors.New(response.ErrorMessage)
+	}
+	return nil
+}
+
+// responseStatusError returns the error reported via the status of the given response (e.g. a CosmosError), nil if there is none
+func responseStatusError(response interfaces.Response) error {
+	if response.Status.Code == 0 {
+		return nil
+	}
+	return extractFirstError([]interfaces.Response{response})
+}
+
+// streamRetryPolicy prevents retries as soon as a response of the request was handed over to the caller,
+// since a retry would deliver the already received responses again.
+type streamRetryPolicy struct {
+	policy RetryPolicy
+	stream *asyncStream
+}
+
+func (p *streamRetryPolicy) ShouldRetry(attempt RetryAttempt) (bool, time.Duration) {
+	if p.stream.delivered {
+		return false, 0
+	}
+	return p.policy.ShouldRetry(attempt)
+}
@@ -0,0 +1,205 @@
+package gremcos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+const (
+	// bulkDefaultParallelism is the default number of mutations that are executed concurrently
+	bulkDefaultParallelism = 10
+	// bulkDefaultMaxRetries is the default number of times a mutation is retried if suggested by cosmos
+	bulkDefaultMaxRetries = 5
+)
+
+// BulkExecutor executes a stream of mutations concurrently with bounded parallelism.
+// It can be used to import a high volume of vertices and edges without writing a custom worker pool.
+//
+//	bulk := gremcos.NewBulkExecutor(cosmos, gremcos.BulkParallelism(20))
+//	report := bulk.Execute(mutations)
+//	log.Printf("%d/%d mutations succeeded, consumed %f RU (%f mutations/s)", report.Succeeded, len(report.Items), report.RequestCharge, report.Throughput)
+type BulkExecutor struct {
+	cosmos Cosmos
+
+	// parallelism is the maximum number of mutations executed at the same time
+	parallelism int
+	// retryPolicy decides whether a mutation is retried (e.g. in case it was throttled)
+	retryPolicy RetryPolicy
+	// requestOptions are applied to each mutation
+	requestOptions []RequestOption
+}
+
+// BulkOption is the struct for defining optional parameters for the BulkExecutor
+type BulkOption func(*BulkExecutor)
+
+// BulkParallelism sets the maximum number of mutations that are executed at the same time.
+// Hint: The number of concurrent requests is also limited by the size of the connection pool (see NumMaxActiveConnections).
+func BulkParallelism(parallelism int) BulkOption {
+	return func(b *BulkExecutor) {
+		b.parallelism = parallelism
+	}
+}
+
+// BulkRetryPolicy sets the policy that decides whether a mutation is retried.
+// Per default the DefaultRetryPolicy is used with up to 5 retries, which respects the wait time cosmos suggests
+// in case a mutation was throttled (429). Mutations that failed with 1007 or 1008 are only retried if they are marked
+// as idempotent (see BulkRequestOptions and Idempotent), since it is unknown whether cosmos has already applied them.
+// Otherwise they fail with an UnknownOutcomeError.
+func BulkRetryPolicy(policy RetryPolicy) BulkOption {
+	return func(b *BulkExecutor) {
+		b.retryPolicy = policy
+	}
+}
+
+// BulkRequestOptions sets options that are applied to each mutation, e.g. Idempotent.
+func BulkRequestOptions(options ...RequestOption) BulkOption {
+	return func(b *BulkExecutor) {
+		b.requestOptions = options
+	}
+}
+
+// NewBulkExecutor creates a new BulkExecutor that executes the mutations using the given cosmos connector.
+func NewBulkExecutor(cosmos Cosmos, options ...BulkOption) *BulkExecutor {
+	bulk := &BulkExecutor{
+		cosmos:      cosmos,
+		parallelism: bulkDefaultParallelism,
+		retryPolicy: DefaultRetryPolicy(bulkDefaultMaxRetries),
+	}
+
+	for _, opt := range options {
+		opt(bulk)
+	}
+
+	if bulk.parallelism <= 0 {
+		bulk.parallelism = 1
+	}
+	return bulk
+}
+
+// BulkItemResult is the result of one mutation executed by the BulkExecutor
+type BulkItemResult struct {
+	// Index is the position of the mutation in the stream, starting at 0.
+	Index int
+	// Mutation is the executed mutation.
+	Mutation interfaces.QueryBuilder
+	// Err is the error that occurred while executing the mutation, nil in case it succeeded.
+	Err error
+	// RequestCharge is the amount of request units (RU) consumed by the mutation, including all retries.
+	RequestCharge float32
+	// Retries is the number of times the mutation was retried.
+	Retries int
+	// Latency is the time it took to execute the mutation, including all retries.
+	Latency time.Duration
+}
+
+// BulkReport summarizes the execution of a stream of mutations by the BulkExecutor
+type BulkReport struct {
+	// Items contains the results of all mutations in the order of the stream.
+	Items []BulkItemResult
+	// Succeeded is the number of mutations that were executed successfully.
+	Succeeded int
+	// Failed is the number of mutations that failed.
+	Failed int
+	// RequestCharge is the total amount of request units (RU) consumed by all mutations.
+	RequestCharge float32
+	// Duration is the time it took to execute all mutations.
+	Duration time.Duration
+	// Throughput is the number of mutations executed per second.
+	Throughput float64
+}
+
+// Errors returns the results of all mutations that failed.
+func (r BulkReport) Errors() []BulkItemResult {
+	var failed []BulkItemResult
+	for _, item := range r.Items {
+		if item.Err != nil {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// bulkItem is a mutation together with its position in the stream
+type bulkItem struct {
+	index    int
+	mutation interfaces.QueryBuilder
+}
+
+// Execute executes all mutations received from the given channel until it is closed.
+// The call blocks until all mutations are executed and returns the according report.
+// Mutations that support bindings (see interfaces.QueryBuilderWithBindings) are sent as parameterized queries.
+func (b *BulkExecutor) Execute(mutations <-chan interfaces.QueryBuilder) BulkReport {
+	start := time.Now()
+
+	items := make(chan bulkItem)
+	results := make(chan BulkItemResult)
+
+	var workers sync.WaitGroup
+	workers.Add(b.parallelism)
+	for i := 0; i < b.parallelism; i++ {
+		go func() {
+			defer workers.Done()
+			for item := range items {
+				results <- b.executeOne(item)
+			}
+		}()
+	}
+
+	go func() {
+		index := 0
+		for mutation := range mutations {
+			items <- bulkItem{index: index, mutation: mutation}
+			index++
+		}
+		close(items)
+		workers.Wait()
+		close(results)
+	}()
+
+	report := BulkReport{}
+	for result := range results {
+		report.add(result)
+	}
+
+	report.Duration = time.Since(start)
+	if seconds := report.Duration.Seconds(); seconds > 0 {
+		report.Throughput = float64(len(report.Items)) / seconds
+	}
+	return report
+}
+
+// executeOne executes the given mutation and returns the according result
+func (b *BulkExecutor) executeOne(item bulkItem) BulkItemResult {
+	var diagnostics Diagnostics
+	options := make([]RequestOption, 0, len(b.requestOptions)+2)
+	options = append(options, b.requestOptions...)
+	options = append(options, UseRetryPolicy(b.retryPolicy), CollectDiagnostics(&diagnostics))
+
+	_, err := b.cosmos.ExecuteQueryWithBindings(item.mutation, options...)
+
+	return BulkItemResult{
+		Index:         item.index,
+		Mutation:      item.mutation,
+		Err:           err,
+		RequestCharge: diagnostics.RequestCharge,
+		Retries:       diagnostics.Retries,
+		Latency:       diagnostics.Latency,
+	}
+}
+
+// add adds the given result to the report, keeping the items in the order of the stream
+func (r *BulkReport) add(result BulkItemResult) {
+	for len(r.Items) <= result.Index {
+		r.Items = append(r.Items, BulkItemResult{})
+	}
+	r.Items[result.Index] = result
+
+	r.RequestCharge += result.RequestCharge
+	if result.Err != nil {
+		r.Failed++
+		return
+	}
+	r.Succeeded++
+}
@@ -0,0 +1,197 @@
+package gremcos
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/interfaces"
+	"go.uber.org/goleak"
+)
+
+func TestNewBulkExecutor(t *testing.T) {
+	// GIVEN
+	cosmos := &cosmosImpl{}
+	policy := DefaultRetryPolicy(1)
+
+	// WHEN
+	bulkDefault := NewBulkExecutor(cosmos)
+	bulk := NewBulkExecutor(cosmos, BulkParallelism(20), BulkRetryPolicy(policy), BulkRequestOptions(Idempotent()))
+	bulkInvalid := NewBulkExecutor(cosmos, BulkParallelism(-1))
+
+	// THEN
+	assert.Equal(t, bulkDefaultParallelism, bulkDefault.parallelism)
+	assert.Equal(t, DefaultRetryPolicy(bulkDefaultMaxRetries), bulkDefault.retryPolicy)
+	assert.Equal(t, 20, bulk.parallelism)
+	assert.Equal(t, policy, bulk.retryPolicy)
+	assert.Len(t, bulk.requestOptions, 1)
+	assert.Equal(t, 1, bulkInvalid.parallelism)
+}
+
+func TestBulkExecutor_Execute(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl)
+
+	success := func(requestCharge float32) []interfaces.Response {
+		return []interfaces.Response{
+			{
+				Status: interfaces.Status{
+					Code: interfaces.StatusSuccess,
+					Attributes: map[string]interface{}{
+						"x-ms-status-code":          200,
+						"x-ms-total-request-charge": requestCharge,
+					},
+				},
+			},
+		}
+	}
+	throttled := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code":          429,
+					"x-ms-substatus-code":       3200,
+					"x-ms-retry-after-ms":       "00:00:00.0010000",
+					"x-ms-total-request-charge": 0.5,
+				},
+			},
+		},
+	}
+	conflict := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code":          400,
+					"x-ms-total-request-charge": 1,
+				},
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().Execute(`g.addV("user").property("id","0")`).Return(success(10), nil)
+	gomock.InOrder(
+		queryExecutor.EXPECT().Execute(`g.addV("user").property("id","1")`).Return(throttled, nil),
+		queryExecutor.EXPECT().Execute(`g.addV("user").property("id","1")`).Return(success(10), nil),
+	)
+	queryExecutor.EXPECT().Execute(`g.addV("user").property("id","2")`).Return(conflict, nil)
+	queryExecutor.EXPECT().Execute(`g.addV("user").property("id","3")`).Return(nil, fmt.Errorf("connection lost"))
+
+	mutations := make(chan interfaces.QueryBuilder)
+	go func() {
+		defer close(mutations)
+		for i := 0; i < 4; i++ {
+			mutations <- rawQuery(fmt.Sprintf(`g.addV("user").property("id","%d")`, i))
+		}
+	}()
+
+	// WHEN
+	bulk := NewBulkExecutor(cosmos, BulkParallelism(2))
+	report := bulk.Execute(mutations)
+
+	// THEN
+	require.Len(t, report.Items, 4)
+	assert.Equal(t, 2, report.Succeeded)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, float32(21.5), report.RequestCharge)
+	assert.True(t, report.Duration > 0)
+	assert.True(t, report.Throughput > 0)
+
+	for i, item := range report.Items {
+		assert.Equal(t, i, item.Index)
+		assert.Equal(t, fmt.Sprintf(`g.addV("user").property("id","%d")`, i), item.Mutation.String())
+	}
+	assert.NoError(t, report.Items[0].Err)
+	assert.Equal(t, float32(10), report.Items[0].RequestCharge)
+	assert.NoError(t, report.Items[1].Err)
+	assert.Equal(t, 1, report.Items[1].Retries)
+	assert.Equal(t, float32(10.5), report.Items[1].RequestCharge)
+	assert.Error(t, report.Items[2].Err)
+	require.Error(t, report.Items[3].Err)
+	assert.Contains(t, report.Items[3].Err.Error(), "connection lost")
+
+	failed := report.Errors()
+	require.Len(t, failed, 2)
+	assert.Equal(t, 2, failed[0].Index)
+	assert.Equal(t, 3, failed[1].Index)
+}
+
+func TestBulkExecutor_Execute_ConnectionClosed(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl)
+
+	connectionClosed := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code": 1007,
+				},
+			},
+		},
+	}
+	success := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}
+
+	queryExecutor.EXPECT().Close().AnyTimes().Return(nil)
+	gomock.InOrder(
+		queryExecutor.EXPECT().Execute(`g.addV("user").property("id","0")`).Return(connectionClosed, nil),
+		queryExecutor.EXPECT().Execute(`g.addV("user").property("id","1")`).Return(connectionClosed, nil),
+		queryExecutor.EXPECT().Execute(`g.addV("user").property("id","1")`).Return(success, nil),
+	)
+
+	execute := func(bulk *BulkExecutor, query string) BulkReport {
+		mutations := make(chan interfaces.QueryBuilder, 1)
+		mutations <- rawQuery(query)
+		close(mutations)
+		return bulk.Execute(mutations)
+	}
+
+	// WHEN
+	unmarked := execute(NewBulkExecutor(cosmos), `g.addV("user").property("id","0")`)
+	idempotent := execute(NewBulkExecutor(cosmos, BulkRequestOptions(Idempotent())), `g.addV("user").property("id","1")`)
+
+	// THEN
+	require.Len(t, unmarked.Items, 1)
+	assert.True(t, IsUnknownOutcome(unmarked.Items[0].Err), "Expected mutations that are not marked as idempotent not to be retried")
+	require.Len(t, idempotent.Items, 1)
+	assert.NoError(t, idempotent.Items[0].Err)
+	assert.Equal(t, 1, idempotent.Items[0].Retries)
+}
+
+func TestBulkExecutor_Execute_Empty(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mutations := make(chan interfaces.QueryBuilder)
+	close(mutations)
+	bulk := NewBulkExecutor(&cosmosImpl{})
+
+	// WHEN
+	report := bulk.Execute(mutations)
+
+	// THEN
+	assert.Empty(t, report.Items)
+	assert.Equal(t, 0, report.Succeeded)
+	assert.Equal(t, 0, report.Failed)
+	assert.Equal(t, float64(0), report.Throughput)
+}
+
+// rawQuery is a QueryBuilder for the given query
+type rawQuery string
+
+func (q rawQuery) String() string {
+	return string(q)
+}
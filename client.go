@@ -53,6 +53,10 @@ type client struct {
 	// <RequestID string,codeChannel chan int>
 	responseStatusNotifier *sync.Map
 
+	// calls contains the call the events of a request are attributed to (see Observer)
+	// <RequestID string,call callTrace>
+	calls sync.Map
+
 	// stores the most recent error
 	lastError atomic.Value
 
@@ -73,6 +77,12 @@ type client struct {
 	once sync.Once
 
 	metrics clientMetrics
+
+	// observer is notified about the lifecycle of the connection and its requests
+	observer Observer
+
+	// mimeType defines the serializer the server uses for the responses
+	mimeType []byte
 }
 
 // clientOption is the struct for defining optional parameters for the Client
@@ -99,6 +109,13 @@ func WithMetrics(metrics clientMetrics) clientOption {
 	}
 }
 
+// SetMimeType sets the mime type of the requests, which defines the serializer the server uses for the responses (see MimeTypeGraphSONv2)
+func SetMimeType(mimeType string) clientOption {
+	return func(c *client) {
+		c.mimeType = []byte(mimeType)
+	}
+}
+
 func newClient(dialer interfaces.Dialer, options ...clientOption) *client {
 	client := &client{
 		conn:                   dialer,
@@ -110,6 +127,8 @@ func newClient(dialer interfaces.Dialer, options ...clientOption) *client {
 		quitChannel:            make(chan struct{}),
 		credentialProvider:     noCredentials{},
 		metrics:                &clientMetricsNop{},
+		observer:               NopObserver{},
+		mimeType:               MimeType,
 	}
 
 	for _, opt := range options {
@@ -128,7 +147,9 @@ func Dial(conn interfaces.Dialer, errorChannel chan error, options ...clientOpti
 	}
 	client := newClient(conn, options...)
 
+	start := time.Now()
 	err := client.conn.Connect()
+	observerOrNop(client.observer).OnDial(DialEvent{Duration: time.Since(start), Err: err})
 	if err != nil {
 		client.metrics.incrementConnectivityErrorCount()
 		return nil, errors.Wrapf(err, "dialer connecting")
@@ -185,7 +206,7 @@ func (c *client) IsConnected() bool {
 	return c.conn.IsConnected()
 }
 
-func (c *client) executeRequest(query string, bindings, rebindings *map[string]interface{}) ([]interfaces.Response, error) {
+func (c *client) executeRequest(call callTrace, query string, bindings, rebindings *map[string]interface{}) ([]interfaces.Response, error) {
 	var req request
 	var id string
 	var err error
@@ -200,17 +221,22 @@ func (c *client) executeRequest(query string, bindings, rebindings *map[string]i
 		return nil, err
 	}
 
-	msg, err := packageRequest(req)
+	msg, err := packageRequestAs(req, c.mimeType)
 	if err != nil {
 		return nil, err
 	}
 
 	c.responseNotifier.Store(id, newSafeCloseErrorChannel(1))
 	c.responseStatusNotifier.Store(id, newSafeCloseIntChannel(1))
+	c.calls.Store(id, call)
+	defer c.calls.Delete(id)
+	start := time.Now()
 	c.dispatchRequest(msg)
+	observerOrNop(c.observer).OnRequestStart(RequestStartEvent{CallID: call.id, Attempt: call.attempt, RequestID: id, Query: query})
 
 	// this call blocks until the response has been retrieved from the server
 	resp, err := c.retrieveResponse(id)
+	observerOrNop(c.observer).OnRequestEnd(RequestEndEvent{CallID: call.id, Attempt: call.attempt, RequestID: id, Duration: time.Since(start), Chunks: len(resp), Err: err})
 
 	if err != nil {
 		err = errors.Wrapf(err, "query: %s", query)
@@ -218,7 +244,7 @@ func (c *client) executeRequest(query string, bindings, rebindings *map[string]i
 	return resp, err
 }
 
-func (c *client) executeAsync(query string, bindings, rebindings *map[string]interface{}, responseChannel chan interfaces.AsyncResponse) (err error) {
+func (c *client) executeAsync(call callTrace, query string, bindings, rebindings *map[string]interface{}, responseChannel chan interfaces.AsyncResponse) (err error) {
 	var req request
 	var id string
 	if bindings != nil && rebindings != nil {
@@ -230,15 +256,23 @@ func (c *client) executeAsync(query string, bindings, rebindings *map[string]int
 		return
 	}
 
-	msg, err := packageRequest(req)
+	msg, err := packageRequestAs(req, c.mimeType)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 	c.responseNotifier.Store(id, newSafeCloseErrorChannel(1))
 	c.responseStatusNotifier.Store(id, newSafeCloseIntChannel(1))
+	c.calls.Store(id, call)
+	start := time.Now()
 	c.dispatchRequest(msg)
-	go c.retrieveResponseAsync(id, responseChannel)
+	observerOrNop(c.observer).OnRequestStart(RequestStartEvent{CallID: call.id, Attempt: call.attempt, RequestID: id, Query: query})
+
+	go func() {
+		defer c.calls.Delete(id)
+		chunks, err := c.retrieveResponseAsync(id, responseChannel)
+		observerOrNop(c.observer).OnRequestEnd(RequestEndEvent{CallID: call.id, Attempt: call.attempt, RequestID: id, Duration: time.Since(start), Chunks: chunks, Err: err})
+	}()
 	return
 }
 
@@ -253,7 +287,11 @@ func validateCredentials(username string, password string) error {
 	return nil
 }
 
-func (c *client) authenticate(requestID string) error {
+func (c *client) authenticate(requestID string) (err error) {
+	defer func() {
+		observerOrNop(c.observer).OnAuth(AuthEvent{CallID: c.callOf(requestID).id, RequestID: requestID, Err: err})
+	}()
+
 	username, err := c.credentialProvider.Username()
 	if err != nil {
 		return errors.Wrap(err, "obtaining username")
@@ -270,7 +308,7 @@ func (c *client) authenticate(requestID string) error {
 
 	req := prepareAuthRequest(requestID, username, password)
 
-	msg, err := packageRequest(req)
+	msg, err := packageRequestAs(req, c.mimeType)
 	if err != nil {
 		log.Println(err)
 		return err
@@ -285,7 +323,7 @@ func (c *client) ExecuteWithBindings(query string, bindings, rebindings map[stri
 	if !c.conn.IsConnected() {
 		return resp, ErrNoConnection
 	}
-	resp, err = c.executeRequest(query, &bindings, &rebindings)
+	resp, err = c.executeRequest(callTrace{}, query, &bindings, &rebindings)
 	return
 }
 
@@ -294,7 +332,7 @@ func (c *client) Execute(query string) (resp []interfaces.Response, err error) {
 	if !c.conn.IsConnected() {
 		return resp, ErrNoConnection
 	}
-	resp, err = c.executeRequest(query, nil, nil)
+	resp, err = c.executeRequest(callTrace{}, query, nil, nil)
 	return
 }
 
@@ -303,10 +341,37 @@ func (c *client) ExecuteAsync(query string, responseChannel chan interfaces.Asyn
 	if !c.conn.IsConnected() {
 		return ErrNoConnection
 	}
-	err = c.executeAsync(query, nil, nil, responseChannel)
+	err = c.executeAsync(callTrace{}, query, nil, nil, responseChannel)
 	return
 }
 
+// executeFor executes the given query like ExecuteWithBindings (Execute if bindings are nil).
+// The events of the request are attributed to the given call (see Observer).
+func (c *client) executeFor(call callTrace, query string, bindings, rebindings map[string]interface{}) (resp []interfaces.Response, err error) {
+	if !c.conn.IsConnected() {
+		return resp, ErrNoConnection
+	}
+	if bindings == nil && rebindings == nil {
+		return c.executeRequest(call, query, nil, nil)
+	}
+	return c.executeRequest(call, query, &bindings, &rebindings)
+}
+
+// executeAsyncFor executes the given query like ExecuteAsync. The events of the request are attributed to the given call (see Observer).
+func (c *client) executeAsyncFor(call callTrace, query string, responseChannel chan interfaces.AsyncResponse) (err error) {
+	if !c.conn.IsConnected() {
+		return ErrNoConnection
+	}
+	return c.executeAsync(call, query, nil, nil, responseChannel)
+}
+
+// callOf returns the call the events of the request with the given id are attributed to
+func (c *client) callOf(requestID string) callTrace {
+	call, _ := c.calls.Load(requestID)
+	trace, _ := call.(callTrace)
+	return trace
+}
+
 // ExecuteFileWithBindings takes a file path to a Gremlin script, sends it to Gremlin Server with bindings, and returns the result.
 func (c *client) ExecuteFileWithBindings(path string, bindings, rebindings map[string]interface{}) (resp []interfaces.Response, err error) {
 	if !c.conn.IsConnected() {
@@ -318,7 +383,7 @@ func (c *client) ExecuteFileWithBindings(path string, bindings, rebindings map[s
 		return
 	}
 	query := string(d)
-	resp, err = c.executeRequest(query, &bindings, &rebindings)
+	resp, err = c.executeRequest(callTrace{}, query, &bindings, &rebindings)
 	return
 }
 
@@ -333,7 +398,7 @@ func (c *client) ExecuteFile(path string) (resp []interfaces.Response, err error
 		return
 	}
 	query := string(d)
-	resp, err = c.executeRequest(query, nil, nil)
+	resp, err = c.executeRequest(callTrace{}, query, nil, nil)
 	return
 }
 
@@ -351,6 +416,7 @@ func (c *client) Close() error {
 // safeClose encapsulates the cleanup logic that enables failed workers to clean up after them. It is called by the deferred workerSaveExit
 func (c *client) safeClose() error {
 	var err error
+	closed := false
 
 	// ensure that the channels are only closed once
 	c.once.Do(func() {
@@ -376,7 +442,12 @@ func (c *client) safeClose() error {
 		} else {
 			err = c.conn.Close()
 		}
+		closed = true
 	})
+
+	if closed {
+		observerOrNop(c.observer).OnConnectionClosed(ConnectionClosedEvent{Reason: c.LastError(), Err: err})
+	}
 	return err
 }
 
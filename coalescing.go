@@ -0,0 +1,130 @@
+package gremcos
+
+import (
+	"sync"
+
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// coalescer lets identical read requests that are in flight at the same time share one execution (singleflight)
+type coalescer struct {
+	// metrics is used to count the coalesced requests (optional)
+	metrics *Metrics
+
+	mux sync.Mutex
+	// flights are the executions in flight by request key
+	flights map[string]*flight
+}
+
+// flight is one execution that is shared by identical requests
+type flight struct {
+	// done is closed as soon as the responses and the error are available
+	done      chan struct{}
+	responses []interfaces.Response
+	err       error
+	// followers is the number of requests waiting for this execution
+	followers int
+}
+
+// CoalesceReads lets identical read-only requests (same query, bindings and operation name) that are issued
+// while one of them is in flight share its execution, including its retries, and its result.
+// This saves request units and connections in case many goroutines issue the same read at once.
+// Mutations are never coalesced. A read issued after a mutation completed never joins an execution started before it.
+// An asynchronous mutation (ExecuteAsync) is completed as soon as its response channel is closed.
+// Asynchronous requests (ExecuteAsync) and requests using the options UseRetryPolicy or CollectDiagnostics are not coalesced.
+// The latency, request charge and server time are recorded only once for the shared execution.
+// The waiting requests get the result of the shared execution, including its error. In case the execution fails because
+// the read or retry timeout (see QueryTimeouts and AutomaticRetries) was reached or the connection was lost, they get
+// the same error (e.g. IsNetworkErr holds for it). Since the coalesced requests share the retry timeout and joined
+// the execution after it was started, they never wait longer than for an execution of their own.
+// In case the execution is aborted without a result (e.g. it panics), they get ErrSharedExecutionAborted.
+// A failed execution is never shared with requests issued after it returned, they start a new one.
+func CoalesceReads() Option {
+	return func(c *cosmosImpl) {
+		c.coalescer = newCoalescer()
+	}
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{flights: make(map[string]*flight)}
+}
+
+// intercept is the interceptor that lets identical read requests share one execution
+func (c *coalescer) intercept(next ExecuteFunc) ExecuteFunc {
+	return func(request *Request) ([]interfaces.Response, error) {
+		if !isReadOnlyQuery(request.Query) {
+			// reads issued after the mutation must not get a result read before it
+			if request.IsAsync() {
+				// the asynchronous mutation is only done as soon as the response channel is closed
+				request.ResponseChannel = c.forgetAllWhenDone(request.ResponseChannel)
+				return next(request)
+			}
+			defer c.forgetAll()
+			return next(request)
+		}
+
+		reqOptions := newRequestOptions(request.Options...)
+		if request.IsAsync() || reqOptions.retryPolicy != nil || reqOptions.diagnostics != nil {
+			return next(request)
+		}
+
+		key, err := cacheKey(request)
+		if err != nil {
+			return next(request)
+		}
+		key = reqOptions.operation + "\x00" + key
+
+		c.mux.Lock()
+		if f, ok := c.flights[key]; ok {
+			f.followers++
+			c.mux.Unlock()
+			if c.metrics != nil {
+				c.metrics.requestCoalescedTotal.Inc()
+			}
+			<-f.done
+			return append([]interfaces.Response{}, f.responses...), f.err
+		}
+		f := &flight{done: make(chan struct{})}
+		c.flights[key] = f
+		c.mux.Unlock()
+
+		defer c.land(key, f)
+		// the followers get this error in case the execution panics
+		f.err = ErrSharedExecutionAborted
+		f.responses, f.err = next(request)
+		return f.responses, f.err
+	}
+}
+
+// land removes the given flight and hands over its result to the waiting requests
+func (c *coalescer) land(key string, f *flight) {
+	c.mux.Lock()
+	// the flight might already be removed by a mutation
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+	c.mux.Unlock()
+	close(f.done)
+}
+
+// forgetAll removes all flights, hence following requests start a new execution.
+// The requests already waiting for a flight still get its result.
+func (c *coalescer) forgetAll() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.flights = make(map[string]*flight)
+}
+
+// forgetAllWhenDone returns a channel that forwards the responses of an asynchronous mutation to the given channel.
+// All flights are removed as soon as the returned channel is closed, before the given channel is closed.
+func (c *coalescer) forgetAllWhenDone(responseChannel chan interfaces.AsyncResponse) chan interfaces.AsyncResponse {
+	forwarded := make(chan interfaces.AsyncResponse, cap(responseChannel))
+	go func() {
+		defer close(responseChannel)
+		for response := range forwarded {
+			responseChannel <- response
+		}
+		c.forgetAll()
+	}()
+	return forwarded
+}
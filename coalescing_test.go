@@ -0,0 +1,335 @@
+package gremcos
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/supplyon/gremcos/interfaces"
+	mock_metrics "github.com/supplyon/gremcos/test/mocks/metrics"
+)
+
+// waitForFollowers blocks until the given number of requests wait for the flight of the given query
+func waitForFollowers(t *testing.T, c *coalescer, query string, followers int) {
+	key, err := cacheKey(&Request{Query: query})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		f, ok := c.flights["\x00"+key]
+		return ok && f.followers == followers
+	}, time.Second, time.Millisecond)
+}
+
+// blockingExecute returns an ExecuteFunc that counts its calls and blocks until release is closed
+func blockingExecute(calls *int32, release chan struct{}, responses []interfaces.Response, err error) ExecuteFunc {
+	return func(request *Request) ([]interfaces.Response, error) {
+		atomic.AddInt32(calls, 1)
+		<-release
+		return responses, err
+	}
+}
+
+func TestCosmosImpl_CoalesceReads(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl)
+	coalescedTotal := mock_metrics.NewMockCounter(mockCtrl)
+	coalescedTotal.EXPECT().Inc().Times(4)
+	cosmos.metrics.requestCoalescedTotal = coalescedTotal
+	CoalesceReads()(cosmos)
+	cosmos.coalescer.metrics = cosmos.metrics
+	cosmos.interceptors = append(cosmos.interceptors, cosmos.coalescer.intercept)
+
+	query := "g.V().hasLabel('user')"
+	release := make(chan struct{})
+	queryExecutor.EXPECT().Execute(query).DoAndReturn(func(q string) ([]interfaces.Response, error) {
+		<-release
+		return successResponses("shared"), nil
+	})
+
+	// WHEN
+	var wg sync.WaitGroup
+	results := make([][]interfaces.Response, 5)
+	errs := make([]error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = cosmos.Execute(query)
+		}(i)
+	}
+	waitForFollowers(t, cosmos.coalescer, query, 4)
+	close(release)
+	wg.Wait()
+
+	// THEN
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, successResponses("shared"), results[i])
+	}
+	assert.Empty(t, cosmos.coalescer.flights)
+}
+
+func TestCosmosImpl_CoalesceReads_TimeoutIsSharedWithFollowers(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl, CoalesceReads())
+	cosmos.interceptors = append(cosmos.interceptors, cosmos.coalescer.intercept)
+
+	query := "g.V().hasLabel('user')"
+	release := make(chan struct{})
+	timeout := myNetError("i/o timeout")
+	gomock.InOrder(
+		queryExecutor.EXPECT().Execute(query).DoAndReturn(func(q string) ([]interfaces.Response, error) {
+			<-release
+			return nil, timeout
+		}),
+		queryExecutor.EXPECT().Execute(query).Return(successResponses("after"), nil),
+	)
+
+	// WHEN
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = cosmos.Execute(query)
+		}(i)
+	}
+	waitForFollowers(t, cosmos.coalescer, query, 2)
+	close(release)
+	wg.Wait()
+	responsesAfter, errAfter := cosmos.Execute(query)
+
+	// THEN
+	for _, err := range errs {
+		assert.ErrorIs(t, err, timeout)
+		assert.True(t, IsNetworkErr(err))
+	}
+	assert.NoError(t, errAfter)
+	assert.Equal(t, successResponses("after"), responsesAfter)
+	assert.Empty(t, cosmos.coalescer.flights)
+}
+
+func TestCoalescer_DifferentRequestsAreNotCoalesced(t *testing.T) {
+	// GIVEN
+	c := newCoalescer()
+	var calls int32
+	execute := c.intercept(func(request *Request) ([]interfaces.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	})
+
+	// WHEN
+	_, _ = execute(&Request{Query: "g.V().has('name',name)", Bindings: map[string]interface{}{"name": "alice"}})
+	_, _ = execute(&Request{Query: "g.V().has('name',name)", Bindings: map[string]interface{}{"name": "bob"}})
+	_, _ = execute(&Request{Query: "g.V()", Options: []RequestOption{OperationName("list")}})
+	_, _ = execute(&Request{Query: "g.V()", Options: []RequestOption{CollectDiagnostics(&Diagnostics{})}})
+	_, _ = execute(&Request{Query: "g.V()", Options: []RequestOption{UseRetryPolicy(DefaultRetryPolicy(0))}})
+
+	// THEN
+	assert.Equal(t, int32(5), calls)
+	assert.Empty(t, c.flights)
+}
+
+func TestCoalescer_MutationsAreNotCoalesced(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	c := newCoalescer()
+	var calls int32
+	release := make(chan struct{})
+	execute := c.intercept(blockingExecute(&calls, release, nil, nil))
+	mutation := "g.addV('user').property('name','x')"
+
+	// WHEN
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = execute(&Request{Query: mutation})
+		}()
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 3 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// THEN
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestCoalescer_ReadAfterMutationStartsNewExecution(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	c := newCoalescer()
+	query := "g.V().hasLabel('user')"
+	var calls int32
+	release := make(chan struct{})
+	read := c.intercept(blockingExecute(&calls, release, successResponses("before"), nil))
+	mutate := c.intercept(func(request *Request) ([]interfaces.Response, error) { return nil, nil })
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = read(&Request{Query: query})
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond)
+
+	// WHEN
+	_, err := mutate(&Request{Query: "g.V().hasLabel('user').drop()"})
+	require.NoError(t, err)
+	after := c.intercept(func(request *Request) ([]interfaces.Response, error) { return successResponses("after"), nil })
+	responses, err := after(&Request{Query: query})
+	close(release)
+	wg.Wait()
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, successResponses("after"), responses)
+	assert.Empty(t, c.flights)
+}
+
+func TestCoalescer_ReadAfterAsyncMutationFinishedStartsNewExecution(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	c := newCoalescer()
+	query := "g.V().hasLabel('user')"
+	var calls int32
+	release := make(chan struct{})
+	read := c.intercept(blockingExecute(&calls, release, successResponses("before"), nil))
+	streamed := make(chan struct{})
+	mutate := c.intercept(func(request *Request) ([]interfaces.Response, error) {
+		go func() {
+			request.ResponseChannel <- interfaces.AsyncResponse{Response: successResponses("mutation")[0]}
+			<-streamed
+			close(request.ResponseChannel)
+		}()
+		return nil, nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = read(&Request{Query: query})
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond)
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	_, err := mutate(&Request{Query: "g.V().hasLabel('user').drop()", ResponseChannel: responseChannel})
+	require.NoError(t, err)
+	c.mux.Lock()
+	flightsWhileStreaming := len(c.flights)
+	c.mux.Unlock()
+	close(streamed)
+
+	var responses []interfaces.Response
+	for response := range responseChannel {
+		responses = append(responses, response.Response)
+	}
+	after := c.intercept(func(request *Request) ([]interfaces.Response, error) { return successResponses("after"), nil })
+	afterResponses, err := after(&Request{Query: query})
+	close(release)
+	wg.Wait()
+
+	// THEN
+	assert.Equal(t, 1, flightsWhileStreaming)
+	assert.Equal(t, successResponses("mutation"), responses)
+	require.NoError(t, err)
+	assert.Equal(t, successResponses("after"), afterResponses)
+	assert.Empty(t, c.flights)
+}
+
+func TestCoalescer_ErrorIsSharedButNotRetained(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	c := newCoalescer()
+	query := "g.V()"
+	var calls int32
+	release := make(chan struct{})
+	execute := c.intercept(blockingExecute(&calls, release, nil, fmt.Errorf("retry timeout reached")))
+
+	// WHEN
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = execute(&Request{Query: query})
+		}(i)
+	}
+	waitForFollowers(t, c, query, 2)
+	close(release)
+	wg.Wait()
+	_, errAfter := execute(&Request{Query: query})
+
+	// THEN
+	for _, err := range errs {
+		assert.EqualError(t, err, "retry timeout reached")
+	}
+	assert.Error(t, errAfter)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestCoalescer_PanicDoesNotBlockFollowers(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	c := newCoalescer()
+	query := "g.V()"
+	release := make(chan struct{})
+	execute := c.intercept(func(request *Request) ([]interfaces.Response, error) {
+		<-release
+		panic("boom")
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() { _ = recover() }()
+		_, _ = execute(&Request{Query: query})
+	}()
+	require.Eventually(t, func() bool {
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		return len(c.flights) == 1
+	}, time.Second, time.Millisecond)
+
+	// WHEN
+	var err error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err = execute(&Request{Query: query})
+	}()
+	waitForFollowers(t, c, query, 1)
+	close(release)
+	wg.Wait()
+
+	// THEN
+	assert.ErrorIs(t, err, ErrSharedExecutionAborted)
+	assert.Empty(t, c.flights)
+}
@@ -6,24 +6,32 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/rs/zerolog"
 	"github.com/supplyon/gremcos/interfaces"
+	m "github.com/supplyon/gremcos/metrics"
 )
 
 // Cosmos is an abstraction of the CosmosDB
 type Cosmos interface {
 	// ExecuteQuery executes the given query and returns the according responses from the CosmosDB
-	ExecuteQuery(query interfaces.QueryBuilder) ([]interfaces.Response, error)
+	ExecuteQuery(query interfaces.QueryBuilder, options ...RequestOption) ([]interfaces.Response, error)
+
+	// ExecuteQueryWithBindings executes the given query as parameterized query and returns the according responses from the CosmosDB.
+	// The values of the query are sent as bindings instead of being inlined into the query (see interfaces.QueryBuilderWithBindings).
+	// Query builders that do not support bindings are executed like in ExecuteQuery.
+	ExecuteQueryWithBindings(query interfaces.QueryBuilder, options ...RequestOption) ([]interfaces.Response, error)
 
 	// Execute can be used to execute a raw query (string). This can be used to issue queries that are not yet supported by the QueryBuilder.
-	Execute(query string) ([]interfaces.Response, error)
+	Execute(query string, options ...RequestOption) ([]interfaces.Response, error)
 
-	// ExecuteAsync can be used to issue a query and streaming in the responses as they are available / are provided by the CosmosDB
-	ExecuteAsync(query string, responseChannel chan interfaces.AsyncResponse) (err error)
+	// ExecuteAsync can be used to issue a query and streaming in the responses as they are available / are provided by the CosmosDB.
+	// The request is only retried until the first response was delivered. Errors are delivered as AsyncResponse.Err.
+	ExecuteAsync(query string, responseChannel chan interfaces.AsyncResponse, options ...RequestOption) (err error)
 
 	// ExecuteWithBindings can be used to execute a raw query (string) with optional bindings/rebindings. This can be used to issue queries that are not yet supported by the QueryBuilder.
-	ExecuteWithBindings(path string, bindings, rebindings map[string]interface{}) (resp []interfaces.Response, err error)
+	ExecuteWithBindings(path string, bindings, rebindings map[string]interface{}, options ...RequestOption) (resp []interfaces.Response, err error)
 
 	// IsConnected returns true in case the connection to the CosmosDB is up, false otherwise.
 	IsConnected() bool
@@ -36,6 +44,10 @@ type Cosmos interface {
 
 	// IsHealthy returns nil in case the connection to the CosmosDB is up, the according error otherwise.
 	IsHealthy() error
+
+	// ServerInfo returns the flavour and version of the gremlin server the connector is connected to.
+	// The server is only detected in case the connector was created using the option DetectServer.
+	ServerInfo() ServerInfo
 }
 
 // cosmos is a connector that can be used to connect to and interact with a CosmosDB
@@ -51,6 +63,15 @@ type cosmosImpl struct {
 	numMaxActiveConnections int
 	connectionIdleTimeout   time.Duration
 
+	// healthCheckInterval is the interval in which idle connections of the pool are checked in the background.
+	// A value of 0 disables the background health check.
+	healthCheckInterval time.Duration
+	// validateOnBorrow defines whether idle connections are pinged before they are used for a request.
+	validateOnBorrow bool
+	// maxConcurrentRequestsPerConnection is the maximum number of requests executed on one connection at the same time.
+	// A value of 0 means that each connection is used exclusively by one request.
+	maxConcurrentRequestsPerConnection int
+
 	// readTimeout specifies the amount of time a query can last until the response is completely fetched at the client.
 	readTimeout time.Duration
 
@@ -63,6 +84,14 @@ type cosmosImpl struct {
 
 	// metrics for cosmos
 	metrics *Metrics
+	// metricsPrefix is the namespace of the metrics
+	metricsPrefix string
+	// metricsRegisterer is the prometheus registerer the metrics are registered at
+	metricsRegisterer prometheus.Registerer
+	// metricsConstLabels are added to all metrics (e.g. the cosmos account or graph)
+	metricsConstLabels prometheus.Labels
+	// metricsFactory creates the metrics, if nil prometheus metrics are created
+	metricsFactory m.Factory
 
 	wg sync.WaitGroup
 
@@ -72,6 +101,43 @@ type cosmosImpl struct {
 	maxRetries int
 	// defines the max duration a request should be retried
 	retryTimeout time.Duration
+	// defines whether idempotent requests are retried on connectivity errors
+	retryOnConnectivityErrors bool
+	// defines whether and how requests are retried, if nil the DefaultRetryPolicy based on maxRetries is used
+	retryPolicy RetryPolicy
+
+	// requestUnitsPerSecond is the maximum of request units (RU) per second the requests should consume.
+	// A value of 0 disables the client-side rate limiting.
+	requestUnitsPerSecond float64
+	// ruLimiter paces the requests to stay below requestUnitsPerSecond (nil if disabled)
+	ruLimiter *ruLimiter
+
+	// queryLog logs slow, expensive and failed queries (nil if disabled)
+	queryLog *queryLogger
+
+	// hedging sends read requests a second time in case they are slow (nil if disabled)
+	hedging *hedging
+
+	// detectServer defines whether the gremlin server is detected while the connector is created
+	detectServer bool
+	// configureSerializer defines whether the mime type of the detected server is used (see ServerInfo.MimeType)
+	configureSerializer bool
+	// mimeType is the mime type used by the connections, empty for the default (see MimeType)
+	mimeType string
+	// serverInfo describes the detected gremlin server
+	serverInfo ServerInfo
+
+	// observer is notified about the lifecycle of connections and requests (nil if disabled)
+	observer Observer
+
+	// interceptors wrap the execution of all requests, the first one is the outermost one
+	interceptors []Interceptor
+
+	// queryCache caches the responses of read-only queries (nil if disabled)
+	queryCache *QueryCache
+
+	// coalescer lets identical concurrent read requests share one execution (nil if disabled)
+	coalescer *coalescer
 }
 
 type websocketGeneratorFun func(host string, options ...optionWebsocket) (interfaces.Dialer, error)
@@ -121,6 +187,17 @@ func ConnectionIdleTimeout(timeout time.Duration) Option {
 	}
 }
 
+// ConnectionHealthCheck enables a background health check of the idle connections in the pool.
+// Each interval all idle connections are pinged. Connections that failed the ping, have an error or
+// exceeded the idle timeout (see ConnectionIdleTimeout) are closed and removed from the pool.
+// If validateOnBorrow is true, idle connections are additionally pinged before they are used for a request.
+func ConnectionHealthCheck(interval time.Duration, validateOnBorrow bool) Option {
+	return func(c *cosmosImpl) {
+		c.healthCheckInterval = interval
+		c.validateOnBorrow = validateOnBorrow
+	}
+}
+
 // QueryTimeouts specifies the timeouts for executing a query.
 // readTimeout specifies the amount of time a query can last until the response is completely fetched at the client.
 // writeTimeout specifies the amount of time its allowed to take to send the query and all related data to the server.
@@ -138,12 +215,55 @@ func NumMaxActiveConnections(numMaxActiveConnections int) Option {
 	}
 }
 
+// MultiplexConnections enables sharing connections between concurrent requests.
+// Per default each request uses a connection of the pool exclusively, which limits the number of concurrent
+// requests to the number of active connections (see NumMaxActiveConnections).
+// With multiplexing each request is executed on the least loaded connection that serves less than
+// maxConcurrentRequestsPerConnection requests. A new connection is only dialed if all connections are fully loaded.
+// Hence NumMaxActiveConnections then limits the number of sockets and
+// NumMaxActiveConnections * maxConcurrentRequestsPerConnection the number of concurrent requests.
+func MultiplexConnections(maxConcurrentRequestsPerConnection int) Option {
+	return func(c *cosmosImpl) {
+		c.maxConcurrentRequestsPerConnection = maxConcurrentRequestsPerConnection
+	}
+}
+
 // MetricsPrefix can be used to customize the metrics prefix
 // as needed for a specific service. Per default 'gremcos' is used
 // as prefix.
 func MetricsPrefix(prefix string) Option {
 	return func(c *cosmosImpl) {
-		c.metrics = NewMetrics(prefix)
+		c.metricsPrefix = prefix
+	}
+}
+
+// MetricsRegisterer sets the prometheus registerer the metrics are registered at.
+// Per default the prometheus.DefaultRegisterer is used.
+func MetricsRegisterer(registerer prometheus.Registerer) Option {
+	return func(c *cosmosImpl) {
+		c.metricsRegisterer = registerer
+	}
+}
+
+// MetricsConstLabels sets labels that are added to all metrics, e.g. the cosmos account or graph.
+// This allows to use several cosmos connectors within one process whose metrics can be distinguished.
+//
+//	gremcos.New(host, gremcos.MetricsConstLabels(map[string]string{"graph": "users"}))
+func MetricsConstLabels(labels map[string]string) Option {
+	return func(c *cosmosImpl) {
+		c.metricsConstLabels = labels
+	}
+}
+
+// MetricsFactory sets the factory the metrics are created with. This allows to report the metrics to
+// other backends than prometheus, e.g. expvar or StatsD. In this case MetricsPrefix, MetricsRegisterer and
+// MetricsConstLabels are ignored, the namespace and labels are defined by the factory.
+//
+//	statsd, err := metrics.NewStatsDFactory("127.0.0.1:8125", "myservice", "cosmos")
+//	gremcos.New(host, gremcos.MetricsFactory(statsd))
+func MetricsFactory(factory m.Factory) Option {
+	return func(c *cosmosImpl) {
+		c.metricsFactory = factory
 	}
 }
 
@@ -180,6 +300,41 @@ func AutomaticRetries(maxRetries int, timeout time.Duration) Option {
 	}
 }
 
+// WithRetryPolicy sets the policy that decides whether and how long to wait before a request is retried.
+// Per default the DefaultRetryPolicy is used, with the number of retries specified via AutomaticRetries.
+// The overall duration of retries is limited by the timeout specified via AutomaticRetries (default 30s).
+// The policy can be overridden for single requests (see UseRetryPolicy).
+//	New("wss://example.com", WithRetryPolicy(gremcos.ExponentialBackoff(3, time.Millisecond*100, time.Second*5)))
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *cosmosImpl) {
+		c.retryPolicy = policy
+	}
+}
+
+// RequestUnitRateLimit enables a client-side rate limiter that paces the requests to stay below the given request units (RU) per second.
+// This should be set to the throughput provisioned for the graph in order to avoid requests being throttled by cosmos (429).
+// The limiter learns the costs of the queries based on the request charges reported by cosmos. In case cosmos
+// throttles requests nevertheless (e.g. since the throughput is shared), the limiter reduces the rate and
+// increases it again slowly as long as no requests are throttled.
+func RequestUnitRateLimit(requestUnitsPerSecond float64) Option {
+	return func(c *cosmosImpl) {
+		c.requestUnitsPerSecond = requestUnitsPerSecond
+	}
+}
+
+// RetryOnConnectivityErrors enables retries of requests that failed due to connectivity issues (e.g. a broken socket,
+// no connection or a failure while dialing a new connection, see IsNetworkErr).
+// Since it is unknown whether the server has already executed such a request, only requests that are safe to retry are retried.
+// These are requests marked as idempotent (see Idempotent) or read-only (see ReadOnly) and queries without mutating steps
+// (e.g. addV, property, drop). Other requests that failed after they were sent fail with an UnknownOutcomeError instead.
+// The retries are limited as specified via AutomaticRetries.
+//	cosmos.Execute("g.V().hasLabel('user')", gremcos.Idempotent())
+func RetryOnConnectivityErrors() Option {
+	return func(c *cosmosImpl) {
+		c.retryOnConnectivityErrors = true
+	}
+}
+
 // New creates a new instance of the Cosmos (-DB connector)
 func New(host string, options ...Option) (Cosmos, error) {
 	cosmos := &cosmosImpl{
@@ -189,6 +344,8 @@ func New(host string, options ...Option) (Cosmos, error) {
 		numMaxActiveConnections: 10,
 		connectionIdleTimeout:   time.Second * 30,
 		metrics:                 nil,
+		metricsPrefix:           "gremcos",
+		metricsRegisterer:       prometheus.DefaultRegisterer,
 		websocketGenerator:      NewWebsocket,
 		credentialProvider:      noCredentials{},
 		readTimeout:             15 * time.Second,
@@ -199,13 +356,61 @@ func New(host string, options ...Option) (Cosmos, error) {
 		opt(cosmos)
 	}
 
-	// if metrics not set via MetricsPrefix instantiate the metrics
-	// using the default prefix
+	// if no retry timeout is set via AutomaticRetries but a retry policy is used
+	if cosmos.retryTimeout <= 0 {
+		cosmos.retryTimeout = time.Second * 30
+	}
+
+	// if metrics not injected instantiate the metrics
+	// using the configured factory or prefix, registerer and labels
+	if cosmos.metrics == nil && cosmos.metricsFactory != nil {
+		metrics, err := NewMetricsFromFactory(cosmos.metricsFactory)
+		if err != nil {
+			return nil, err
+		}
+		cosmos.metrics = metrics
+	}
 	if cosmos.metrics == nil {
-		cosmos.metrics = NewMetrics("gremcos")
+		metrics, err := NewMetricsWith(cosmos.metricsRegisterer, cosmos.metricsPrefix, cosmos.metricsConstLabels)
+		if err != nil {
+			return nil, err
+		}
+		cosmos.metrics = metrics
+	}
+
+	if cosmos.queryLog != nil {
+		cosmos.queryLog.logger = cosmos.logger
+	}
+
+	if cosmos.requestUnitsPerSecond < 0 {
+		return nil, fmt.Errorf("requestUnitsPerSecond has to be >=0")
+	}
+
+	if err := cosmos.hedging.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cosmos.queryCache.validate(); err != nil {
+		return nil, err
 	}
 
-	pool, err := NewPool(cosmos.dial, cosmos.numMaxActiveConnections, cosmos.connectionIdleTimeout, cosmos.logger)
+	// the query cache is the innermost interceptor, hence it sees the requests as modified by the other ones
+	if cosmos.queryCache != nil {
+		cosmos.queryCache.metrics = cosmos.metrics
+		cosmos.interceptors = append(cosmos.interceptors, cosmos.queryCache.intercept)
+	}
+
+	// the coalescer is applied after the query cache, hence only cache misses share an execution
+	if cosmos.coalescer != nil {
+		cosmos.coalescer.metrics = cosmos.metrics
+		cosmos.interceptors = append(cosmos.interceptors, cosmos.coalescer.intercept)
+	}
+
+	if cosmos.requestUnitsPerSecond > 0 {
+		cosmos.ruLimiter = newRULimiter(cosmos.requestUnitsPerSecond, cosmos.metrics)
+	}
+
+	pool, err := cosmos.newPool(cosmos.mimeType)
 	if err != nil {
 		return nil, err
 	}
@@ -226,11 +431,33 @@ func New(host string, options ...Option) (Cosmos, error) {
 		cosmos.logger.Debug().Msg("Error channel consumer closed")
 	}()
 
+	if cosmos.detectServer {
+		cosmos.serverInfo = cosmos.detectServerInfo()
+		cosmos.logger.Info().Str("flavour", string(cosmos.serverInfo.Flavour)).Str("version", cosmos.serverInfo.Version).Msg("Gremlin server detected")
+
+		if cosmos.configureSerializer && cosmos.serverInfo.Flavour != ServerFlavourUnknown {
+			if err := cosmos.useSerializer(cosmos.serverInfo.MimeType()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return cosmos, nil
 }
 
-// dial creates new connections. It is called by the pool in case a new connection is demanded.
-func (c *cosmosImpl) dial() (interfaces.QueryExecutor, error) {
+// newPool creates the connection pool. The connections use the given mime type (empty for the default).
+func (c *cosmosImpl) newPool(mimeType string) (*pool, error) {
+	return NewPool(func() (interfaces.QueryExecutor, error) { return c.dial(mimeType) }, c.numMaxActiveConnections, c.connectionIdleTimeout, c.logger,
+		PoolHealthCheckInterval(c.healthCheckInterval),
+		PoolValidateOnBorrow(c.validateOnBorrow),
+		PoolMaxConcurrentRequestsPerConnection(c.maxConcurrentRequestsPerConnection),
+		PoolMetrics(c.metrics),
+		PoolObserver(c.observer),
+	)
+}
+
+// dial creates new connections using the given mime type (empty for the default). It is called by the pool in case a new connection is demanded.
+func (c *cosmosImpl) dial(mimeType string) (interfaces.QueryExecutor, error) {
 
 	// create a new websocket dialer to avoid using the same websocket connection for
 	// multiple queries at the same time
@@ -240,95 +467,293 @@ func (c *cosmosImpl) dial() (interfaces.QueryExecutor, error) {
 		return nil, err
 	}
 
-	return Dial(dialer, c.errorChannel, SetAuth(c.credentialProvider), PingInterval(time.Second*30), WithMetrics(c.metrics))
+	options := []clientOption{SetAuth(c.credentialProvider), PingInterval(time.Second * 30), WithMetrics(c.metrics), SetObserver(observerOrNop(c.observer))}
+	if len(mimeType) > 0 {
+		options = append(options, SetMimeType(mimeType))
+	}
+
+	client, err := Dial(dialer, c.errorChannel, options...)
+	if err != nil {
+		// mark the error as connectivity error to be able to retry the request on a new connection
+		return nil, Error{Wrapped: err, Category: ErrorCategoryConnectivity}
+	}
+	return client, nil
+}
+
+// newRequestOptions creates the settings for a request based on the given options.
+func (c *cosmosImpl) newRequestOptions(options ...RequestOption) requestOptions {
+	reqOptions := newRequestOptions(options...)
+
+	// the query log and the operation metrics need the diagnostics even if the caller is not interested in them
+	if reqOptions.diagnostics == nil {
+		reqOptions.diagnostics = &Diagnostics{}
+	}
+	return reqOptions
+}
+
+// retryPolicyFor returns the retry policy that shall be used for a request with the given options
+func (c *cosmosImpl) retryPolicyFor(reqOptions requestOptions) RetryPolicy {
+	if reqOptions.retryPolicy != nil {
+		return reqOptions.retryPolicy
+	}
+
+	if c.retryPolicy != nil {
+		return c.retryPolicy
+	}
+	return DefaultRetryPolicy(c.maxRetries)
+}
+
+func (c *cosmosImpl) ExecuteQuery(query interfaces.QueryBuilder, options ...RequestOption) ([]interfaces.Response, error) {
+	if query == nil {
+		return nil, fmt.Errorf("query is nil")
+	}
+	c.warnOnCrossPartitionQuery(query)
+	return c.intercept(&Request{Query: query.String(), QueryBuilder: query, Options: options})
 }
 
-func (c *cosmosImpl) ExecuteQuery(query interfaces.QueryBuilder) ([]interfaces.Response, error) {
+func (c *cosmosImpl) ExecuteQueryWithBindings(query interfaces.QueryBuilder, options ...RequestOption) ([]interfaces.Response, error) {
 	if query == nil {
 		return nil, fmt.Errorf("query is nil")
 	}
-	return c.Execute(query.String())
+	c.warnOnCrossPartitionQuery(query)
+
+	queryWithBindings, ok := query.(interfaces.QueryBuilderWithBindings)
+	if !ok {
+		return c.intercept(&Request{Query: query.String(), QueryBuilder: query, Options: options})
+	}
+
+	queryStr, bindings := queryWithBindings.StringWithBindings()
+	return c.intercept(&Request{Query: queryStr, QueryBuilder: query, Bindings: bindings, Rebindings: map[string]interface{}{}, Options: options, withBindings: true})
 }
 
-func (c *cosmosImpl) Execute(query string) ([]interfaces.Response, error) {
+// warnOnCrossPartitionQuery logs a warning in case the given query is not scoped to a partition
+// and hence fans out across all partitions of the graph (see interfaces.PartitionFilterValidator).
+func (c *cosmosImpl) warnOnCrossPartitionQuery(query interfaces.QueryBuilder) {
+	validator, ok := query.(interfaces.PartitionFilterValidator)
+	if !ok {
+		return
+	}
 
-	doRetry := func() ([]interfaces.Response, error) {
-		return c.pool.Execute(query)
+	if err := validator.ValidatePartitionFilter(); err != nil {
+		c.logger.Warn().Err(err).Msg("Query fans out across all partitions")
 	}
+}
+
+func (c *cosmosImpl) Execute(query string, options ...RequestOption) ([]interfaces.Response, error) {
+	return c.intercept(&Request{Query: query, Options: options})
+}
 
-	responses, err := retryLoop(doRetry, c.maxRetries, c.retryTimeout, c.metrics, c.logger)
+// execute executes the given query of the call with the given id, retries it if needed and updates the query log and metrics
+func (c *cosmosImpl) execute(callID string, query string, options ...RequestOption) ([]interfaces.Response, error) {
+	reqOptions := c.newRequestOptions(options...)
+
+	doRetry := c.attempt(callID, query, reqOptions, true, func(call callTrace, used *usedConnections) ([]interfaces.Response, error) {
+		return c.executeOnPool(call, used, func(client interfaces.QueryExecutor) ([]interfaces.Response, error) {
+			if traced, ok := client.(tracedExecutor); ok {
+				return markSent(traced.executeFor(call, query, nil, nil))
+			}
+			return markSent(client.Execute(query))
+		})
+	})
+
+	responses, err := retryLoop(doRetry, c.retryPolicyFor(reqOptions), c.retryTimeout, c.retryOnConnectivityErrors, reqOptions.safeToRetry(query), c.metrics, c.observerFor(callID), c.logger)
 
 	// try to investigate the responses and to find out if we can find more specific error information
 	if respErr := extractFirstError(responses); respErr != nil {
 		err = respErr
 	}
+	c.queryLog.log(query, nil, reqOptions.diagnostics, err)
+	updateOperationMetrics(reqOptions, err, c.metrics)
 
 	return responses, err
 }
 
-func (c *cosmosImpl) ExecuteWithBindings(query string, bindings, rebindings map[string]interface{}) ([]interfaces.Response, error) {
+func (c *cosmosImpl) ExecuteWithBindings(query string, bindings, rebindings map[string]interface{}, options ...RequestOption) ([]interfaces.Response, error) {
+	return c.intercept(&Request{Query: query, Bindings: bindings, Rebindings: rebindings, Options: options, withBindings: true})
+}
 
-	doRetry := func() ([]interfaces.Response, error) {
-		return c.pool.ExecuteWithBindings(query, bindings, rebindings)
-	}
+// executeWithBindings executes the given parameterized query of the call with the given id, retries it if needed
+// and updates the query log and metrics
+func (c *cosmosImpl) executeWithBindings(callID string, query string, bindings, rebindings map[string]interface{}, options ...RequestOption) ([]interfaces.Response, error) {
+	reqOptions := c.newRequestOptions(options...)
 
-	responses, err := retryLoop(doRetry, c.maxRetries, c.retryTimeout, c.metrics, c.logger)
+	doRetry := c.attempt(callID, query, reqOptions, true, func(call callTrace, used *usedConnections) ([]interfaces.Response, error) {
+		return c.executeOnPool(call, used, func(client interfaces.QueryExecutor) ([]interfaces.Response, error) {
+			if traced, ok := client.(tracedExecutor); ok && (bindings != nil || rebindings != nil) {
+				return markSent(traced.executeFor(call, query, bindings, rebindings))
+			}
+			return markSent(client.ExecuteWithBindings(query, bindings, rebindings))
+		})
+	})
+
+	responses, err := retryLoop(doRetry, c.retryPolicyFor(reqOptions), c.retryTimeout, c.retryOnConnectivityErrors, reqOptions.safeToRetry(query), c.metrics, c.observerFor(callID), c.logger)
 
 	// try to investigate the responses and to find out if we can find more specific error information
 	if respErr := extractFirstError(responses); respErr != nil {
 		err = respErr
 	}
+	c.queryLog.log(query, bindings, reqOptions.diagnostics, err)
+	updateOperationMetrics(reqOptions, err, c.metrics)
 
 	return responses, err
 }
 
 type retryFun func() ([]interfaces.Response, error)
 
-func retryLoop(executeRequest retryFun, maxRetries int, retryTimeout time.Duration, metrics *Metrics, logger zerolog.Logger) (responses []interfaces.Response, err error) {
+// sentRequestError marks the error of a request that occurred after the request was handed over to a connection.
+// It is unknown whether the server has executed such a request (see retryLoop).
+type sentRequestError struct {
+	err error
+}
+
+func (e sentRequestError) Error() string {
+	return e.err.Error()
+}
+
+// markSent marks the error of a request that was handed over to a connection as sentRequestError,
+// unless the request could not be sent at all (ErrNoConnection).
+func markSent(responses []interfaces.Response, err error) ([]interfaces.Response, error) {
+	if err == nil || errors.Is(err, ErrNoConnection) {
+		return responses, err
+	}
+	return responses, sentRequestError{err: err}
+}
+
+// attemptFun executes one attempt of a request on a pooled connection, avoiding the shared connections in used (see hedging).
+type attemptFun func(used *usedConnections) ([]interfaces.Response, error)
+
+// attempt wraps the given function that executes one attempt of a request of the call with the given id. Before each request
+// is sent the rate limiter is consulted (if enabled), afterwards the rate limiter and the diagnostics are updated.
+// If hedge is true, the attempt is hedged in case hedging is enabled for the request (see hedge).
+func (c *cosmosImpl) attempt(callID string, query string, reqOptions requestOptions, hedge bool, execute func(call callTrace, used *usedConnections) ([]interfaces.Response, error)) retryFun {
+	start := time.Now()
+	attempts := 0
+
+	return func() ([]interfaces.Response, error) {
+		attempts++
+		call := callTrace{id: callID, attempt: attempts}
+		limited := func(used *usedConnections) ([]interfaces.Response, error) {
+			reservation := c.ruLimiter.acquire(query)
+			responses, err := execute(call, used)
+			c.ruLimiter.observe(reservation, responses)
+			if err != nil && extractFirstError(responses) != nil {
+				// The client returns the error reported via the status of a response as well. It is dropped,
+				// since the retry loop evaluates the responses (e.g. 429) and the caller gets the error of the responses.
+				err = nil
+			}
+			return responses, err
+		}
+
+		run := func() ([]interfaces.Response, error) {
+			return limited(nil)
+		}
+		if hedge {
+			run = c.hedge(query, reqOptions, limited)
+		}
+
+		responses, err := run()
+		reqOptions.diagnostics.addAttempt(responses, time.Since(start))
+		return responses, err
+	}
+}
+
+// executeOnPool executes the given function of the given call on a pooled connection.
+// The shared connections in used are avoided (see pool.get).
+func (c *cosmosImpl) executeOnPool(call callTrace, used *usedConnections, execute func(client interfaces.QueryExecutor) ([]interfaces.Response, error)) ([]interfaces.Response, error) {
+	if p, ok := c.pool.(*pool); ok {
+		return p.executeFor(call, used, execute)
+	}
+	return execute(c.pool)
+}
+
+// retryLoop executes the given request and asks the given policy after each attempt whether the request shall be retried.
+// In case the policy is nil the request is not retried at all.
+// If retryOnConnectivityErrors is true, requests that failed due to connectivity issues (see IsNetworkErr) are regarded as retryable,
+// in case they are safe to retry. Otherwise they fail with an UnknownOutcomeError if they were sent already (see sentRequestError).
+func retryLoop(executeRequest retryFun, policy RetryPolicy, retryTimeout time.Duration, retryOnConnectivityErrors bool, safe bool, metrics *Metrics, observer Observer, logger zerolog.Logger) (responses []interfaces.Response, err error) {
 	if metrics == nil {
 		return nil, fmt.Errorf("metrics must not be nil")
 	}
-
-	var tryCount int
-	shouldRetry := maxRetries > 0
-	maxTries := maxRetries + 1
+	observer = observerOrNop(observer)
 
 	done := make(chan bool)
 	defer close(done)
 
 	timeoutReachedChan := handleTimeout(done, retryTimeout, logger)
 
-	for tryCount = 0; tryCount < maxTries; tryCount++ {
+	for attempt := 1; ; attempt++ {
 		responses, err = executeRequest()
-		isARetry := tryCount > 0
+		sent := false
+		if sentErr, ok := err.(sentRequestError); ok {
+			sent, err = true, sentErr.err
+		}
+		isARetry := attempt > 1
 		updateRequestMetrics(responses, metrics, isARetry)
 
 		// error is handled late to ensure an update of the metrics
 		if err != nil {
 			metrics.requestErrorsTotal.Inc()
-			return nil, errors.Wrap(err, "executing request in retry loop")
+			err = errors.Wrap(err, "executing request in retry loop")
+			responses = nil
+		}
+
+		if policy == nil {
+			return responses, err
 		}
 
-		if !shouldRetry {
-			return responses, nil
+		retryAttempt := RetryAttempt{Attempt: attempt, Err: err}
+		unknownOutcome := false
+		if err != nil {
+			retryAttempt.Retryable = retryOnConnectivityErrors && IsNetworkErr(err)
+			if !safe {
+				// The connection broke after the request was sent, hence it is unknown whether the server has executed it.
+				// A request that was not sent is not retried either, since it is not safe to retry.
+				unknownOutcome = retryAttempt.Retryable && sent
+				retryAttempt.Retryable = unknownOutcome
+			}
+		} else {
+			retryInformation := extractRetryConditions(responses)
+			retryAttempt.StatusCode = retryInformation.responseStatusCode
+			retryAttempt.SubStatusCode = retryInformation.subStatusCode
+			retryAttempt.RetryAfter = retryInformation.retryAfter
+
+			// Retry is always on a new or at least active connection, since the pool evicts connections
+			// that produced a response suggesting to retry on a new connection (see pooledConnection.release).
+			// Therefore retryInformation.retryOnNewConnection can be used here as well
+			retryAttempt.Retryable = retryInformation.retry || retryInformation.retryOnNewConnection
+
+			// The connection was closed while the request was processed, hence it is unknown whether the server has
+			// executed the request.
+			unknownOutcome = retryInformation.retryOnNewConnection && !safe
 		}
 
-		retryInformation := extractRetryConditions(responses)
+		retry, wait := policy.ShouldRetry(retryAttempt)
+		if !retry {
+			return responses, err
+		}
 
-		// Retry is always on a new or at least active connection,
-		// therefore retryInformation.retryOnNewConnection can be used here as well
-		if !(retryInformation.retry || retryInformation.retryOnNewConnection) {
-			return responses, nil
+		// Only requests that are safe to retry are retried, since a retry could apply a mutation twice.
+		// The caller is informed that the request would have been retried otherwise.
+		if unknownOutcome {
+			if err == nil {
+				err = extractFirstError(responses)
+			}
+			return nil, UnknownOutcomeError{Wrapped: err}
 		}
+		observer.OnRetry(RetryEvent{RetryAttempt: retryAttempt, RequestID: firstRequestID(responses), Wait: wait})
 
-		if retryInformation.retryAfter > 0 {
-			logger.Info().Msgf("retry %d of query after %v because of header status code %d", tryCount+1, retryInformation.retryAfter, retryInformation.responseStatusCode)
+		if err != nil {
+			logger.Info().Err(err).Msgf("retry %d of query after %v because of connectivity error", attempt, wait)
+		} else {
+			logger.Info().Msgf("retry %d of query after %v because of header status code %d", attempt, wait, retryAttempt.StatusCode)
+		}
 
-			if waitDone := waitForRetry(retryInformation.retryAfter, timeoutReachedChan); !waitDone {
+		if wait > 0 {
+			if waitDone := waitForRetry(wait, timeoutReachedChan); !waitDone {
 				// timeout occurred
-				logger.Warn().Msgf("Timed out while waiting to do a retry after %s (timeout=%s)", retryInformation.retryAfter, retryTimeout)
+				logger.Warn().Msgf("Timed out while waiting to do a retry after %s (timeout=%s)", wait, retryTimeout)
 				metrics.requestRetryTimeoutsTotal.Inc()
-				return responses, nil
+				return responses, err
 			}
 		}
 
@@ -338,14 +763,12 @@ func retryLoop(executeRequest retryFun, maxRetries int, retryTimeout time.Durati
 			// we stop here and return what we got so far
 			metrics.requestRetryTimeoutsTotal.Inc()
 			logger.Warn().Msgf("Timed out while doing a retry (timeout=%s)", retryTimeout)
-			return responses, nil
+			return responses, err
 		default:
 			continue
 			// continue with next retry
 		}
 	}
-
-	return responses, err
 }
 
 func handleTimeout(done <-chan bool, retryTimeout time.Duration, logger zerolog.Logger) (timedOutChan <-chan bool) {
@@ -382,21 +805,26 @@ func waitForRetry(wait time.Duration, stop <-chan bool) (waitDone bool) {
 	}
 }
 
-func (c *cosmosImpl) executeAsync(query string, asyncResponses *[]interfaces.AsyncResponse, errorCallback func(err error)) (responses []interfaces.Response, err error) {
+// executeAsync starts one attempt of the given asynchronous request and pushes the responses to the stream as they arrive
+func (c *cosmosImpl) executeAsync(call callTrace, query string, stream *asyncStream, errorCallback func(err error)) (responses []interfaces.Response, err error) {
 	intermediateChannel := make(chan interfaces.AsyncResponse, 100)
 
-	if err := c.pool.ExecuteAsync(query, intermediateChannel); err != nil {
+	if err := c.executeAsyncOnPool(call, query, intermediateChannel); err != nil {
 		return nil, err
 	}
+	stream.started = true
 	errorCallback(err)
 
 	responses = make([]interfaces.Response, 0, 5)
-	*asyncResponses = make([]interfaces.AsyncResponse, 0, 5)
+	stream.reset()
 
 	for resp := range intermediateChannel {
-		*asyncResponses = append(*asyncResponses, resp)
+		resp.Err = asyncResponseError(resp)
 		responses = append(responses, resp.Response)
-		if resp.ErrorMessage != "" {
+		stream.push(resp)
+
+		// errors reported by cosmos via the status are evaluated by the retry loop based on the responses
+		if resp.ErrorMessage != "" && responseStatusError(resp.Response) == nil {
 			if err == nil {
 				err = errors.New(resp.ErrorMessage)
 				continue
@@ -408,9 +836,28 @@ func (c *cosmosImpl) executeAsync(query string, asyncResponses *[]interfaces.Asy
 	return responses, err
 }
 
-func (c *cosmosImpl) ExecuteAsync(query string, responseChannel chan interfaces.AsyncResponse) (err error) {
+// executeAsyncOnPool starts the given query of the given call on a pooled connection
+func (c *cosmosImpl) executeAsyncOnPool(call callTrace, query string, responseChannel chan interfaces.AsyncResponse) error {
+	if p, ok := c.pool.(*pool); ok {
+		return p.executeAsyncFor(call, query, responseChannel)
+	}
+	return c.pool.ExecuteAsync(query, responseChannel)
+}
 
-	var asyncResponses []interfaces.AsyncResponse
+// ExecuteAsync executes the given query and forwards the responses to the responseChannel as soon as they arrive.
+// The request is retried only as long as no response was delivered. Errors are delivered as typed errors (AsyncResponse.Err).
+// The responseChannel is closed after the last response was delivered.
+func (c *cosmosImpl) ExecuteAsync(query string, responseChannel chan interfaces.AsyncResponse, options ...RequestOption) (err error) {
+	_, err = c.intercept(&Request{Query: query, ResponseChannel: responseChannel, Options: options})
+	return err
+}
+
+// executeAsyncRequest starts the given query of the call with the given id and forwards the responses to the responseChannel
+// as soon as they arrive. It returns after the first attempt was started (or failed to start).
+func (c *cosmosImpl) executeAsyncRequest(callID string, query string, responseChannel chan interfaces.AsyncResponse, options ...RequestOption) (err error) {
+	reqOptions := c.newRequestOptions(options...)
+
+	stream := &asyncStream{responseChannel: responseChannel}
 
 	wg := sync.WaitGroup{}
 	wg.Add(1)
@@ -428,23 +875,35 @@ func (c *cosmosImpl) ExecuteAsync(query string, responseChannel chan interfaces.
 		})
 	}
 
-	doRetry := func() ([]interfaces.Response, error) {
-		return c.executeAsync(query, &asyncResponses, errCallback)
-	}
+	// asynchronous requests are never hedged, since the responses are already streamed to the caller
+	doRetry := c.attempt(callID, query, reqOptions, false, func(call callTrace, _ *usedConnections) ([]interfaces.Response, error) {
+		return c.executeAsync(call, query, stream, errCallback)
+	})
+	policy := &streamRetryPolicy{policy: c.retryPolicyFor(reqOptions), stream: stream}
 
 	go func() {
 		defer close(responseChannel)
-		_, retryErr := retryLoop(doRetry, c.maxRetries, c.retryTimeout, c.metrics, c.logger)
+		responses, retryErr := retryLoop(doRetry, policy, c.retryTimeout, c.retryOnConnectivityErrors, reqOptions.safeToRetry(query), c.metrics, c.observerFor(callID), c.logger)
+
+		logErr := retryErr
+		if respErr := extractFirstError(responses); respErr != nil {
+			logErr = respErr
+		}
+		c.queryLog.log(query, nil, reqOptions.diagnostics, logErr)
+		updateOperationMetrics(reqOptions, logErr, c.metrics)
 
 		if retryErr != nil {
-			// return because the asyncResponses we gathered might be outdated
+			if !stream.started {
+				// in case no attempt could be started, the error has to be handed over to the caller
+				errCallback(retryErr)
+				return
+			}
+			// the held back responses are dropped because they belong to the failed attempt
+			stream.fail(retryErr)
 			return
 		}
 
-		// Write final result
-		for _, response := range asyncResponses {
-			responseChannel <- response
-		}
+		stream.flush()
 	}()
 
 	wg.Wait()
@@ -481,6 +940,32 @@ func (c *cosmosImpl) IsHealthy() error {
 	return c.pool.Ping()
 }
 
+// ServerInfo returns the flavour and version of the gremlin server the connector is connected to
+func (c *cosmosImpl) ServerInfo() ServerInfo {
+	if len(c.serverInfo.Flavour) == 0 {
+		return ServerInfo{Flavour: ServerFlavourUnknown}
+	}
+	return c.serverInfo
+}
+
+// updateOperationMetrics records the latency, request charge and server time of a finished request labelled by operation and outcome
+func updateOperationMetrics(reqOptions requestOptions, err error, metrics *Metrics) {
+	diagnostics := reqOptions.diagnostics
+	if diagnostics == nil {
+		return
+	}
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+
+	operation := reqOptions.operationName()
+	metrics.requestLatencyMS.WithLabelValues(operation, outcome).Observe(float64(diagnostics.Latency) / float64(time.Millisecond))
+	metrics.requestChargePerOperation.WithLabelValues(operation, outcome).Observe(float64(diagnostics.RequestCharge))
+	metrics.serverTimePerOperationMS.WithLabelValues(operation, outcome).Observe(float64(diagnostics.ServerTime) / float64(time.Millisecond))
+}
+
 // updateRequestMetrics updates the request relevant metrics based on the given chunk of responses
 func updateRequestMetrics(responses []interfaces.Response, metrics *Metrics, isARetry bool) {
 	if isARetry {
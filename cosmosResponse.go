@@ -21,7 +21,8 @@ type cosmosStatusCodeDescription struct {
 
 type cosmosRetryInformation struct {
 	cosmosStatusCodeDescription
-	retryAfter time.Duration
+	subStatusCode int
+	retryAfter    time.Duration
 }
 
 var noRetry = cosmosStatusCodeDescription{retry: false}
@@ -83,7 +84,7 @@ func extractFirstError(responses []interfaces.Response) error {
 			// if we can't parse/ interpret the attribute map then we return the full/ unparsed error information
 			return fmt.Errorf("Failed parsing attributes of response: '%s'. Unparsed error: %d - %s", err.Error(), response.Status.Code, response.Status.Message)
 		}
-		return fmt.Errorf("%d (%d) - %s", responseInfo.statusCode, responseInfo.subStatusCode, responseInfo.statusDescription)
+		return newCosmosError(responseInfo, response.Status.Message)
 
 	}
 
@@ -93,6 +94,7 @@ func extractFirstError(responses []interfaces.Response) error {
 
 func extractRetryConditions(responses []interfaces.Response) (c cosmosRetryInformation) {
 	lastRetryResponseStatusCode := noRetry
+	lastRetrySubStatusCode := 0
 	retryAfter := time.Second * 0
 	for _, response := range responses {
 		statusCode := response.Status.Code
@@ -117,13 +119,39 @@ func extractRetryConditions(responses []interfaces.Response) (c cosmosRetryInfor
 
 		if responseCosmosStatusCode.retry {
 			lastRetryResponseStatusCode = responseCosmosStatusCode
+			lastRetrySubStatusCode = responseInfo.subStatusCode
 
 			if responseInfo.retryAfter > retryAfter {
 				retryAfter = responseInfo.retryAfter
 			}
 		}
 	}
-	return cosmosRetryInformation{lastRetryResponseStatusCode, retryAfter}
+	return cosmosRetryInformation{lastRetryResponseStatusCode, lastRetrySubStatusCode, retryAfter}
+}
+
+// requiresNewConnection returns true in case one of the given responses indicates that the connection
+// used for the request is not usable any more (e.g. status codes 1007, 1008). Hence the connection has to be
+// removed from the pool and a retry has to be done on a different connection.
+func requiresNewConnection(responses []interfaces.Response) bool {
+	for _, response := range responses {
+		statusCode := response.Status.Code
+
+		// everything ok --> skip this response
+		if statusCode == interfaces.StatusSuccess || statusCode == interfaces.StatusNoContent || statusCode == interfaces.StatusPartialContent {
+			continue
+		}
+
+		responseInfo, err := parseAttributeMap(response.Status.Attributes)
+		if err != nil {
+			// if we can't parse/ interpret the attribute map then we ignore it
+			continue
+		}
+
+		if statusCodeDescription[responseInfo.statusCode].retryOnNewConnection {
+			return true
+		}
+	}
+	return false
 }
 
 // parseAttributeMap parses the given attribute map assuming that it contains CosmosDB specific headers.
@@ -1,6 +1,7 @@
 package gremcos
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -33,6 +34,12 @@ func TestExtractFirstError(t *testing.T) {
 	// THEN
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "429")
+	cosmosErr := CosmosError{}
+	require.True(t, errors.As(err, &cosmosErr))
+	assert.Equal(t, 429, cosmosErr.StatusCode)
+	assert.Equal(t, 3200, cosmosErr.SubStatusCode)
+	assert.Equal(t, ErrorCategoryThrottled, cosmosErr.Category)
+	assert.True(t, IsThrottled(err))
 }
 
 func TestExtractFirstErrorNoError(t *testing.T) {
@@ -321,3 +328,43 @@ func TestExtractRetryConditionsIgnoreError(t *testing.T) {
 	assert.NotEqual(t, noRetry, retryConditions.cosmosStatusCodeDescription)
 	assert.Equal(t, time.Millisecond*500, retryConditions.retryAfter)
 }
+
+func TestRequiresNewConnection(t *testing.T) {
+	// GIVEN
+	noError := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusSuccess,
+		},
+	}
+	tooManyRequests := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusServerError,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code": 429,
+			},
+		},
+	}
+	connectionClosed := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusServerError,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code": 1007,
+			},
+		},
+	}
+	connectionTooBusy := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusServerError,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code": 1008,
+			},
+		},
+	}
+
+	// WHEN + THEN
+	assert.False(t, requiresNewConnection(nil))
+	assert.False(t, requiresNewConnection([]interfaces.Response{noError}))
+	assert.False(t, requiresNewConnection([]interfaces.Response{noError, tooManyRequests}))
+	assert.True(t, requiresNewConnection([]interfaces.Response{noError, connectionClosed}))
+	assert.True(t, requiresNewConnection([]interfaces.Response{connectionTooBusy, tooManyRequests}))
+}
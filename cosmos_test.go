@@ -1,6 +1,7 @@
 package gremcos
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"sync"
@@ -10,10 +11,12 @@ import (
 	"go.uber.org/goleak"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/supplyon/gremcos/interfaces"
+	m "github.com/supplyon/gremcos/metrics"
 	mock_interfaces "github.com/supplyon/gremcos/test/mocks/interfaces"
 	mock_metrics "github.com/supplyon/gremcos/test/mocks/metrics"
 )
@@ -80,8 +83,8 @@ func TestDialUsingDifferentWebsockets(t *testing.T) {
 	mockCount.EXPECT().Inc().Times(2)
 	metricMocks.connectionUsageTotal.EXPECT().WithLabelValues("READ", "true").Return(mockCount).Times(2)
 
-	queryExecutor1, err1 := cImpl.dial()
-	queryExecutor2, err2 := cImpl.dial()
+	queryExecutor1, err1 := cImpl.dial("")
+	queryExecutor2, err2 := cImpl.dial("")
 
 	// THEN
 	require.NoError(t, err1)
@@ -203,6 +206,53 @@ func TestNewWithMetrics(t *testing.T) {
 	assert.NoError(t, cosmos.Stop())
 }
 
+func TestNewWithMetricsRegistererAndConstLabels(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+
+	// WHEN
+	cosmos, err := New("ws://host", MetricsPrefix("prefix"), MetricsRegisterer(registry), MetricsConstLabels(map[string]string{"graph": "users"}))
+
+	// THEN
+	require.NoError(t, err)
+	cImpl := toCosmosImpl(t, cosmos)
+	require.NotNil(t, cImpl.metrics)
+	cImpl.metrics.requestErrorsTotal.Inc()
+	assert.Equal(t, 1, gatherAndCount(t, registry, "prefix_cosmos_request_errors_total"))
+	assert.NoError(t, cosmos.Stop())
+}
+
+func TestNewWithMetricsFactory(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+	factory := m.NewPrometheusFactory(registry, "factory", "cosmos", nil)
+
+	// WHEN
+	cosmos, err := New("ws://host", MetricsPrefix("prefix"), MetricsFactory(factory))
+
+	// THEN
+	require.NoError(t, err)
+	cImpl := toCosmosImpl(t, cosmos)
+	require.NotNil(t, cImpl.metrics)
+	cImpl.metrics.requestErrorsTotal.Inc()
+	assert.Equal(t, 1, gatherAndCount(t, registry, "factory_cosmos_request_errors_total"))
+	assert.NoError(t, cosmos.Stop())
+}
+
+func TestNewWithMetricsRegistrationError(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+	conflicting := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: "prefix", Subsystem: "cosmos", Name: "request_errors_total", Help: "other"})
+	require.NoError(t, registry.Register(conflicting))
+
+	// WHEN
+	cosmos, err := New("ws://host", MetricsPrefix("prefix"), MetricsRegisterer(registry))
+
+	// THEN
+	assert.Error(t, err)
+	assert.Nil(t, cosmos)
+}
+
 func TestUpdateMetricsNoResponses(t *testing.T) {
 	// GIVEN
 	mockCtrl := gomock.NewController(t)
@@ -374,6 +424,27 @@ func TestWithLogger(t *testing.T) {
 	assert.NoError(t, cosmos.Stop())
 }
 
+func TestQueryLogOption(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, _ := NewMockedMetrics(mockCtrl)
+	log := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	// WHEN
+	cosmos, err := New("ws://host", WithLogger(log), QueryLog(time.Second, 100), withMetrics(metrics))
+	require.NoError(t, err)
+
+	// THEN
+	cImpl := toCosmosImpl(t, cosmos)
+	require.NotNil(t, cImpl.queryLog)
+	assert.Equal(t, time.Second, cImpl.queryLog.latencyThreshold)
+	assert.Equal(t, float32(100), cImpl.queryLog.requestChargeThreshold)
+	assert.Equal(t, defaultSensitiveBindingKeys, cImpl.queryLog.sensitiveBindingKeys)
+	assert.Equal(t, zerolog.DebugLevel, cImpl.queryLog.logger.GetLevel())
+	assert.NoError(t, cosmos.Stop())
+}
+
 func TestAutomaticRetries(t *testing.T) {
 	// GIVEN
 	mockCtrl := gomock.NewController(t)
@@ -408,6 +479,49 @@ func TestAutomaticRetries_DefaultTimeout(t *testing.T) {
 	assert.NoError(t, cosmos.Stop())
 }
 
+func TestConnectionHealthCheck(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, _ := NewMockedMetrics(mockCtrl)
+
+	// WHEN
+	cosmos, err := New("ws://host", ConnectionHealthCheck(time.Second*10, true), withMetrics(metrics))
+	require.NoError(t, err)
+
+	// THEN
+	cImpl := toCosmosImpl(t, cosmos)
+	assert.Equal(t, time.Second*10, cImpl.healthCheckInterval)
+	assert.True(t, cImpl.validateOnBorrow)
+	p, ok := cImpl.pool.(*pool)
+	require.True(t, ok)
+	assert.Equal(t, time.Second*10, p.healthCheckInterval)
+	assert.True(t, p.validateOnBorrow)
+	assert.NoError(t, cosmos.Stop())
+}
+
+func TestMultiplexConnections(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, _ := NewMockedMetrics(mockCtrl)
+
+	// WHEN
+	cosmos, err := New("ws://host", MultiplexConnections(8), withMetrics(metrics))
+	require.NoError(t, err)
+
+	// THEN
+	cImpl := toCosmosImpl(t, cosmos)
+	assert.Equal(t, 8, cImpl.maxConcurrentRequestsPerConnection)
+	p, ok := cImpl.pool.(*pool)
+	require.True(t, ok)
+	assert.Equal(t, 8, p.maxConcurrentRequestsPerConnection)
+	assert.True(t, p.isMultiplexing())
+	assert.NoError(t, cosmos.Stop())
+}
+
 func TestCosmosImpl_ExecuteQuery_NoQuery(t *testing.T) {
 	// GIVEN
 	cosmos := cosmosImpl{}
@@ -420,6 +534,121 @@ func TestCosmosImpl_ExecuteQuery_NoQuery(t *testing.T) {
 	assert.Nil(t, responses)
 }
 
+func TestCosmosImpl_ExecuteQueryWithBindings_NoQuery(t *testing.T) {
+	// GIVEN
+	cosmos := cosmosImpl{}
+
+	// WHEN
+	responses, err := cosmos.ExecuteQueryWithBindings(nil)
+
+	// THEN
+	assert.EqualError(t, err, "query is nil")
+	assert.Nil(t, responses)
+}
+
+func TestCosmosImpl_ExecuteQueryWithBindings(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		retryTimeout: time.Second * 2,
+	}
+
+	query := mock_interfaces.NewMockQueryBuilderWithBindings(mockCtrl)
+	query.EXPECT().StringWithBindings().Return(`g.V().has("name",_b0)`, map[string]interface{}{"_b0": "Max"})
+	response := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().ExecuteWithBindings(`g.V().has("name",_b0)`, map[string]interface{}{"_b0": "Max"}, map[string]interface{}{}).Return(response, nil)
+
+	// WHEN
+	responses, err := cosmos.ExecuteQueryWithBindings(query)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, response, responses)
+}
+
+func TestCosmosImpl_ExecuteQueryWithBindings_NotSupported(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		retryTimeout: time.Second * 2,
+	}
+
+	query := mock_interfaces.NewMockQueryBuilder(mockCtrl)
+	query.EXPECT().String().Return("g.V()")
+	response := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().Execute("g.V()").Return(response, nil)
+
+	// WHEN
+	responses, err := cosmos.ExecuteQueryWithBindings(query)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, response, responses)
+}
+
+func TestCosmosImpl_ExecuteQuery_WarnOnCrossPartitionQuery(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	cosmos := cosmosImpl{
+		logger:       zerolog.New(&buf),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		retryTimeout: time.Second * 2,
+	}
+
+	query := mock_interfaces.NewMockPartitionFilterValidator(mockCtrl)
+	query.EXPECT().ValidatePartitionFilter().Return(fmt.Errorf("traversal does not filter by partition key"))
+	query.EXPECT().String().Return("g.V()")
+	response := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().Execute("g.V()").Return(response, nil)
+
+	// WHEN
+	responses, err := cosmos.ExecuteQuery(query)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, response, responses)
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), "traversal does not filter by partition key")
+}
+
 func TestCosmosImpl_Execute_RetriesSuccess(t *testing.T) {
 	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
 
@@ -579,6 +808,51 @@ func TestCosmosImpl_Execute_MaxRetriesFailure(t *testing.T) {
 	assert.EqualError(t, err, "429 (3200) - Request was throttled and should be retried after value in x-ms-retry-after-ms")
 }
 
+func TestCosmosImpl_Execute_RetriesCosmosErrorReturnedAsError(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   1,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V()"
+	throttled := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code":    429,
+					"x-ms-substatus-code": 3200,
+					"x-ms-retry-after-ms": "00:00:00.0100000",
+				},
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	// the client returns the error of the response status together with the responses
+	queryExecutor.EXPECT().Execute(query).Times(2).Return(throttled, extractError(throttled[0]))
+
+	// WHEN
+	responses, err := cosmos.Execute(query)
+
+	// THEN
+	assert.EqualValues(t, throttled, responses)
+	assert.True(t, IsThrottled(err))
+}
+
 func TestCosmosImpl_Execute_NoRetriesAfterSuccess(t *testing.T) {
 	// GIVEN
 	mockCtrl := gomock.NewController(t)
@@ -1341,7 +1615,7 @@ func TestHandleRetryLoop_FailMissingMetrics(t *testing.T) {
 		return nil, nil
 	}
 	// WHEN
-	responses, err := retryLoop(retryFn, 0, time.Second, nil, zerolog.Nop())
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(0), time.Second, false, true, nil, nil, zerolog.Nop())
 
 	// THEN
 	assert.Error(t, err)
@@ -1359,7 +1633,7 @@ func TestHandleRetryLoop_FailureNoRetry(t *testing.T) {
 	}
 	// WHEN
 	metricMocks.requestErrorsTotal.EXPECT().Inc()
-	responses, err := retryLoop(retryFn, 1, time.Second, metrics, zerolog.Nop())
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(1), time.Second, false, true, metrics, nil, zerolog.Nop())
 
 	// THEN
 	assert.Error(t, err)
@@ -1388,7 +1662,7 @@ func TestHandleRetryLoop_SuccessNoRetry(t *testing.T) {
 	metricMocks.requestChargePerQuery.EXPECT().Set(float64(0))
 	metricMocks.requestChargeTotal.EXPECT().Add(float64(0))
 	metricMocks.retryAfterMS.EXPECT().Observe(float64(0))
-	responses, err := retryLoop(retryFn, 1, time.Second, metrics, zerolog.Nop())
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(1), time.Second, false, true, metrics, nil, zerolog.Nop())
 
 	// THEN
 	assert.NoError(t, err)
@@ -1428,7 +1702,7 @@ func TestHandleRetryLoop_FailWaitingTooLongForRetry(t *testing.T) {
 	metricMocks.requestChargePerQuery.EXPECT().Set(float64(0))
 	metricMocks.requestChargeTotal.EXPECT().Add(float64(0))
 	metricMocks.retryAfterMS.EXPECT().Observe(float64(600000))
-	responses, err := retryLoop(retryFn, 1, time.Second, metrics, zerolog.Nop())
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(1), time.Second, false, true, metrics, nil, zerolog.Nop())
 
 	// THEN
 	assert.NoError(t, err)
@@ -1458,9 +1732,1179 @@ func TestHandleRetryLoop_FailOnEndlessRetries(t *testing.T) {
 		return []interfaces.Response{response}, nil
 	}
 	// WHEN
-	responses, err := retryLoop(retryFn, 2, time.Millisecond*100, metrics, zerolog.Nop())
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(2), time.Millisecond*100, false, true, metrics, nil, zerolog.Nop())
 
 	// THEN
 	assert.NoError(t, err)
 	assert.NotEmpty(t, responses)
 }
+
+func TestHandleRetryLoop_RetryOnConnectivityError(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	tryCount := 0
+	retryFn := func() ([]interfaces.Response, error) {
+		tryCount++
+		if tryCount == 1 {
+			return nil, ErrNoConnection
+		}
+		response := interfaces.Response{
+			Result: interfaces.Result{Data: []byte("[]")},
+			Status: interfaces.Status{Code: 200},
+		}
+		return []interfaces.Response{response}, nil
+	}
+	// WHEN
+	metricMocks.requestErrorsTotal.EXPECT().Inc()
+	metricMocks.requestRetiesTotal.EXPECT().Inc()
+	mockCount200 := mock_metrics.NewMockCounter(mockCtrl)
+	mockCount200.EXPECT().Inc()
+	metricMocks.statusCodeTotal.EXPECT().WithLabelValues("200").Return(mockCount200)
+	metricMocks.serverTimePerQueryResponseAvgMS.EXPECT().Set(float64(0))
+	metricMocks.serverTimePerQueryMS.EXPECT().Set(float64(0))
+	metricMocks.requestChargePerQueryResponseAvg.EXPECT().Set(float64(0))
+	metricMocks.requestChargePerQuery.EXPECT().Set(float64(0))
+	metricMocks.requestChargeTotal.EXPECT().Add(float64(0))
+	metricMocks.retryAfterMS.EXPECT().Observe(float64(0))
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(1), time.Second, true, true, metrics, nil, zerolog.Nop())
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Len(t, responses, 1)
+	assert.Equal(t, 2, tryCount)
+}
+
+func TestHandleRetryLoop_RetryOnConnectivityErrorMaxRetries(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	tryCount := 0
+	retryFn := func() ([]interfaces.Response, error) {
+		tryCount++
+		return nil, Error{Wrapped: fmt.Errorf("broken pipe"), Category: ErrorCategoryConnectivity}
+	}
+	// WHEN
+	metricMocks.requestErrorsTotal.EXPECT().Inc().Times(3)
+	metricMocks.requestRetiesTotal.EXPECT().Inc().Times(2)
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(2), time.Second, true, true, metrics, nil, zerolog.Nop())
+
+	// THEN
+	assert.Error(t, err)
+	assert.True(t, IsNetworkErr(err))
+	assert.Nil(t, responses)
+	assert.Equal(t, 3, tryCount)
+}
+
+func TestHandleRetryLoop_UnknownOutcomeOnConnectivityErrorAfterSending(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	tryCount := 0
+	retryFn := func() ([]interfaces.Response, error) {
+		tryCount++
+		return nil, sentRequestError{err: Error{Wrapped: fmt.Errorf("broken pipe"), Category: ErrorCategoryConnectivity}}
+	}
+	// WHEN
+	metricMocks.requestErrorsTotal.EXPECT().Inc().Times(1)
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(2), time.Second, true, false, metrics, nil, zerolog.Nop())
+
+	// THEN
+	assert.Error(t, err)
+	assert.True(t, IsUnknownOutcome(err))
+	assert.True(t, IsNetworkErr(err))
+	assert.Nil(t, responses)
+	assert.Equal(t, 1, tryCount)
+}
+
+func TestHandleRetryLoop_NoUnknownOutcomeOnConnectivityErrorBeforeSending(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	tryCount := 0
+	retryFn := func() ([]interfaces.Response, error) {
+		tryCount++
+		return nil, ErrNoConnection
+	}
+	// WHEN
+	metricMocks.requestErrorsTotal.EXPECT().Inc().Times(1)
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(2), time.Second, true, false, metrics, nil, zerolog.Nop())
+
+	// THEN
+	assert.Error(t, err)
+	assert.False(t, IsUnknownOutcome(err))
+	assert.True(t, IsNetworkErr(err))
+	assert.Nil(t, responses)
+	assert.Equal(t, 1, tryCount)
+}
+
+func TestHandleRetryLoop_NoRetryOnConnectivityError(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	tryCount := 0
+	retryFn := func() ([]interfaces.Response, error) {
+		tryCount++
+		return nil, ErrNoConnection
+	}
+	// WHEN
+	metricMocks.requestErrorsTotal.EXPECT().Inc()
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(2), time.Second, false, true, metrics, nil, zerolog.Nop())
+
+	// THEN
+	assert.Error(t, err)
+	assert.Nil(t, responses)
+	assert.Equal(t, 1, tryCount)
+}
+
+func TestHandleRetryLoop_NoRetryOnOtherErrors(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	tryCount := 0
+	retryFn := func() ([]interfaces.Response, error) {
+		tryCount++
+		return nil, fmt.Errorf("Failure")
+	}
+	// WHEN
+	metricMocks.requestErrorsTotal.EXPECT().Inc()
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(2), time.Second, true, true, metrics, nil, zerolog.Nop())
+
+	// THEN
+	assert.Error(t, err)
+	assert.Nil(t, responses)
+	assert.Equal(t, 1, tryCount)
+}
+
+func TestRetryOnConnectivityErrors(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, _ := NewMockedMetrics(mockCtrl)
+
+	// WHEN
+	cosmos, err := New("ws://host", RetryOnConnectivityErrors(), withMetrics(metrics))
+	require.NoError(t, err)
+
+	// THEN
+	cImpl := toCosmosImpl(t, cosmos)
+	assert.True(t, cImpl.retryOnConnectivityErrors)
+	assert.False(t, newRequestOptions().safeToRetry("g.addV('user')"))
+	assert.True(t, newRequestOptions(Idempotent()).safeToRetry("g.addV('user')"))
+	assert.True(t, newRequestOptions(ReadOnly()).safeToRetry("g.addV('user')"))
+	assert.True(t, newRequestOptions().safeToRetry("g.V()"))
+	assert.NoError(t, cosmos.Stop())
+}
+
+func TestCosmosImpl_Execute_RetryOnConnectivityErrorIdempotent(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:                    zerolog.Nop(),
+		pool:                      poolMock,
+		metrics:                   newStubbedMetrics(),
+		maxRetries:                2,
+		retryTimeout:              time.Second * 2,
+		retryOnConnectivityErrors: true,
+	}
+
+	query := "g.V()"
+	success := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+
+	gomock.InOrder(
+		queryExecutor.EXPECT().Execute(query).Return(nil, ErrNoConnection),
+		queryExecutor.EXPECT().Execute(query).Return(success, nil),
+	)
+
+	// WHEN
+	responses, err := cosmos.Execute(query, Idempotent())
+
+	// THEN
+	assert.NoError(t, err)
+	assert.EqualValues(t, success, responses)
+}
+
+func TestCosmosImpl_Execute_NoRetryOnConnectivityErrorNotIdempotent(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:                    zerolog.Nop(),
+		pool:                      poolMock,
+		metrics:                   newStubbedMetrics(),
+		maxRetries:                2,
+		retryTimeout:              time.Second * 2,
+		retryOnConnectivityErrors: true,
+	}
+
+	query := "g.addV('user')"
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().ExecuteWithBindings(query, nil, nil).Times(1).Return(nil, ErrNoConnection)
+
+	// WHEN
+	responses, err := cosmos.ExecuteWithBindings(query, nil, nil)
+
+	// THEN
+	assert.Error(t, err)
+	assert.True(t, IsNetworkErr(err))
+	assert.Nil(t, responses)
+}
+
+func TestCosmosImpl_Execute_UnknownOutcomeOnConnectivityErrorNotIdempotent(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:                    zerolog.Nop(),
+		pool:                      poolMock,
+		metrics:                   newStubbedMetrics(),
+		maxRetries:                2,
+		retryTimeout:              time.Second * 2,
+		retryOnConnectivityErrors: true,
+	}
+
+	query := "g.addV('user')"
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().Execute(query).Times(1).Return(nil, myNetError("connection reset by peer"))
+
+	// WHEN
+	responses, err := cosmos.Execute(query)
+
+	// THEN
+	require.Error(t, err)
+	assert.True(t, IsUnknownOutcome(err), "Expected a mutation that failed after it was sent to have an unknown outcome")
+	assert.True(t, IsNetworkErr(err))
+	assert.Nil(t, responses)
+}
+
+func TestCosmosImpl_Execute_UnknownOutcome(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   2,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V('1').addE('knows').to(g.V('2'))"
+	connectionClosed := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code": 1007,
+				},
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().Close().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().Execute(query).Times(1).Return(connectionClosed, nil)
+
+	// WHEN
+	responses, err := cosmos.Execute(query)
+
+	// THEN
+	require.Error(t, err)
+	assert.True(t, IsUnknownOutcome(err))
+	cosmosErr, ok := asCosmosError(err)
+	require.True(t, ok)
+	assert.Equal(t, 1007, cosmosErr.StatusCode)
+	assert.Nil(t, responses)
+}
+
+func TestCosmosImpl_Execute_NoUnknownOutcomeWithoutRetries(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   0,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V('1').addE('knows').to(g.V('2'))"
+	connectionClosed := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code": 1007,
+				},
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().Close().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().Execute(query).Times(1).Return(connectionClosed, nil)
+
+	// WHEN
+	responses, err := cosmos.Execute(query)
+
+	// THEN
+	require.Error(t, err)
+	assert.False(t, IsUnknownOutcome(err), "Expected the error of the response since the request would not have been retried anyway")
+	cosmosErr, ok := asCosmosError(err)
+	require.True(t, ok)
+	assert.Equal(t, 1007, cosmosErr.StatusCode)
+	assert.Equal(t, connectionClosed, responses)
+}
+
+func TestCosmosImpl_Execute_RetryAmbiguousFailureOfSafeRequest(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   2,
+		retryTimeout: time.Second * 2,
+	}
+
+	readQuery := "g.V('1').out('knows')"
+	idempotentQuery := "g.V('1').property('name','Max')"
+	connectionClosed := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code": 1008,
+				},
+			},
+		},
+	}
+	success := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().Close().AnyTimes().Return(nil)
+	gomock.InOrder(
+		queryExecutor.EXPECT().Execute(readQuery).Return(connectionClosed, nil),
+		queryExecutor.EXPECT().Execute(readQuery).Return(success, nil),
+		queryExecutor.EXPECT().Execute(idempotentQuery).Return(connectionClosed, nil),
+		queryExecutor.EXPECT().Execute(idempotentQuery).Return(success, nil),
+	)
+
+	// WHEN
+	readResponses, readErr := cosmos.Execute(readQuery)
+	idempotentResponses, idempotentErr := cosmos.Execute(idempotentQuery, Idempotent())
+
+	// THEN
+	assert.NoError(t, readErr)
+	assert.Equal(t, success, readResponses)
+	assert.NoError(t, idempotentErr)
+	assert.Equal(t, success, idempotentResponses)
+}
+
+func TestCosmosImpl_ExecuteAsync_FailureOnFirstCall(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:                    zerolog.Nop(),
+		pool:                      poolMock,
+		metrics:                   newStubbedMetrics(),
+		maxRetries:                1,
+		retryTimeout:              time.Second * 2,
+		retryOnConnectivityErrors: true,
+	}
+
+	query := "g.V()"
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().ExecuteAsync(query, gomock.Any()).Times(2).Return(ErrNoConnection)
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	err = cosmos.ExecuteAsync(query, responseChannel, Idempotent())
+
+	// THEN
+	assert.Error(t, err)
+	assert.True(t, IsNetworkErr(err))
+	_, open := <-responseChannel
+	assert.False(t, open, "Expected the response channel to be closed")
+}
+
+func TestCosmosImpl_ExecuteAsync_StreamsResponses(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   3,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V()"
+	partial := interfaces.Response{Status: interfaces.Status{Code: interfaces.StatusPartialContent}}
+	final := interfaces.Response{Status: interfaces.Status{Code: interfaces.StatusSuccess}}
+	proceed := make(chan struct{})
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().ExecuteAsync(query, gomock.Any()).Times(1).DoAndReturn(func(q string, resp chan interfaces.AsyncResponse) error {
+		go func() {
+			resp <- interfaces.AsyncResponse{Response: partial}
+			<-proceed
+			resp <- interfaces.AsyncResponse{Response: final}
+			close(resp)
+		}()
+		return nil
+	})
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse)
+	err = cosmos.ExecuteAsync(query, responseChannel)
+	require.NoError(t, err)
+
+	// THEN
+	// the first chunk has to be delivered before the query is completed
+	first := <-responseChannel
+	assert.Equal(t, partial, first.Response)
+	assert.NoError(t, first.Err)
+
+	close(proceed)
+	second := <-responseChannel
+	assert.Equal(t, final, second.Response)
+	assert.NoError(t, second.Err)
+
+	_, open := <-responseChannel
+	assert.False(t, open, "Expected the response channel to be closed")
+}
+
+func TestCosmosImpl_ExecuteAsync_NoRetriesAfterFirstDelivery(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   3,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V()"
+	partial := interfaces.Response{Status: interfaces.Status{Code: interfaces.StatusPartialContent}}
+	throttled := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusServerError,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code":    429,
+				"x-ms-substatus-code": 3200,
+				"x-ms-retry-after-ms": "00:00:00.0100000",
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().ExecuteAsync(query, gomock.Any()).Times(1).DoAndReturn(func(q string, resp chan interfaces.AsyncResponse) error {
+		resp <- interfaces.AsyncResponse{Response: partial}
+		resp <- interfaces.AsyncResponse{Response: throttled}
+		close(resp)
+		return nil
+	})
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	err = cosmos.ExecuteAsync(query, responseChannel)
+	require.NoError(t, err)
+
+	asyncResponses := make([]interfaces.AsyncResponse, 0, 2)
+	for resp := range responseChannel {
+		asyncResponses = append(asyncResponses, resp)
+	}
+
+	// THEN
+	require.Len(t, asyncResponses, 2)
+	assert.Equal(t, partial, asyncResponses[0].Response)
+	assert.NoError(t, asyncResponses[0].Err)
+	assert.Equal(t, throttled, asyncResponses[1].Response)
+	require.Error(t, asyncResponses[1].Err)
+	cosmosErr, ok := asyncResponses[1].Err.(CosmosError)
+	require.True(t, ok, "Expected a CosmosError")
+	assert.Equal(t, 429, cosmosErr.StatusCode)
+}
+
+func TestCosmosImpl_ExecuteAsync_ErrorAfterStart(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   1,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V()"
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().ExecuteAsync(query, gomock.Any()).Times(1).DoAndReturn(func(q string, resp chan interfaces.AsyncResponse) error {
+		resp <- interfaces.AsyncResponse{ErrorMessage: "connection lost"}
+		close(resp)
+		return nil
+	})
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	err = cosmos.ExecuteAsync(query, responseChannel)
+	require.NoError(t, err)
+
+	asyncResponses := make([]interfaces.AsyncResponse, 0, 1)
+	for resp := range responseChannel {
+		asyncResponses = append(asyncResponses, resp)
+	}
+
+	// THEN
+	require.Len(t, asyncResponses, 1)
+	require.Error(t, asyncResponses[0].Err)
+	assert.Contains(t, asyncResponses[0].Err.Error(), "connection lost")
+	assert.Equal(t, asyncResponses[0].Err.Error(), asyncResponses[0].ErrorMessage)
+}
+
+func TestCosmosImpl_ExecuteAsync_RetriesCosmosErrorWithErrorMessage(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   1,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V()"
+	success := interfaces.Response{Status: interfaces.Status{Code: interfaces.StatusSuccess}}
+	throttled := interfaces.Response{
+		Status: interfaces.Status{
+			Code:    interfaces.StatusServerError,
+			Message: "throttled",
+			Attributes: map[string]interface{}{
+				"x-ms-status-code":    429,
+				"x-ms-substatus-code": 3200,
+				"x-ms-retry-after-ms": "00:00:00.0100000",
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	gomock.InOrder(
+		// the client reports the error of the last response via the error message as well
+		queryExecutor.EXPECT().ExecuteAsync(query, gomock.Any()).Times(1).DoAndReturn(func(q string, resp chan interfaces.AsyncResponse) error {
+			resp <- interfaces.AsyncResponse{Response: throttled, ErrorMessage: "server error"}
+			close(resp)
+			return nil
+		}),
+		queryExecutor.EXPECT().ExecuteAsync(query, gomock.Any()).Times(1).DoAndReturn(func(q string, resp chan interfaces.AsyncResponse) error {
+			resp <- interfaces.AsyncResponse{Response: success}
+			close(resp)
+			return nil
+		}),
+	)
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	err = cosmos.ExecuteAsync(query, responseChannel)
+	require.NoError(t, err)
+
+	asyncResponses := make([]interfaces.AsyncResponse, 0, 1)
+	for resp := range responseChannel {
+		asyncResponses = append(asyncResponses, resp)
+	}
+
+	// THEN
+	require.Len(t, asyncResponses, 1)
+	assert.Equal(t, success, asyncResponses[0].Response)
+	assert.NoError(t, asyncResponses[0].Err)
+}
+
+func TestCosmosImpl_ExecuteAsync_DeliversCosmosErrorWithErrorMessage(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   1,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V()"
+	throttled := interfaces.Response{
+		Status: interfaces.Status{
+			Code:    interfaces.StatusServerError,
+			Message: "throttled",
+			Attributes: map[string]interface{}{
+				"x-ms-status-code":    429,
+				"x-ms-substatus-code": 3200,
+				"x-ms-retry-after-ms": "00:00:00.0100000",
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().ExecuteAsync(query, gomock.Any()).Times(2).DoAndReturn(func(q string, resp chan interfaces.AsyncResponse) error {
+		resp <- interfaces.AsyncResponse{Response: throttled, ErrorMessage: "server error"}
+		close(resp)
+		return nil
+	})
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	err = cosmos.ExecuteAsync(query, responseChannel)
+	require.NoError(t, err)
+
+	asyncResponses := make([]interfaces.AsyncResponse, 0, 1)
+	for resp := range responseChannel {
+		asyncResponses = append(asyncResponses, resp)
+	}
+
+	// THEN
+	require.Len(t, asyncResponses, 1)
+	assert.Equal(t, throttled, asyncResponses[0].Response)
+	assert.True(t, IsThrottled(asyncResponses[0].Err))
+	cosmosErr, ok := asyncResponses[0].Err.(CosmosError)
+	require.True(t, ok, "Expected a CosmosError")
+	assert.Equal(t, 429, cosmosErr.StatusCode)
+}
+
+type recordingRetryPolicy struct {
+	attempts []RetryAttempt
+	policy   RetryPolicy
+}
+
+func (p *recordingRetryPolicy) ShouldRetry(attempt RetryAttempt) (bool, time.Duration) {
+	p.attempts = append(p.attempts, attempt)
+	return p.policy.ShouldRetry(attempt)
+}
+
+func TestHandleRetryLoop_RetryPolicy(t *testing.T) {
+	// GIVEN
+	metrics := newStubbedMetrics()
+	policy := &recordingRetryPolicy{policy: DefaultRetryPolicy(2)}
+	tryCount := 0
+	retryFn := func() ([]interfaces.Response, error) {
+		tryCount++
+		if tryCount == 2 {
+			return nil, ErrNoConnection
+		}
+		response := interfaces.Response{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					string(headerRetryAfterMS):  "00:00:00.010",
+					string(headerStatusCode):    "429",
+					string(headerSubStatusCode): "3200",
+				},
+			},
+		}
+		return []interfaces.Response{response}, nil
+	}
+
+	// WHEN
+	responses, err := retryLoop(retryFn, policy, time.Second, true, true, metrics, nil, zerolog.Nop())
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Len(t, responses, 1)
+	assert.Equal(t, 3, tryCount)
+	require.Len(t, policy.attempts, 3)
+	assert.Equal(t, RetryAttempt{Attempt: 1, StatusCode: 429, SubStatusCode: 3200, RetryAfter: time.Millisecond * 10, Retryable: true}, policy.attempts[0])
+	assert.Equal(t, 2, policy.attempts[1].Attempt)
+	assert.True(t, policy.attempts[1].Retryable)
+	assert.True(t, IsNetworkErr(policy.attempts[1].Err))
+	assert.Equal(t, 3, policy.attempts[2].Attempt)
+	assert.Equal(t, 429, policy.attempts[2].StatusCode)
+}
+
+func TestHandleRetryLoop_NoPolicy(t *testing.T) {
+	// GIVEN
+	metrics := newStubbedMetrics()
+	tryCount := 0
+	retryFn := func() ([]interfaces.Response, error) {
+		tryCount++
+		return nil, ErrNoConnection
+	}
+
+	// WHEN
+	responses, err := retryLoop(retryFn, nil, time.Second, true, true, metrics, nil, zerolog.Nop())
+
+	// THEN
+	assert.Error(t, err)
+	assert.Nil(t, responses)
+	assert.Equal(t, 1, tryCount)
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, _ := NewMockedMetrics(mockCtrl)
+	policy := ExponentialBackoff(3, time.Millisecond*100, time.Second)
+	overridePolicy := DefaultRetryPolicy(1)
+
+	// WHEN
+	cosmos, err := New("ws://host", WithRetryPolicy(policy), withMetrics(metrics))
+	require.NoError(t, err)
+
+	// THEN
+	cImpl := toCosmosImpl(t, cosmos)
+	assert.Equal(t, policy, cImpl.retryPolicy)
+	assert.Equal(t, time.Second*30, cImpl.retryTimeout)
+	assert.Equal(t, policy, cImpl.retryPolicyFor(newRequestOptions()))
+	assert.Equal(t, overridePolicy, cImpl.retryPolicyFor(newRequestOptions(UseRetryPolicy(overridePolicy))))
+	assert.NoError(t, cosmos.Stop())
+}
+
+func TestCosmosImpl_Execute_UseRetryPolicy(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   0,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V()"
+	doRetry := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code":    429,
+					"x-ms-substatus-code": 3200,
+				},
+			},
+		},
+	}
+	success := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	gomock.InOrder(
+		queryExecutor.EXPECT().Execute(query).Return(doRetry, nil),
+		queryExecutor.EXPECT().Execute(query).Return(success, nil),
+	)
+	policy := &recordingRetryPolicy{policy: ExponentialBackoff(1, time.Millisecond, time.Millisecond*10)}
+
+	// WHEN
+	responses, err := cosmos.Execute(query, UseRetryPolicy(policy))
+
+	// THEN
+	assert.NoError(t, err)
+	assert.EqualValues(t, success, responses)
+	assert.Len(t, policy.attempts, 2)
+}
+
+func TestRequestUnitRateLimit(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, _ := NewMockedMetrics(mockCtrl)
+
+	// WHEN
+	cosmos, err := New("ws://host", RequestUnitRateLimit(400), withMetrics(metrics))
+	require.NoError(t, err)
+
+	// THEN
+	cImpl := toCosmosImpl(t, cosmos)
+	assert.Equal(t, 400.0, cImpl.requestUnitsPerSecond)
+	require.NotNil(t, cImpl.ruLimiter)
+	assert.Equal(t, 400.0, cImpl.ruLimiter.maxRate)
+	assert.NoError(t, cosmos.Stop())
+
+	// WHEN
+	cosmos, err = New("ws://host", RequestUnitRateLimit(-1), withMetrics(metrics))
+
+	// THEN
+	assert.Error(t, err)
+	assert.Nil(t, cosmos)
+}
+
+func TestCosmosImpl_Execute_RequestUnitRateLimit(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	metrics := newStubbedMetrics()
+	limiter, clock := newTestRULimiter(100, metrics)
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      metrics,
+		retryTimeout: time.Second * 2,
+		ruLimiter:    limiter,
+	}
+
+	query := "g.V()"
+	responses := []interfaces.Response{newChargedResponse(200, 60, "")}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().Execute(query).Times(3).Return(responses, nil)
+
+	// WHEN
+	for i := 0; i < 3; i++ {
+		_, err = cosmos.Execute(query)
+		require.NoError(t, err)
+	}
+
+	// THEN
+	assert.Equal(t, 60.0, limiter.learnedCosts[query])
+	require.Len(t, clock.slept, 2, "Expected the requests to be paced after the first one")
+	assert.Equal(t, time.Millisecond*200, clock.slept[0])
+	assert.Equal(t, time.Millisecond*600, clock.slept[1])
+}
+
+func TestCosmosImpl_Execute_CollectDiagnostics(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   1,
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V()"
+	doRetry := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusServerError,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code":          429,
+					"x-ms-substatus-code":       3200,
+					"x-ms-total-request-charge": 1.5,
+					"x-ms-activity-id":          "activity-1",
+				},
+			},
+		},
+	}
+	success := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusSuccess,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code":          200,
+					"x-ms-total-request-charge": 20,
+					"x-ms-activity-id":          "activity-2",
+				},
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	gomock.InOrder(
+		queryExecutor.EXPECT().Execute(query).Return(doRetry, nil),
+		queryExecutor.EXPECT().Execute(query).Return(success, nil),
+	)
+
+	// WHEN
+	var diagnostics Diagnostics
+	_, err = cosmos.Execute(query, CollectDiagnostics(&diagnostics))
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, float32(21.5), diagnostics.RequestCharge)
+	assert.Equal(t, []string{"activity-1", "activity-2"}, diagnostics.ActivityIDs)
+	assert.Equal(t, 2, diagnostics.Attempts)
+	assert.Equal(t, 1, diagnostics.Retries)
+	assert.True(t, diagnostics.Latency > 0)
+	require.Len(t, diagnostics.Chunks, 2)
+	assert.Equal(t, 429, diagnostics.Chunks[0].StatusCode)
+	assert.Equal(t, 3200, diagnostics.Chunks[0].SubStatusCode)
+	assert.Equal(t, 2, diagnostics.Chunks[1].Attempt)
+}
+
+func TestCosmosImpl_Execute_QueryLog(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		retryTimeout: time.Second * 2,
+		queryLog:     &queryLogger{logger: zerolog.New(&buf), requestChargeThreshold: 10},
+	}
+
+	query := `g.V().has("name", "Max")`
+	expensive := []interfaces.Response{
+		{
+			Status: interfaces.Status{
+				Code: interfaces.StatusSuccess,
+				Attributes: map[string]interface{}{
+					"x-ms-status-code":          200,
+					"x-ms-total-request-charge": 20,
+					"x-ms-activity-id":          "activity-1",
+				},
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().Execute(query).Return(expensive, nil)
+
+	// WHEN
+	_, err = cosmos.Execute(query)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `"reason":"expensive"`)
+	assert.Contains(t, buf.String(), `"fingerprint":"g.V().has(?, ?)"`)
+	assert.Contains(t, buf.String(), `"activity_ids":["activity-1"]`)
+}
+
+func TestCosmosImpl_ExecuteAsync_CollectDiagnostics(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		retryTimeout: time.Second * 2,
+	}
+
+	query := "g.V()"
+	success := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusSuccess,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code":          200,
+				"x-ms-total-request-charge": 20,
+				"x-ms-activity-id":          "activity-1",
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().ExecuteAsync(query, gomock.Any()).DoAndReturn(func(q string, resp chan interfaces.AsyncResponse) error {
+		resp <- interfaces.AsyncResponse{Response: success}
+		close(resp)
+		return nil
+	})
+
+	// WHEN
+	var diagnostics Diagnostics
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	err = cosmos.ExecuteAsync(query, responseChannel, CollectDiagnostics(&diagnostics))
+	require.NoError(t, err)
+	for range responseChannel {
+	}
+
+	// THEN
+	assert.Equal(t, float32(20), diagnostics.RequestCharge)
+	assert.Equal(t, []string{"activity-1"}, diagnostics.ActivityIDs)
+	assert.Equal(t, 1, diagnostics.Attempts)
+}
+
+func TestCosmosImpl_Execute_OperationMetrics(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	latency := mock_metrics.NewMockHistogramVec(mockCtrl)
+	requestCharge := mock_metrics.NewMockHistogramVec(mockCtrl)
+	serverTime := mock_metrics.NewMockHistogramVec(mockCtrl)
+	metrics := newStubbedMetrics()
+	metrics.requestLatencyMS = latency
+	metrics.requestChargePerOperation = requestCharge
+	metrics.serverTimePerOperationMS = serverTime
+
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      metrics,
+		retryTimeout: time.Second,
+	}
+
+	query := "g.V().hasLabel('user')"
+	response := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusSuccess,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code":          200,
+				"x-ms-total-request-charge": 12.5,
+				"x-ms-total-server-time-ms": 7,
+			},
+		},
+	}
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+	queryExecutor.EXPECT().Execute(query).Return([]interfaces.Response{response}, nil)
+	queryExecutor.EXPECT().Execute(query).Return(nil, fmt.Errorf("failed"))
+
+	latencyHistogram := mock_metrics.NewMockHistogram(mockCtrl)
+	latencyHistogram.EXPECT().Observe(gomock.Any()).Times(2)
+	requestChargeHistogram := mock_metrics.NewMockHistogram(mockCtrl)
+	requestChargeHistogram.EXPECT().Observe(float64(12.5))
+	requestChargeHistogram.EXPECT().Observe(float64(0))
+	serverTimeHistogram := mock_metrics.NewMockHistogram(mockCtrl)
+	serverTimeHistogram.EXPECT().Observe(float64(7))
+	serverTimeHistogram.EXPECT().Observe(float64(0))
+
+	latency.EXPECT().WithLabelValues("list_users", "success").Return(latencyHistogram)
+	requestCharge.EXPECT().WithLabelValues("list_users", "success").Return(requestChargeHistogram)
+	serverTime.EXPECT().WithLabelValues("list_users", "success").Return(serverTimeHistogram)
+	latency.EXPECT().WithLabelValues("unnamed", "error").Return(latencyHistogram)
+	requestCharge.EXPECT().WithLabelValues("unnamed", "error").Return(requestChargeHistogram)
+	serverTime.EXPECT().WithLabelValues("unnamed", "error").Return(serverTimeHistogram)
+
+	// WHEN
+	_, errSuccess := cosmos.Execute(query, OperationName("list_users"))
+	_, errFailure := cosmos.Execute(query)
+
+	// THEN
+	assert.NoError(t, errSuccess)
+	assert.Error(t, errFailure)
+}
@@ -0,0 +1,126 @@
+package gremcos
+
+import (
+	"time"
+
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// Diagnostics contains information about the execution of one request, e.g. the request units (RU) it consumed.
+// It can be obtained by passing the CollectDiagnostics option to the request.
+type Diagnostics struct {
+	// RequestCharge is the total amount of request units (RU) consumed by the request, including all retries
+	// and the abandoned hedged requests that finished before the request returned (see HedgeReadRequests).
+	RequestCharge float32
+	// ServerTime is the total time spent on the server for the request, including all retries.
+	ServerTime time.Duration
+	// ActivityIDs are the cosmos activity IDs of the responses (x-ms-activity-id).
+	// They are needed when filing a support ticket for a specific request at Azure.
+	ActivityIDs []string
+	// Attempts is the number of times the request was sent. Retries = Attempts - 1.
+	Attempts int
+	// Retries is the number of times the request was retried.
+	Retries int
+	// Latency is the total time it took to execute the request on client side, including all retries and waiting times.
+	Latency time.Duration
+	// Chunks contains the information for each of the received responses (of all attempts).
+	Chunks []ChunkDiagnostics
+}
+
+// ChunkDiagnostics contains information about one response of a request.
+// A request can be answered by multiple responses (partial content), e.g. in case of a big result.
+type ChunkDiagnostics struct {
+	// Attempt is the number of the attempt the response belongs to, starting at 1 for the initial request.
+	Attempt int
+	// StatusCode is the cosmos status code (x-ms-status-code) or the gremlin status code if it is not available.
+	StatusCode int
+	// SubStatusCode is the cosmos sub status code (x-ms-substatus-code)
+	SubStatusCode int
+	// RequestCharge is the amount of request units (RU) consumed for this response (x-ms-request-charge).
+	RequestCharge float32
+	// ServerTime is the time spent on the server for this response (x-ms-server-time-ms).
+	ServerTime time.Duration
+	// ActivityID is the cosmos activity ID of this response (x-ms-activity-id).
+	ActivityID string
+}
+
+// CollectDiagnostics fills the given Diagnostics with the information gathered while executing the request.
+//
+//	var diagnostics gremcos.Diagnostics
+//	responses, err := cosmos.Execute("g.V()", gremcos.CollectDiagnostics(&diagnostics))
+//	log.Printf("query consumed %f RU (activity ids: %v)", diagnostics.RequestCharge, diagnostics.ActivityIDs)
+//
+// For ExecuteAsync the diagnostics are complete as soon as the response channel is closed.
+func CollectDiagnostics(diagnostics *Diagnostics) RequestOption {
+	return func(r *requestOptions) {
+		r.diagnostics = diagnostics
+	}
+}
+
+// addAttempt adds the information of the given responses as new attempt.
+func (d *Diagnostics) addAttempt(responses []interfaces.Response, latency time.Duration) {
+	if d == nil {
+		return
+	}
+
+	d.Attempts++
+	d.Retries = d.Attempts - 1
+	d.Latency = latency
+	d.addResponses(d.Attempts, responses)
+}
+
+// addAbandoned adds the information of the given responses of an abandoned hedged request (see HedgeReadRequests)
+// to the attempt in progress. This attempt is added via addAttempt as soon as the winning request returned.
+func (d *Diagnostics) addAbandoned(responses []interfaces.Response) {
+	if d == nil {
+		return
+	}
+	d.addResponses(d.Attempts+1, responses)
+}
+
+// addResponses adds the information of the given responses that belong to the given attempt.
+func (d *Diagnostics) addResponses(attempt int, responses []interfaces.Response) {
+	var requestChargeTotal float32
+	var serverTimeTotal time.Duration
+	for _, response := range responses {
+		chunk := ChunkDiagnostics{Attempt: attempt, StatusCode: response.Status.Code}
+
+		respInfo, err := parseAttributeMap(response.Status.Attributes)
+		if err == nil {
+			chunk.StatusCode = respInfo.statusCode
+			chunk.SubStatusCode = respInfo.subStatusCode
+			chunk.RequestCharge = respInfo.requestCharge
+			chunk.ServerTime = respInfo.serverTime
+			chunk.ActivityID = respInfo.activityID
+
+			// only take the largest value since cosmos already accumulates this value
+			if requestChargeTotal < respInfo.requestChargeTotal {
+				requestChargeTotal = respInfo.requestChargeTotal
+			}
+
+			// only take the largest value since cosmos already accumulates this value
+			if serverTimeTotal < respInfo.serverTimeTotal {
+				serverTimeTotal = respInfo.serverTimeTotal
+			}
+
+			d.addActivityID(respInfo.activityID)
+		}
+		d.Chunks = append(d.Chunks, chunk)
+	}
+
+	d.RequestCharge += requestChargeTotal
+	d.ServerTime += serverTimeTotal
+}
+
+func (d *Diagnostics) addActivityID(activityID string) {
+	if len(activityID) == 0 {
+		return
+	}
+
+	for _, known := range d.ActivityIDs {
+		if known == activityID {
+			return
+		}
+	}
+	d.ActivityIDs = append(d.ActivityIDs, activityID)
+}
@@ -0,0 +1,68 @@
+package gremcos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+func TestDiagnosticsAddAttempt(t *testing.T) {
+	// GIVEN
+	diagnostics := &Diagnostics{}
+	partial := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusPartialContent,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code":          200,
+				"x-ms-request-charge":       10.5,
+				"x-ms-total-request-charge": 10.5,
+				"x-ms-server-time-ms":       2.5,
+				"x-ms-total-server-time-ms": 2.5,
+				"x-ms-activity-id":          "activity-1",
+			},
+		},
+	}
+	final := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusSuccess,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code":          200,
+				"x-ms-request-charge":       4.5,
+				"x-ms-total-request-charge": 15,
+				"x-ms-server-time-ms":       1.5,
+				"x-ms-total-server-time-ms": 4,
+				"x-ms-activity-id":          "activity-1",
+			},
+		},
+	}
+	noAttributes := interfaces.Response{Status: interfaces.Status{Code: interfaces.StatusSuccess}}
+
+	// WHEN
+	diagnostics.addAttempt([]interfaces.Response{partial, final}, time.Millisecond*10)
+	diagnostics.addAttempt([]interfaces.Response{noAttributes}, time.Millisecond*20)
+
+	// THEN
+	assert.Equal(t, float32(15), diagnostics.RequestCharge)
+	assert.Equal(t, time.Millisecond*4, diagnostics.ServerTime)
+	assert.Equal(t, []string{"activity-1"}, diagnostics.ActivityIDs)
+	assert.Equal(t, 2, diagnostics.Attempts)
+	assert.Equal(t, 1, diagnostics.Retries)
+	assert.Equal(t, time.Millisecond*20, diagnostics.Latency)
+	require.Len(t, diagnostics.Chunks, 3)
+	assert.Equal(t, ChunkDiagnostics{Attempt: 1, StatusCode: 200, RequestCharge: 10.5, ServerTime: time.Microsecond * 2500, ActivityID: "activity-1"}, diagnostics.Chunks[0])
+	assert.Equal(t, ChunkDiagnostics{Attempt: 1, StatusCode: 200, RequestCharge: 4.5, ServerTime: time.Microsecond * 1500, ActivityID: "activity-1"}, diagnostics.Chunks[1])
+	assert.Equal(t, ChunkDiagnostics{Attempt: 2, StatusCode: interfaces.StatusSuccess}, diagnostics.Chunks[2])
+}
+
+func TestDiagnosticsNil(t *testing.T) {
+	// GIVEN
+	var diagnostics *Diagnostics
+
+	// WHEN + THEN
+	assert.NotPanics(t, func() {
+		diagnostics.addAttempt([]interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}, time.Second)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/supplyon/gremcos/interfaces"
 )
@@ -16,6 +17,16 @@ const (
 	ErrorCategoryAuth         ErrorCategory = "AuthErr"
 	ErrorCategoryClient       ErrorCategory = "ClientErr"
 	ErrorCategoryServer       ErrorCategory = "ServerErr"
+	// ErrorCategoryThrottled the request was throttled by cosmos since the provisioned throughput was exceeded (429)
+	ErrorCategoryThrottled ErrorCategory = "ThrottledErr"
+	// ErrorCategoryConflict the request conflicts with the current state of a resource, e.g. the vertex already exists (409)
+	ErrorCategoryConflict ErrorCategory = "ConflictErr"
+	// ErrorCategoryPreconditionFailed an optimistic concurrency control violation occurred (412)
+	ErrorCategoryPreconditionFailed ErrorCategory = "PreconditionFailedErr"
+	// ErrorCategoryNotFound the addressed resource (e.g. database or graph) does not exist (404)
+	ErrorCategoryNotFound ErrorCategory = "NotFoundErr"
+	// ErrorCategoryTimeout the request took too long and was canceled by the server (408)
+	ErrorCategoryTimeout ErrorCategory = "TimeoutErr"
 )
 
 type Error struct {
@@ -29,6 +40,143 @@ func (e Error) Error() string {
 
 var ErrNoConnection = Error{Wrapped: fmt.Errorf("no connection"), Category: ErrorCategoryConnectivity}
 
+// ErrSharedExecutionAborted is returned to coalesced requests (see CoalesceReads) in case the execution they waited for
+// was aborted without a result (e.g. it panicked).
+var ErrSharedExecutionAborted = Error{Wrapped: fmt.Errorf("the shared execution of the request was aborted"), Category: ErrorCategoryGeneral}
+
+// ErrUnknownOutcome can be used to check (errors.Is) whether a request failed with an unknown outcome (see UnknownOutcomeError).
+var ErrUnknownOutcome = errors.New("unknown outcome")
+
+// UnknownOutcomeError is returned in case a request failed in a way that it is unknown whether the server has executed it
+// (e.g. 1007, 1008 or a connectivity issue after the request was sent, see RetryOnConnectivityErrors) and the retry policy
+// would have retried it, but the request is not safe to retry (see ReadOnly and Idempotent).
+// In case the retry policy would not have retried the request anyway (e.g. DefaultRetryPolicy(0)), the error of the response is returned.
+// The caller has to check whether the mutation was applied before issuing the request again.
+// The error wraps the error of the failed request.
+type UnknownOutcomeError struct {
+	Wrapped error
+}
+
+func (e UnknownOutcomeError) Error() string {
+	return fmt.Sprintf("%v, the request might have been executed (not retried since it is not marked as read-only or idempotent): %v", ErrUnknownOutcome, e.Wrapped)
+}
+
+func (e UnknownOutcomeError) Unwrap() error {
+	return e.Wrapped
+}
+
+func (e UnknownOutcomeError) Is(target error) bool {
+	return target == ErrUnknownOutcome
+}
+
+// CosmosError is the error returned in case cosmos responded with an error status code.
+// It can be obtained via errors.As:
+//
+//	var cosmosErr gremcos.CosmosError
+//	if errors.As(err, &cosmosErr) && cosmosErr.StatusCode == 404 {
+//		...
+//	}
+type CosmosError struct {
+	// StatusCode is the cosmos status code (x-ms-status-code)
+	StatusCode int
+	// SubStatusCode is the cosmos sub status code (x-ms-substatus-code)
+	SubStatusCode int
+	// ActivityID is the cosmos activity ID of the response (x-ms-activity-id)
+	ActivityID string
+	// RetryAfter is the time cosmos suggests to wait before the request is retried (x-ms-retry-after-ms)
+	RetryAfter time.Duration
+	// RequestCharge is the amount of request units (RU) consumed by the request (x-ms-total-request-charge)
+	RequestCharge float32
+	// Message is the raw error message of the response
+	Message string
+	// Description is the description of the status code taken from https://docs.microsoft.com/en-us/azure/cosmos-db/gremlin-headers#status-codes
+	Description string
+	// Category is the category of the error derived from the status code
+	Category ErrorCategory
+}
+
+func (e CosmosError) Error() string {
+	return fmt.Sprintf("%d (%d) - %s", e.StatusCode, e.SubStatusCode, e.Description)
+}
+
+// newCosmosError creates the error based on the given information taken from the response attributes
+func newCosmosError(responseInfo responseInformation, message string) CosmosError {
+	return CosmosError{
+		StatusCode:    responseInfo.statusCode,
+		SubStatusCode: responseInfo.subStatusCode,
+		ActivityID:    responseInfo.activityID,
+		RetryAfter:    responseInfo.retryAfter,
+		RequestCharge: responseInfo.requestChargeTotal,
+		Message:       message,
+		Description:   responseInfo.statusDescription,
+		Category:      cosmosStatusCodeToCategory(responseInfo.statusCode),
+	}
+}
+
+// cosmosStatusCodeToCategory maps the cosmos status code to the according error category
+func cosmosStatusCodeToCategory(statusCode int) ErrorCategory {
+	switch statusCode {
+	case 401:
+		return ErrorCategoryAuth
+	case 404:
+		return ErrorCategoryNotFound
+	case 408:
+		return ErrorCategoryTimeout
+	case 409:
+		return ErrorCategoryConflict
+	case 412:
+		return ErrorCategoryPreconditionFailed
+	case 429:
+		return ErrorCategoryThrottled
+	case 1000, 1001, 1003, 1004:
+		return ErrorCategoryClient
+	case 1007, 1008:
+		return ErrorCategoryConnectivity
+	default:
+		return ErrorCategoryServer
+	}
+}
+
+// asCosmosError returns the CosmosError in case the given error is (or wraps) one
+func asCosmosError(err error) (CosmosError, bool) {
+	cosmosErr := CosmosError{}
+	ok := errors.As(err, &cosmosErr)
+	return cosmosErr, ok
+}
+
+// IsThrottled returns true in case the request was throttled by cosmos (429), since the provisioned throughput was exceeded.
+func IsThrottled(err error) bool {
+	cosmosErr, ok := asCosmosError(err)
+	return ok && cosmosErr.StatusCode == 429
+}
+
+// IsConflict returns true in case the request conflicts with the current state of a resource (409), e.g. the vertex already exists.
+func IsConflict(err error) bool {
+	cosmosErr, ok := asCosmosError(err)
+	return ok && cosmosErr.StatusCode == 409
+}
+
+// IsPreconditionFailed returns true in case an optimistic concurrency control violation occurred (412).
+func IsPreconditionFailed(err error) bool {
+	cosmosErr, ok := asCosmosError(err)
+	return ok && cosmosErr.StatusCode == 412
+}
+
+// IsUnknownOutcome returns true in case it is unknown whether the server has executed the failed request (see UnknownOutcomeError).
+func IsUnknownOutcome(err error) bool {
+	return errors.Is(err, ErrUnknownOutcome)
+}
+
+// IsRetryable returns true in case cosmos suggests to retry the failed request (409, 412, 429, 1007, 1008).
+func IsRetryable(err error) bool {
+	cosmosErr, ok := asCosmosError(err)
+	if !ok {
+		return false
+	}
+	description := statusCodeDescription[cosmosErr.StatusCode]
+	return description.retry || description.retryOnNewConnection
+}
+
 // IsNetworkErr determines whether the given error is related to any network issues (timeout, connectivity,..)
 func IsNetworkErr(err error) bool {
 	if errors.Is(err, ErrNoConnection) {
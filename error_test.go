@@ -3,6 +3,9 @@ package gremcos
 import (
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -108,3 +111,87 @@ func TestResponseErrorDetection(t *testing.T) {
 		}
 	}
 }
+
+func TestCosmosError(t *testing.T) {
+	// GIVEN
+	responseInfo := responseInformation{
+		statusCode:         429,
+		subStatusCode:      3200,
+		statusDescription:  "Request was throttled and should be retried after value in x-ms-retry-after-ms",
+		requestChargeTotal: 3.5,
+		activityID:         "activity-1",
+		retryAfter:         time.Millisecond * 500,
+	}
+
+	// WHEN
+	err := newCosmosError(responseInfo, "raw message")
+
+	// THEN
+	assert.EqualError(t, err, "429 (3200) - Request was throttled and should be retried after value in x-ms-retry-after-ms")
+	assert.Equal(t, 429, err.StatusCode)
+	assert.Equal(t, 3200, err.SubStatusCode)
+	assert.Equal(t, "activity-1", err.ActivityID)
+	assert.Equal(t, time.Millisecond*500, err.RetryAfter)
+	assert.Equal(t, float32(3.5), err.RequestCharge)
+	assert.Equal(t, "raw message", err.Message)
+	assert.Equal(t, ErrorCategoryThrottled, err.Category)
+}
+
+func TestCosmosErrorPredicates(t *testing.T) {
+	// GIVEN
+	throttled := errors.Wrap(CosmosError{StatusCode: 429}, "executing request")
+	conflict := CosmosError{StatusCode: 409}
+	preconditionFailed := CosmosError{StatusCode: 412}
+	connectionClosed := CosmosError{StatusCode: 1007}
+	notFound := CosmosError{StatusCode: 404}
+	other := fmt.Errorf("429")
+
+	// WHEN + THEN
+	assert.True(t, IsThrottled(throttled))
+	assert.False(t, IsThrottled(conflict))
+	assert.False(t, IsThrottled(other))
+	assert.False(t, IsThrottled(nil))
+
+	assert.True(t, IsConflict(conflict))
+	assert.False(t, IsConflict(throttled))
+
+	assert.True(t, IsPreconditionFailed(preconditionFailed))
+	assert.False(t, IsPreconditionFailed(conflict))
+
+	assert.True(t, IsRetryable(throttled))
+	assert.True(t, IsRetryable(conflict))
+	assert.True(t, IsRetryable(preconditionFailed))
+	assert.True(t, IsRetryable(connectionClosed))
+	assert.False(t, IsRetryable(notFound))
+	assert.False(t, IsRetryable(other))
+}
+
+func TestUnknownOutcomeError(t *testing.T) {
+	// GIVEN
+	cosmosErr := CosmosError{StatusCode: 1007, Description: "connection closed"}
+
+	// WHEN
+	err := fmt.Errorf("executing: %w", UnknownOutcomeError{Wrapped: cosmosErr})
+
+	// THEN
+	assert.True(t, IsUnknownOutcome(err))
+	assert.True(t, errors.Is(err, ErrUnknownOutcome))
+	assert.False(t, IsUnknownOutcome(cosmosErr))
+	wrapped, ok := asCosmosError(err)
+	assert.True(t, ok)
+	assert.Equal(t, cosmosErr, wrapped)
+	assert.Equal(t, "executing: unknown outcome, the request might have been executed (not retried since it is not marked as read-only or idempotent): 1007 (0) - connection closed", err.Error())
+}
+
+func TestCosmosStatusCodeToCategory(t *testing.T) {
+	assert.Equal(t, ErrorCategoryAuth, cosmosStatusCodeToCategory(401))
+	assert.Equal(t, ErrorCategoryNotFound, cosmosStatusCodeToCategory(404))
+	assert.Equal(t, ErrorCategoryTimeout, cosmosStatusCodeToCategory(408))
+	assert.Equal(t, ErrorCategoryConflict, cosmosStatusCodeToCategory(409))
+	assert.Equal(t, ErrorCategoryPreconditionFailed, cosmosStatusCodeToCategory(412))
+	assert.Equal(t, ErrorCategoryThrottled, cosmosStatusCodeToCategory(429))
+	assert.Equal(t, ErrorCategoryServer, cosmosStatusCodeToCategory(500))
+	assert.Equal(t, ErrorCategoryClient, cosmosStatusCodeToCategory(1004))
+	assert.Equal(t, ErrorCategoryConnectivity, cosmosStatusCodeToCategory(1008))
+	assert.Equal(t, ErrorCategoryServer, cosmosStatusCodeToCategory(1234))
+}
@@ -76,9 +76,7 @@ func queryCosmos(cosmos gremcos.Cosmos, logger zerolog.Logger) {
 	// If you want to run your queries against a apache tinkerpop gremlin server it is recommended
 	// to switch the used query language to QueryLanguageTinkerpopGremlin.
 	// Per default the CosmosDB compatible query language will be used.
-	api.SetQueryLanguageTo(api.QueryLanguageTinkerpopGremlin)
-
-	g := api.NewGraph("g")
+	g := api.NewGraph("g", api.WithDialect(api.QueryLanguageTinkerpopGremlin))
 	query := g.AddV("User").Property("userid", "12345").Property("email", "max.mustermann@example.com")
 
 	logger.Info().Msgf("Query: %s", query)
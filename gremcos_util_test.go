@@ -4,8 +4,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 	"github.com/supplyon/gremcos/interfaces"
+	mock_interfaces "github.com/supplyon/gremcos/test/mocks/interfaces"
 )
 
 var failingErrorChannelConsumerFunc = func(errChan chan error, t *testing.T) {
@@ -25,6 +28,27 @@ func newTestClient(t *testing.T, errChan chan error) interfaces.QueryExecutor {
 	return client
 }
 
+// newMockedCosmos creates a connector whose pool hands out connections represented by the returned mock.
+// The connections are always healthy. The given options are applied to the connector.
+func newMockedCosmos(t *testing.T, mockCtrl *gomock.Controller, options ...Option) (*cosmosImpl, *mock_interfaces.MockQueryExecutor) {
+	queryExecutor, poolMock, err := newMockedPool(mockCtrl)
+	require.NoError(t, err)
+
+	queryExecutor.EXPECT().LastError().AnyTimes().Return(nil)
+	queryExecutor.EXPECT().IsConnected().AnyTimes().Return(true)
+
+	cosmos := &cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         poolMock,
+		metrics:      newStubbedMetrics(),
+		retryTimeout: time.Second * 2,
+	}
+	for _, option := range options {
+		option(cosmos)
+	}
+	return cosmos, queryExecutor
+}
+
 func newTestPool(t *testing.T, errChan chan error) *pool {
 	createQueryExecutorFn := func() (interfaces.QueryExecutor, error) {
 		websocket, err := NewWebsocket("ws://127.0.0.1:8182/gremlin")
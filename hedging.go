@@ -0,0 +1,187 @@
+package gremcos
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+const (
+	// hedgeLatencyWindowSize is the number of latencies of recent read requests the hedging delay is learned from
+	hedgeLatencyWindowSize = 500
+	// hedgeMinLatencySamples is the number of latencies needed before the learned hedging delay is used
+	hedgeMinLatencySamples = 20
+)
+
+// hedging sends a read request a second time on another pooled connection in case no final response arrived within a delay.
+// The result that arrives first wins, the other one is abandoned. The request charge of both requests is accounted.
+type hedging struct {
+	// delay is the time to wait for the final response before the hedged request is sent.
+	// In case a percentile is set, it is used until enough latencies were observed.
+	delay time.Duration
+	// percentile of the latencies of recent read requests that is used as delay (0 = use the fixed delay)
+	percentile float64
+
+	mux sync.Mutex
+	// latencies of recent read requests (ring buffer)
+	latencies []time.Duration
+	// next is the position in latencies where the next latency is stored
+	next int
+}
+
+// hedgeResult is the result of the original or the hedged request
+type hedgeResult struct {
+	responses []interfaces.Response
+	err       error
+	hedged    bool
+	// latency is the time from sending the original request until the result arrived
+	latency time.Duration
+}
+
+// HedgeReadRequests enables hedging of read requests to reduce the tail latency.
+// In case no final response arrived within the given delay, the request is sent a second time using another pooled connection.
+// The result that arrives first wins, the other one is abandoned. Hedged requests consume request units (RU) twice,
+// both charges are fed to the RU limiter (see RequestUnitRateLimit) and the request charge metrics.
+// In multiplexing mode (see MultiplexConnections) the hedged request uses another connection than the original one,
+// unless no other connection is available and no further one can be dialed.
+// Only requests that are marked as read-only (see ReadOnly) are hedged, mutations are never hedged.
+//
+//	cosmos.Execute("g.V().hasLabel('user')", gremcos.ReadOnly())
+func HedgeReadRequests(delay time.Duration) Option {
+	return func(c *cosmosImpl) {
+		c.hedging = &hedging{delay: delay}
+	}
+}
+
+// HedgeReadRequestsAtPercentile enables hedging of read requests like HedgeReadRequests, but the delay is learned
+// from the latencies of recent read requests. The hedged request is sent in case the request takes longer than
+// the given percentile (e.g. 95) of these latencies. The initialDelay is used until enough latencies were observed.
+func HedgeReadRequestsAtPercentile(percentile float64, initialDelay time.Duration) Option {
+	return func(c *cosmosImpl) {
+		c.hedging = &hedging{delay: initialDelay, percentile: percentile}
+	}
+}
+
+// validate returns an error in case the hedging is not configured properly
+func (h *hedging) validate() error {
+	if h == nil {
+		return nil
+	}
+	if h.delay <= 0 {
+		return fmt.Errorf("the hedging delay has to be >0")
+	}
+	if h.percentile < 0 || h.percentile > 100 {
+		return fmt.Errorf("the hedging percentile has to be in [0,100]")
+	}
+	return nil
+}
+
+// hedge wraps the given function that executes one attempt of a request, such that the attempt is hedged.
+// Requests that are not marked as read-only or that contain mutating steps are never hedged.
+// The responses of the abandoned request update the request metrics as soon as they arrive. They are added to
+// the diagnostics only if they arrived before the request returned, since the diagnostics are handed over to the caller then.
+func (c *cosmosImpl) hedge(query string, reqOptions requestOptions, execute attemptFun) retryFun {
+	if c.hedging == nil || !reqOptions.readOnly || !isReadOnlyQuery(query) {
+		return func() ([]interfaces.Response, error) {
+			return execute(nil)
+		}
+	}
+	return func() ([]interfaces.Response, error) {
+		return c.hedging.execute(execute, c.metrics, func(responses []interfaces.Response, afterReturn bool) {
+			updateRequestMetrics(responses, c.metrics, false)
+			if !afterReturn {
+				reqOptions.diagnostics.addAbandoned(responses)
+			}
+		})
+	}
+}
+
+// execute runs the given function and runs it a second time in case it didn't return within the hedging delay.
+// Both runs use different connections (as long as the pool is able to provide one).
+// The first successful result is returned. The result of the loser is handed over to abandoned as soon as it arrives,
+// afterReturn tells whether execute has returned already at that time.
+func (h *hedging) execute(execute attemptFun, metrics *Metrics, abandoned func(responses []interfaces.Response, afterReturn bool)) ([]interfaces.Response, error) {
+	used := &usedConnections{}
+	start := time.Now()
+	// buffered, hence both requests can always hand over their result and terminate
+	results := make(chan hedgeResult, 2)
+	run := func(hedged bool) {
+		responses, err := execute(used)
+		results <- hedgeResult{responses: responses, err: err, hedged: hedged, latency: time.Since(start)}
+	}
+
+	go run(false)
+
+	hedgeTimer := time.NewTimer(h.currentDelay())
+	defer hedgeTimer.Stop()
+
+	select {
+	case result := <-results:
+		h.observe(result.latency)
+		return result.responses, result.err
+	case <-hedgeTimer.C:
+	}
+
+	metrics.requestHedgesIssuedTotal.Inc()
+	go run(true)
+
+	result := <-results
+	if result.err != nil {
+		// the other request might still succeed
+		loser := result
+		result = <-results
+		abandoned(loser.responses, false)
+	} else {
+		go func() {
+			loser := <-results
+			abandoned(loser.responses, true)
+		}()
+	}
+
+	if result.hedged && result.err == nil {
+		metrics.requestHedgesWonTotal.Inc()
+	}
+	h.observe(result.latency)
+	return result.responses, result.err
+}
+
+// currentDelay returns the time to wait before the hedged request is sent
+func (h *hedging) currentDelay() time.Duration {
+	if h.percentile <= 0 {
+		return h.delay
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if len(h.latencies) < hedgeMinLatencySamples {
+		return h.delay
+	}
+
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	index := int(float64(len(sorted)-1) * h.percentile / 100)
+	return sorted[index]
+}
+
+// observe records the latency of a read request to learn the hedging delay
+func (h *hedging) observe(latency time.Duration) {
+	if h.percentile <= 0 {
+		return
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if len(h.latencies) < hedgeLatencyWindowSize {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgeLatencyWindowSize
+}
@@ -0,0 +1,320 @@
+package gremcos
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/interfaces"
+	mock_interfaces "github.com/supplyon/gremcos/test/mocks/interfaces"
+	mock_metrics "github.com/supplyon/gremcos/test/mocks/metrics"
+	"go.uber.org/goleak"
+)
+
+// ignoreAbandoned drops the result of the abandoned request
+func ignoreAbandoned(responses []interfaces.Response, afterReturn bool) {}
+
+func TestHedging_Validate(t *testing.T) {
+	var disabled *hedging
+	assert.NoError(t, disabled.validate())
+	assert.NoError(t, (&hedging{delay: time.Millisecond}).validate())
+	assert.NoError(t, (&hedging{delay: time.Millisecond, percentile: 95}).validate())
+	assert.Error(t, (&hedging{delay: 0}).validate())
+	assert.Error(t, (&hedging{delay: time.Millisecond, percentile: 101}).validate())
+	assert.Error(t, (&hedging{delay: time.Millisecond, percentile: -1}).validate())
+}
+
+func TestHedging_Execute_NoHedgeIfFast(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, _ := NewMockedMetrics(mockCtrl)
+
+	h := &hedging{delay: time.Second}
+	var calls int32
+	execute := func(_ *usedConnections) ([]interfaces.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return []interfaces.Response{{RequestID: "original"}}, nil
+	}
+
+	// WHEN
+	responses, err := h.execute(execute, metrics, ignoreAbandoned)
+
+	// THEN
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "original", responses[0].RequestID)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestHedging_Execute_HedgeWins(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, mocks := NewMockedMetrics(mockCtrl)
+	mocks.requestHedgesIssuedTotal.EXPECT().Inc()
+	mocks.requestHedgesWonTotal.EXPECT().Inc()
+
+	h := &hedging{delay: time.Millisecond * 10, percentile: 50}
+	release := make(chan struct{})
+	var calls int32
+	execute := func(_ *usedConnections) ([]interfaces.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// the original request is stuck on a slow connection
+			<-release
+			return []interfaces.Response{{RequestID: "original"}}, nil
+		}
+		return []interfaces.Response{{RequestID: "hedged"}}, nil
+	}
+	abandonedResponses := make(chan []interfaces.Response, 1)
+	abandoned := func(responses []interfaces.Response, afterReturn bool) {
+		assert.True(t, afterReturn)
+		abandonedResponses <- responses
+	}
+
+	// WHEN
+	responses, err := h.execute(execute, metrics, abandoned)
+	close(release)
+
+	// THEN
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "hedged", responses[0].RequestID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	// the latency is measured from the start of the original request
+	require.Len(t, h.latencies, 1)
+	assert.GreaterOrEqual(t, h.latencies[0], time.Millisecond*10)
+	// the result of the abandoned request is handed over as soon as it arrives
+	select {
+	case late := <-abandonedResponses:
+		assert.Equal(t, "original", late[0].RequestID)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the result of the abandoned request was not handed over")
+	}
+}
+
+func TestHedging_Execute_OriginalWinsAfterHedgeIssued(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, mocks := NewMockedMetrics(mockCtrl)
+	mocks.requestHedgesIssuedTotal.EXPECT().Inc()
+
+	h := &hedging{delay: time.Millisecond * 10}
+	release := make(chan struct{})
+	var calls int32
+	execute := func(_ *usedConnections) ([]interfaces.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(time.Millisecond * 30)
+			return []interfaces.Response{{RequestID: "original"}}, nil
+		}
+		// the hedged request is even slower
+		<-release
+		return []interfaces.Response{{RequestID: "hedged"}}, nil
+	}
+
+	// WHEN
+	responses, err := h.execute(execute, metrics, ignoreAbandoned)
+	close(release)
+
+	// THEN
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "original", responses[0].RequestID)
+}
+
+func TestHedging_Execute_FailureOfFirstResultIsIgnored(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, mocks := NewMockedMetrics(mockCtrl)
+	mocks.requestHedgesIssuedTotal.EXPECT().Inc()
+
+	h := &hedging{delay: time.Millisecond * 10}
+	var calls int32
+	execute := func(_ *usedConnections) ([]interfaces.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(time.Millisecond * 50)
+			return []interfaces.Response{{RequestID: "original"}}, nil
+		}
+		return nil, ErrNoConnection
+	}
+
+	handedOver := false
+	abandoned := func(responses []interfaces.Response, afterReturn bool) {
+		assert.False(t, afterReturn)
+		assert.Empty(t, responses)
+		handedOver = true
+	}
+
+	// WHEN
+	responses, err := h.execute(execute, metrics, abandoned)
+
+	// THEN
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "original", responses[0].RequestID)
+	assert.True(t, handedOver, "expected the failed request to be handed over before returning")
+}
+
+func TestHedging_CurrentDelay(t *testing.T) {
+	// GIVEN
+	h := &hedging{delay: time.Second, percentile: 90}
+
+	// WHEN + THEN
+	// not enough latencies observed
+	for i := 1; i < hedgeMinLatencySamples; i++ {
+		h.observe(time.Millisecond * time.Duration(i))
+	}
+	assert.Equal(t, time.Second, h.currentDelay())
+
+	h = &hedging{delay: time.Second, percentile: 90}
+	for i := 0; i < 100; i++ {
+		h.observe(time.Millisecond * time.Duration(i+1))
+	}
+	assert.Equal(t, time.Millisecond*90, h.currentDelay())
+
+	// the window is limited
+	for i := 0; i < hedgeLatencyWindowSize; i++ {
+		h.observe(time.Millisecond * 5)
+	}
+	assert.Len(t, h.latencies, hedgeLatencyWindowSize)
+	assert.Equal(t, time.Millisecond*5, h.currentDelay())
+
+	// fixed delay
+	fixed := &hedging{delay: time.Millisecond * 20}
+	fixed.observe(time.Second)
+	assert.Equal(t, time.Millisecond*20, fixed.currentDelay())
+	assert.Empty(t, fixed.latencies)
+}
+
+func TestCosmosImpl_Hedge_NeverHedgesMutationsAndUnmarkedRequests(t *testing.T) {
+	// GIVEN
+	cosmos := cosmosImpl{metrics: newStubbedMetrics(), hedging: &hedging{delay: time.Millisecond}}
+	var calls int32
+	execute := func(_ *usedConnections) ([]interfaces.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Millisecond * 20)
+		return nil, nil
+	}
+
+	// WHEN
+	_, err1 := cosmos.hedge("g.addV('user')", newRequestOptions(ReadOnly()), execute)()
+	_, err2 := cosmos.hedge("g.V()", newRequestOptions(), execute)()
+	_, err3 := cosmos.hedge("g.V()", newRequestOptions(Idempotent()), execute)()
+
+	// THEN
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.NoError(t, err3)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestCosmosImpl_Execute_HedgedRead(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	hedgesIssued := mock_metrics.NewMockCounter(mockCtrl)
+	hedgesIssued.EXPECT().Inc()
+	hedgesWon := mock_metrics.NewMockCounter(mockCtrl)
+	hedgesWon.EXPECT().Inc()
+	metrics := newStubbedMetrics()
+	metrics.requestHedgesIssuedTotal = hedgesIssued
+	metrics.requestHedgesWonTotal = hedgesWon
+
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl, withMetrics(metrics), HedgeReadRequests(time.Millisecond*10))
+	poolMock := cosmos.pool.(*pool)
+
+	query := "g.V().hasLabel('user')"
+	release := make(chan struct{})
+	var calls int32
+
+	queryExecutor.EXPECT().Execute(query).Times(2).DoAndReturn(func(q string) ([]interfaces.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+			return []interfaces.Response{{RequestID: "original", Status: interfaces.Status{Code: interfaces.StatusSuccess}}}, nil
+		}
+		return []interfaces.Response{{RequestID: "hedged", Status: interfaces.Status{Code: interfaces.StatusSuccess}}}, nil
+	})
+
+	// WHEN
+	responses, err := cosmos.Execute(query, ReadOnly())
+	close(release)
+
+	// THEN
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.Equal(t, "hedged", responses[0].RequestID)
+	// the abandoned request hands back its connection to the pool
+	assert.Eventually(t, func() bool {
+		poolMock.mu.RLock()
+		defer poolMock.mu.RUnlock()
+		return len(poolMock.idleConnections) == 2
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestCosmosImpl_Execute_HedgedReadUsesAnotherSharedConnection(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	slow := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	fast := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	p := newMockedMultiplexingPool(t, 2, 2, slow, fast)
+
+	metrics := newStubbedMetrics()
+	limiter, _ := newTestRULimiter(100, nil)
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         p,
+		metrics:      metrics,
+		retryTimeout: time.Second * 2,
+		hedging:      &hedging{delay: time.Millisecond * 10},
+		ruLimiter:    limiter,
+	}
+
+	query := "g.V().hasLabel('user')"
+	release := make(chan struct{})
+	for _, client := range []*mock_interfaces.MockQueryExecutor{slow, fast} {
+		client.EXPECT().LastError().AnyTimes().Return(nil)
+		client.EXPECT().IsConnected().AnyTimes().Return(true)
+	}
+	slow.EXPECT().Execute(query).DoAndReturn(func(q string) ([]interfaces.Response, error) {
+		<-release
+		return []interfaces.Response{newChargedResponse(200, 5, "")}, nil
+	})
+	fast.EXPECT().Execute(query).Return([]interfaces.Response{newChargedResponse(200, 3, "")}, nil)
+
+	// WHEN
+	var diagnostics Diagnostics
+	responses, err := cosmos.Execute(query, ReadOnly(), CollectDiagnostics(&diagnostics))
+	close(release)
+
+	// THEN
+	require.NoError(t, err)
+	require.Len(t, responses, 1)
+	assert.Equal(t, float32(3), diagnostics.RequestCharge)
+	// the charges of both requests are fed to the limiter
+	assert.Eventually(t, func() bool {
+		limiter.mu.Lock()
+		defer limiter.mu.Unlock()
+		return limiter.tokens == 92
+	}, time.Second, time.Millisecond*10)
+	assert.Eventually(t, func() bool {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		return p.sharedConnections[0].inFlight == 0 && p.sharedConnections[1].inFlight == 0
+	}, time.Second, time.Millisecond*10)
+}
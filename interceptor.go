@@ -0,0 +1,106 @@
+package gremcos
+
+import (
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// Request is a request to the cosmos db as it is passed through the interceptors (see WithInterceptors).
+// Interceptors may modify the request (e.g. the query or the options) before handing it over to the next one.
+type Request struct {
+	// Query is the gremlin query
+	Query string
+	// QueryBuilder is the query builder the query was created with, nil if the query was given as string (e.g. Execute)
+	QueryBuilder interfaces.QueryBuilder
+	// Bindings are the values of a parameterized query, nil if the query has no bindings
+	Bindings map[string]interface{}
+	// Rebindings of a parameterized query, nil if the query has no bindings
+	Rebindings map[string]interface{}
+	// ResponseChannel is the channel the responses are streamed to in case of ExecuteAsync, nil otherwise.
+	// Interceptors that want to inspect the streamed responses can replace it by an own channel that forwards to the original one.
+	// An interceptor that answers the request without calling the next ExecuteFunc has to close the channel.
+	ResponseChannel chan interfaces.AsyncResponse
+	// Metadata are the values the caller attached to the request (see WithMetadata)
+	Metadata map[string]interface{}
+	// Options are the options of the request
+	Options []RequestOption
+	// CallID is the unique id of the call (e.g. Execute) the request was issued by. The events of the call passed
+	// to the observer (see WithObserver) carry it. Coalesced requests (see CoalesceReads) share the events of the first one.
+	CallID string
+
+	// withBindings marks a request issued as parameterized query, even if the bindings are nil
+	withBindings bool
+}
+
+// IsAsync returns true in case the request was issued via ExecuteAsync.
+// In this case the responses are streamed to the ResponseChannel and are not returned by the ExecuteFunc.
+func (r *Request) IsAsync() bool {
+	return r.ResponseChannel != nil
+}
+
+// hasBindings returns true in case the request is a parameterized query
+func (r *Request) hasBindings() bool {
+	return r.withBindings || r.Bindings != nil || r.Rebindings != nil
+}
+
+// ExecuteFunc executes the given request
+type ExecuteFunc func(request *Request) ([]interfaces.Response, error)
+
+// Interceptor wraps the execution of requests, e.g. for auditing, caching or logging.
+// It gets the next ExecuteFunc of the chain and returns an ExecuteFunc that calls it (or not, e.g. in case of a cache hit).
+//
+//	audit := func(next gremcos.ExecuteFunc) gremcos.ExecuteFunc {
+//		return func(request *gremcos.Request) ([]interfaces.Response, error) {
+//			log.Printf("user %v executes %s", request.Metadata["user"], request.Query)
+//			return next(request)
+//		}
+//	}
+//	cosmos, err := gremcos.New(host, gremcos.WithInterceptors(audit))
+type Interceptor func(next ExecuteFunc) ExecuteFunc
+
+// WithInterceptors adds interceptors that are applied to all requests (Execute, ExecuteWithBindings, ExecuteQuery,
+// ExecuteQueryWithBindings and ExecuteAsync). The first given interceptor is the outermost one, it sees the request first.
+// The option can be used multiple times, the interceptors are appended.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *cosmosImpl) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// WithMetadata attaches the given value to the request. It is available to the interceptors via Request.Metadata.
+//
+//	cosmos.Execute("g.V()", gremcos.WithMetadata("tenant", "tenant-1"))
+func WithMetadata(key string, value interface{}) RequestOption {
+	return func(r *requestOptions) {
+		if r.metadata == nil {
+			r.metadata = make(map[string]interface{})
+		}
+		r.metadata[key] = value
+	}
+}
+
+// intercept passes the given request through the interceptors and finally executes it
+func (c *cosmosImpl) intercept(request *Request) ([]interfaces.Response, error) {
+	request.CallID = newCallID()
+	request.Metadata = newRequestOptions(request.Options...).metadata
+	if request.Metadata == nil {
+		request.Metadata = make(map[string]interface{})
+	}
+
+	execute := ExecuteFunc(c.executeRequest)
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		execute = c.interceptors[i](execute)
+	}
+	return execute(request)
+}
+
+// executeRequest executes the given request. It is the end of the interceptor chain.
+func (c *cosmosImpl) executeRequest(request *Request) ([]interfaces.Response, error) {
+	switch {
+	case request.IsAsync():
+		return nil, c.executeAsyncRequest(request.CallID, request.Query, request.ResponseChannel, request.Options...)
+	case request.hasBindings():
+		return c.executeWithBindings(request.CallID, request.Query, request.Bindings, request.Rebindings, request.Options...)
+	default:
+		return c.execute(request.CallID, request.Query, request.Options...)
+	}
+}
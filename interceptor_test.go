@@ -0,0 +1,144 @@
+package gremcos
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/supplyon/gremcos/api"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// recordingInterceptor records the requests it sees and the position in the chain
+func recordingInterceptor(name string, calls *[]string, requests *[]Request) Interceptor {
+	return func(next ExecuteFunc) ExecuteFunc {
+		return func(request *Request) ([]interfaces.Response, error) {
+			*calls = append(*calls, name)
+			*requests = append(*requests, *request)
+			return next(request)
+		}
+	}
+}
+
+func TestInterceptors_Order(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var calls []string
+	var requests []Request
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl, WithInterceptors(
+		recordingInterceptor("first", &calls, &requests),
+		recordingInterceptor("second", &calls, &requests),
+	))
+	success := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}
+	queryExecutor.EXPECT().Execute("g.V()").Return(success, nil)
+
+	// WHEN
+	responses, err := cosmos.Execute("g.V()", WithMetadata("tenant", "tenant-1"), WithMetadata("user", "alice"))
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, success, responses)
+	assert.Equal(t, []string{"first", "second"}, calls)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "g.V()", requests[0].Query)
+	assert.Nil(t, requests[0].QueryBuilder)
+	assert.False(t, requests[0].IsAsync())
+	assert.Equal(t, map[string]interface{}{"tenant": "tenant-1", "user": "alice"}, requests[0].Metadata)
+}
+
+func TestInterceptors_ExecuteQuery(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var calls []string
+	var requests []Request
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl, WithInterceptors(recordingInterceptor("audit", &calls, &requests)))
+	query := api.NewGraph("g").V().HasLabel("user")
+	queryExecutor.EXPECT().Execute(query.String()).Return(nil, nil)
+
+	// WHEN
+	_, err := cosmos.ExecuteQuery(query)
+
+	// THEN
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, query, requests[0].QueryBuilder)
+	assert.Equal(t, query.String(), requests[0].Query)
+	assert.Empty(t, requests[0].Metadata)
+}
+
+func TestInterceptors_ModifyRequestWithBindings(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	tenantFilter := func(next ExecuteFunc) ExecuteFunc {
+		return func(request *Request) ([]interfaces.Response, error) {
+			request.Query += ".has('tenant',tenant)"
+			request.Bindings["tenant"] = request.Metadata["tenant"]
+			return next(request)
+		}
+	}
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl, WithInterceptors(tenantFilter))
+	expectedBindings := map[string]interface{}{"name": "alice", "tenant": "tenant-1"}
+	queryExecutor.EXPECT().ExecuteWithBindings("g.V().has('name',name).has('tenant',tenant)", expectedBindings, map[string]interface{}{}).Return(nil, nil)
+
+	// WHEN
+	_, err := cosmos.ExecuteWithBindings("g.V().has('name',name)", map[string]interface{}{"name": "alice"}, map[string]interface{}{}, WithMetadata("tenant", "tenant-1"))
+
+	// THEN
+	assert.NoError(t, err)
+}
+
+func TestInterceptors_ShortCircuit(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cached := []interfaces.Response{{RequestID: "cached"}}
+	cache := func(next ExecuteFunc) ExecuteFunc {
+		return func(request *Request) ([]interfaces.Response, error) {
+			return cached, nil
+		}
+	}
+	// no call of the query executor is expected
+	cosmos, _ := newMockedCosmos(t, mockCtrl, WithInterceptors(cache))
+
+	// WHEN
+	responses, err := cosmos.Execute("g.V()")
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, cached, responses)
+}
+
+func TestInterceptors_ExecuteAsync(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var calls []string
+	var requests []Request
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl, WithInterceptors(recordingInterceptor("audit", &calls, &requests)))
+	success := interfaces.Response{Status: interfaces.Status{Code: interfaces.StatusSuccess}}
+	queryExecutor.EXPECT().ExecuteAsync("g.V()", gomock.Any()).DoAndReturn(func(q string, resp chan interfaces.AsyncResponse) error {
+		resp <- interfaces.AsyncResponse{Response: success}
+		close(resp)
+		return nil
+	})
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	err := cosmos.ExecuteAsync("g.V()", responseChannel)
+
+	responses := make([]interfaces.Response, 0, 1)
+	for resp := range responseChannel {
+		responses = append(responses, resp.Response)
+	}
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, []interfaces.Response{success}, responses)
+	require.Len(t, requests, 1)
+	assert.True(t, requests[0].IsAsync())
+}
@@ -56,6 +56,7 @@ type Result struct {
 type AsyncResponse struct {
 	Response     Response `json:"response"`     //Partial Response object
 	ErrorMessage string   `json:"errorMessage"` // Error message if there was an error
+	Err          error    `json:"-"`            // Typed error if there was an error (set by the cosmos layer), nil otherwise
 }
 
 // String returns a string representation of the Response struct
@@ -21,6 +21,28 @@ type QueryBuilder interface {
 	String() string
 }
 
+// QueryBuilderWithBindings is a QueryBuilder that is able to generate parameterized queries.
+// Instead of inlining the values, ids, labels of added elements and property keys into the query, they are referenced
+// by generated binding names. This enables the server to cache the compiled script and prevents injections.
+// The values are bound escaped like they are inlined by String (see api.Escape), the ids, labels and keys as given.
+type QueryBuilderWithBindings interface {
+	QueryBuilder
+
+	// StringWithBindings returns the query where the values are replaced by generated binding names (e.g. _b0)
+	// together with the according bindings.
+	StringWithBindings() (query string, bindings map[string]interface{})
+}
+
+// PartitionFilterValidator is a QueryBuilder that is able to check whether the generated traversal is scoped to
+// a partition of a partitioned graph.
+type PartitionFilterValidator interface {
+	QueryBuilder
+
+	// ValidatePartitionFilter returns an error in case the traversal is not scoped to a partition
+	// and hence fans out across all partitions.
+	ValidatePartitionFilter() error
+}
+
 // Graph represents a QueryBuilder that can be used to create
 // queries on graph level
 type Graph interface {
@@ -34,8 +56,17 @@ type Graph interface {
 	VByUUID(id uuid.UUID) Vertex
 	// VByStr adds .V(<id>), e.g. .V("123a"), to the query.  The query call returns the vertex with the given id.
 	VByStr(id string) Vertex
+	// VByPartitionedID adds .V([<partition key>,<id>]), e.g. .V(["pk1","123a"]), to the query. The query call returns the vertex with the given id within the given partition.
+	VByPartitionedID(partitionKey, id string) Vertex
 	// AddV adds .addV('<label>'), e.g. .addV('user'), to the query. The query call adds a vertex with the given label and returns that vertex.
 	AddV(label string) Vertex
+	// AddVWithPartition adds .addV("<label>").property("<partition key name>","<partition key>"), e.g. .addV("user").property("pk","pk1"), to the query.
+	// The query call adds a vertex with the given label to the given partition and returns that vertex.
+	// An error is returned in case the partition key of the graph is not configured.
+	AddVWithPartition(label, partitionKey string) (Vertex, error)
+	// AddEWithPartition adds .V([<partition key>,<id>]).addE("<label>").to(<graph>.V([<partition key>,<id>])), to the query.
+	// The query call adds an edge with the given label between the given vertices and returns that edge.
+	AddEWithPartition(label, fromPartitionKey, fromID, toPartitionKey, toID string) Edge
 	// E adds .E() to the query. The query call returns all edges.
 	E() Edge
 }
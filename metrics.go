@@ -1,10 +1,7 @@
 package gremcos
 
 import (
-	"sync"
-
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	m "github.com/supplyon/gremcos/metrics"
 )
 
@@ -22,120 +19,124 @@ type Metrics struct {
 	requestErrorsTotal               m.Counter
 	requestRetiesTotal               m.Counter
 	requestRetryTimeoutsTotal        m.Counter
+	connectionEvictionsTotal         m.Counter
+	ruLimiterRate                    m.Gauge
+	ruLimiterTokens                  m.Gauge
+	ruLimiterWaitMS                  m.Histogram
+	requestHedgesIssuedTotal         m.Counter
+	requestHedgesWonTotal            m.Counter
+	requestLatencyMS                 m.HistogramVec
+	requestChargePerOperation        m.HistogramVec
+	serverTimePerOperationMS         m.HistogramVec
+	queryCacheHitsTotal              m.Counter
+	queryCacheMissesTotal            m.Counter
+	requestCoalescedTotal            m.Counter
 }
 
-var metricsOnce sync.Once
-
-// Singleton to avoid initializing the metrics multiple times, which would cause a panic
-var instance *Metrics
-
-// NewMetrics returns the metrics collection
+// NewMetrics returns the metrics collection registered at the default prometheus registerer.
+// It panics in case the metrics can't be registered, use NewMetricsWith to get the error instead.
 func NewMetrics(namespace string) *Metrics {
-	metricsOnce.Do(func() {
-		statusCode := []string{"code"}
-		statusCodeTotal := m.NewWrappedCounterVec(prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "statuscode_total",
-			Help:      "Counts the number of responses from cosmos separated by status code.",
-		}, statusCode)
-
-		retryAfterMS := promauto.NewHistogram(prometheus.HistogramOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "retry_after_ms",
-			Help:      "The time in milliseconds suggested by cosmos to wait before issuing the next query.",
-			Buckets:   []float64{0, 50, 100, 250, 500, 1000, 2000, 3000, 5000, 7000, 10000},
-		})
-
-		requestChargePerQuery := promauto.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "request_charge_per_query",
-			Help:      "Cosmos DB reports a request charge accumulated for all responses of one query. This metric represents that value.",
-		})
-
-		requestChargePerQueryResponseAvg := promauto.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "request_charge_per_queryresponse_avg",
-			Help:      "Cosmos DB reports a request charge each of the responses of one query. This metric represents the average of these values for one query.",
-		})
-
-		requestChargeTotal := promauto.NewCounter(prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "request_charge_total",
-			Help:      "The accumulated request charge over all queries issued so far.",
-		})
-
-		serverTimePerQueryMS := promauto.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "server_time_per_query_ms",
-			Help:      "The time spent in ms for one query.",
-		})
-
-		serverTimePerQueryResponseAvgMS := promauto.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "server_time_per_queryresponse_avg_ms",
-			Help:      "The average time spent in ms for one query per response.",
-		})
-
-		connectivityErrorsTotal := promauto.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "connectivity_errors_total",
-			Help:      "The amount of errors happened when creating a new connection.",
-		})
-
-		labels := []string{"kind", "error"}
-		connectionUsageTotal := m.NewWrappedCounterVec(prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "connection_usage_total",
-			Help:      "The amount of reads, writes and pings that where made (the label is called kind). Errors that happened are labelled as error=true.",
-		}, labels)
+	metrics, err := NewMetricsWith(prometheus.DefaultRegisterer, namespace, nil)
+	if err != nil {
+		panic(err)
+	}
+	return metrics
+}
 
-		requestErrorsTotal := promauto.NewCounter(prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "request_errors_total",
-			Help:      "The accumulated number of request errors.",
-		})
+// NewMetricsWith returns the metrics collection registered at the given registerer.
+// The given constant labels (e.g. the cosmos account or graph) are added to all metrics,
+// which allows to use several independent metrics collections within one process.
+// Metrics that are already registered with the same name and labels are reused. An error is returned in case
+// a metric can't be registered (e.g. a metric with the same name but different labels is already registered).
+//
+//	registry := prometheus.NewRegistry()
+//	metrics, err := gremcos.NewMetricsWith(registry, "myservice", prometheus.Labels{"account": "my-cosmos-account"})
+func NewMetricsWith(registerer prometheus.Registerer, namespace string, constLabels prometheus.Labels) (*Metrics, error) {
+	return NewMetricsFromFactory(m.NewPrometheusFactory(registerer, namespace, "cosmos", constLabels))
+}
 
-		requestRetiesTotal := promauto.NewCounter(prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "request_retries_total",
-			Help:      "The accumulated number of retried requests.",
-		})
+// NewMetricsFromFactory returns the metrics collection created by the given factory.
+// This allows to report the metrics to other backends than prometheus (see metrics.NewExpvarFactory and metrics.NewStatsDFactory).
+// In case the factory reports an error after the metrics were created (see metrics.PrometheusFactory.Err), it is returned.
+func NewMetricsFromFactory(f m.Factory) (*Metrics, error) {
+	metrics := newMetricsFromFactory(f)
+	if failing, ok := f.(interface{ Err() error }); ok && failing.Err() != nil {
+		return nil, failing.Err()
+	}
+	return metrics, nil
+}
 
-		requestRetryTimeoutsTotal := promauto.NewCounter(prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: "cosmos",
-			Name:      "request_retry_timeouts_total",
-			Help:      "The accumulated number of timeouts that happened for request retries.",
-		})
+// newMetricsFromFactory creates the metrics of the collection using the given factory
+func newMetricsFromFactory(f m.Factory) *Metrics {
+	operationLabels := []string{"operation", "outcome"}
+	return &Metrics{
+		statusCodeTotal: f.CounterVec("statuscode_total",
+			"Counts the number of responses from cosmos separated by status code.",
+			[]string{"code"}),
+		retryAfterMS: f.Histogram("retry_after_ms",
+			"The time in milliseconds suggested by cosmos to wait before issuing the next query.",
+			[]float64{0, 50, 100, 250, 500, 1000, 2000, 3000, 5000, 7000, 10000}),
+		requestChargeTotal: f.Counter("request_charge_total",
+			"The accumulated request charge over all queries issued so far."),
+		requestChargePerQuery: f.Gauge("request_charge_per_query",
+			"Cosmos DB reports a request charge accumulated for all responses of one query. This metric represents that value."),
+		requestChargePerQueryResponseAvg: f.Gauge("request_charge_per_queryresponse_avg",
+			"Cosmos DB reports a request charge each of the responses of one query. This metric represents the average of these values for one query."),
+		serverTimePerQueryMS: f.Gauge("server_time_per_query_ms",
+			"The time spent in ms for one query."),
+		serverTimePerQueryResponseAvgMS: f.Gauge("server_time_per_queryresponse_avg_ms",
+			"The average time spent in ms for one query per response."),
+		connectivityErrorsTotal: gaugeCounter{f.Gauge("connectivity_errors_total",
+			"The amount of errors happened when creating a new connection.")},
+		connectionUsageTotal: f.CounterVec("connection_usage_total",
+			"The amount of reads, writes and pings that where made (the label is called kind). Errors that happened are labelled as error=true.",
+			[]string{"kind", "error"}),
+		requestErrorsTotal: f.Counter("request_errors_total",
+			"The accumulated number of request errors."),
+		requestRetiesTotal: f.Counter("request_retries_total",
+			"The accumulated number of retried requests."),
+		requestRetryTimeoutsTotal: f.Counter("request_retry_timeouts_total",
+			"The accumulated number of timeouts that happened for request retries."),
+		connectionEvictionsTotal: f.Counter("connection_evictions_total",
+			"The accumulated number of connections that were removed from the pool since cosmos suggested to retry on a new connection (status codes 1007, 1008)."),
+		ruLimiterRate: f.Gauge("ru_limiter_rate",
+			"The request units per second currently allowed by the client-side rate limiter. It is reduced when cosmos throttles requests."),
+		ruLimiterTokens: f.Gauge("ru_limiter_tokens",
+			"The request units currently available in the client-side rate limiter. A negative value represents request units already reserved for waiting requests."),
+		ruLimiterWaitMS: f.Histogram("ru_limiter_wait_ms",
+			"The time in milliseconds requests had to wait for the client-side rate limiter.",
+			[]float64{0, 50, 100, 250, 500, 1000, 2000, 3000, 5000, 7000, 10000}),
+		requestHedgesIssuedTotal: f.Counter("request_hedges_issued_total",
+			"The accumulated number of hedged read requests that were sent since the original request was slow."),
+		requestHedgesWonTotal: f.Counter("request_hedges_won_total",
+			"The accumulated number of hedged read requests that completed before the original request."),
+		requestLatencyMS: f.HistogramVec("request_latency_ms",
+			"The end-to-end latency in milliseconds of a request including all retries, labelled by operation and outcome (success, error).",
+			[]float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}, operationLabels),
+		requestChargePerOperation: f.HistogramVec("request_charge_per_operation",
+			"The request charge of a request including all retries, labelled by operation and outcome (success, error).",
+			[]float64{1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}, operationLabels),
+		serverTimePerOperationMS: f.HistogramVec("server_time_per_operation_ms",
+			"The time in milliseconds spent on the server for a request including all retries, labelled by operation and outcome (success, error).",
+			[]float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}, operationLabels),
+		queryCacheHitsTotal: f.Counter("query_cache_hits_total",
+			"The accumulated number of read requests that were answered by the query cache."),
+		queryCacheMissesTotal: f.Counter("query_cache_misses_total",
+			"The accumulated number of read requests that were not found in the query cache and sent to cosmos."),
+		requestCoalescedTotal: f.Counter("request_coalesced_total",
+			"The accumulated number of read requests that shared the execution of an identical concurrent request."),
+	}
+}
 
-		instance = &Metrics{
-			statusCodeTotal:                  statusCodeTotal,
-			retryAfterMS:                     retryAfterMS,
-			requestChargeTotal:               requestChargeTotal,
-			requestChargePerQuery:            requestChargePerQuery,
-			requestChargePerQueryResponseAvg: requestChargePerQueryResponseAvg,
-			serverTimePerQueryMS:             serverTimePerQueryMS,
-			serverTimePerQueryResponseAvgMS:  serverTimePerQueryResponseAvgMS,
-			connectivityErrorsTotal:          connectivityErrorsTotal,
-			connectionUsageTotal:             connectionUsageTotal,
-			requestErrorsTotal:               requestErrorsTotal,
-			requestRetiesTotal:               requestRetiesTotal,
-			requestRetryTimeoutsTotal:        requestRetryTimeoutsTotal,
-		}
-	})
+// gaugeCounter is a gauge that is used as counter.
+// It keeps the type of metrics that were created as gauge, although they are only incremented.
+type gaugeCounter struct {
+	m.Gauge
+}
 
-	return instance
+// Inc increments the gauge by 1
+func (g gaugeCounter) Inc() {
+	g.Add(1)
 }
 
 func newStubbedMetrics() *Metrics {
@@ -151,6 +152,18 @@ func newStubbedMetrics() *Metrics {
 	requestErrorsTotal := m.NewStubCounter()
 	requestRetiesTotal := m.NewStubCounter()
 	requestRetryTimeoutsTotal := m.NewStubCounter()
+	connectionEvictionsTotal := m.NewStubCounter()
+	ruLimiterRate := m.NewStubGauge()
+	ruLimiterTokens := m.NewStubGauge()
+	ruLimiterWaitMS := m.NewStubHistogram()
+	requestHedgesIssuedTotal := m.NewStubCounter()
+	requestHedgesWonTotal := m.NewStubCounter()
+	requestLatencyMS := m.NewHistogramVec()
+	requestChargePerOperation := m.NewHistogramVec()
+	serverTimePerOperationMS := m.NewHistogramVec()
+	queryCacheHitsTotal := m.NewStubCounter()
+	queryCacheMissesTotal := m.NewStubCounter()
+	requestCoalescedTotal := m.NewStubCounter()
 
 	metrics := &Metrics{
 		statusCodeTotal:                  statusCodeTotal,
@@ -165,6 +178,18 @@ func newStubbedMetrics() *Metrics {
 		requestErrorsTotal:               requestErrorsTotal,
 		requestRetiesTotal:               requestRetiesTotal,
 		requestRetryTimeoutsTotal:        requestRetryTimeoutsTotal,
+		connectionEvictionsTotal:         connectionEvictionsTotal,
+		ruLimiterRate:                    ruLimiterRate,
+		ruLimiterTokens:                  ruLimiterTokens,
+		ruLimiterWaitMS:                  ruLimiterWaitMS,
+		requestHedgesIssuedTotal:         requestHedgesIssuedTotal,
+		requestHedgesWonTotal:            requestHedgesWonTotal,
+		requestLatencyMS:                 requestLatencyMS,
+		requestChargePerOperation:        requestChargePerOperation,
+		serverTimePerOperationMS:         serverTimePerOperationMS,
+		queryCacheHitsTotal:              queryCacheHitsTotal,
+		queryCacheMissesTotal:            queryCacheMissesTotal,
+		requestCoalescedTotal:            requestCoalescedTotal,
 	}
 
 	return metrics
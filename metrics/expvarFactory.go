@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ExpvarFactory creates metrics that are published as expvar variables (see /debug/vars).
+// The variables are named <namespace>_<subsystem>_<name>.
+type ExpvarFactory struct {
+	namespace string
+	subsystem string
+}
+
+// NewExpvarFactory creates a factory for metrics that are published via expvar.
+// Variables that are already published with the same name are reused, which allows to create the factory several times.
+func NewExpvarFactory(namespace, subsystem string) *ExpvarFactory {
+	return &ExpvarFactory{namespace: namespace, subsystem: subsystem}
+}
+
+// name returns the fully qualified name of the variable
+func (f *ExpvarFactory) name(name string) string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{f.namespace, f.subsystem, name} {
+		if len(part) > 0 {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// Counter creates a counter published as expvar.Float
+func (f *ExpvarFactory) Counter(name, _ string) Counter {
+	return &expvarCounter{value: publishExpvar(f.name(name), func() expvar.Var { return new(expvar.Float) }).(*expvar.Float)}
+}
+
+// Gauge creates a gauge published as expvar.Float
+func (f *ExpvarFactory) Gauge(name, _ string) Gauge {
+	return publishExpvar(f.name(name), func() expvar.Var { return new(expvar.Float) }).(*expvar.Float)
+}
+
+// Histogram creates a histogram that publishes the count, sum and the (cumulative) number of observations per bucket
+func (f *ExpvarFactory) Histogram(name, _ string, buckets []float64) Histogram {
+	return publishExpvar(f.name(name), func() expvar.Var { return newExpvarHistogram(buckets) }).(*expvarHistogram)
+}
+
+// CounterVec creates a vector of counters published as expvar.Map. The keys are the label values separated by comma.
+func (f *ExpvarFactory) CounterVec(name, _ string, _ []string) CounterVec {
+	return &expvarCounterVec{values: publishExpvar(f.name(name), func() expvar.Var { return new(expvar.Map) }).(*expvar.Map)}
+}
+
+// HistogramVec creates a vector of histograms published as expvar.Map. The keys are the label values separated by comma.
+func (f *ExpvarFactory) HistogramVec(name, _ string, buckets []float64, _ []string) HistogramVec {
+	return &expvarHistogramVec{
+		buckets:    buckets,
+		histograms: publishExpvar(f.name(name), func() expvar.Var { return new(expvar.Map) }).(*expvar.Map),
+	}
+}
+
+// publishMux ensures that a variable is only published once
+var publishMux sync.Mutex
+
+// publishExpvar returns the variable published with the given name. In case there is none, the variable created by newVar is published.
+// It panics in case a variable of a different type is already published with this name.
+func publishExpvar(name string, newVar func() expvar.Var) expvar.Var {
+	publishMux.Lock()
+	defer publishMux.Unlock()
+
+	v := newVar()
+	existing := expvar.Get(name)
+	if existing == nil {
+		expvar.Publish(name, v)
+		return v
+	}
+
+	if fmt.Sprintf("%T", existing) != fmt.Sprintf("%T", v) {
+		panic(fmt.Sprintf("expvar %s is already published with type %T", name, existing))
+	}
+	return existing
+}
+
+// expvarCounter wraps an expvar.Float as Counter
+type expvarCounter struct {
+	value *expvar.Float
+}
+
+// Inc increments the counter by 1
+func (c *expvarCounter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds the given value to the counter
+func (c *expvarCounter) Add(value float64) {
+	c.value.Add(value)
+}
+
+// expvarCounterVec wraps an expvar.Map as CounterVec
+type expvarCounterVec struct {
+	values *expvar.Map
+}
+
+// WithLabelValues returns the counter for the given label values
+func (c *expvarCounterVec) WithLabelValues(lvs ...string) Counter {
+	key := strings.Join(lvs, ",")
+	if value, ok := c.values.Get(key).(*expvar.Float); ok {
+		return &expvarCounter{value: value}
+	}
+
+	publishMux.Lock()
+	defer publishMux.Unlock()
+	if value, ok := c.values.Get(key).(*expvar.Float); ok {
+		return &expvarCounter{value: value}
+	}
+	value := new(expvar.Float)
+	c.values.Set(key, value)
+	return &expvarCounter{value: value}
+}
+
+// expvarHistogram is a histogram that implements expvar.Var
+type expvarHistogram struct {
+	mux     sync.Mutex
+	buckets []float64
+	// counts contains the number of observations per bucket (not cumulative), the last one counts the observations above all buckets
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newExpvarHistogram(buckets []float64) *expvarHistogram {
+	return &expvarHistogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+// Observe adds the given value to the histogram
+func (h *expvarHistogram) Observe(value float64) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	index := len(h.buckets)
+	for i, upperBound := range h.buckets {
+		if value <= upperBound {
+			index = i
+			break
+		}
+	}
+	h.counts[index]++
+	h.count++
+	h.sum += value
+}
+
+// String returns the histogram as JSON, the buckets are cumulative like the ones of prometheus
+func (h *expvarHistogram) String() string {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	buckets := make(map[string]uint64, len(h.counts))
+	var cumulative uint64
+	for i, count := range h.counts {
+		cumulative += count
+		upperBound := math.Inf(1)
+		if i < len(h.buckets) {
+			upperBound = h.buckets[i]
+		}
+		buckets[strconv.FormatFloat(upperBound, 'g', -1, 64)] = cumulative
+	}
+
+	data, _ := json.Marshal(struct {
+		Count   uint64            `json:"count"`
+		Sum     float64           `json:"sum"`
+		Buckets map[string]uint64 `json:"buckets"`
+	}{Count: h.count, Sum: h.sum, Buckets: buckets})
+	return string(data)
+}
+
+// expvarHistogramVec wraps an expvar.Map of histograms as HistogramVec
+type expvarHistogramVec struct {
+	buckets    []float64
+	histograms *expvar.Map
+}
+
+// WithLabelValues returns the histogram for the given label values
+func (h *expvarHistogramVec) WithLabelValues(lvs ...string) Histogram {
+	key := strings.Join(lvs, ",")
+	if histogram, ok := h.histograms.Get(key).(*expvarHistogram); ok {
+		return histogram
+	}
+
+	publishMux.Lock()
+	defer publishMux.Unlock()
+	if histogram, ok := h.histograms.Get(key).(*expvarHistogram); ok {
+		return histogram
+	}
+	histogram := newExpvarHistogram(h.buckets)
+	h.histograms.Set(key, histogram)
+	return histogram
+}
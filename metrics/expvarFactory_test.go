@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namespaceCounter makes the namespaces unique, since the expvar variables are process-global and outlive a test run (e.g. -count=2)
+var namespaceCounter int64
+
+// uniqueNamespace returns a namespace with the given prefix that was not used before in this process
+func uniqueNamespace(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, atomic.AddInt64(&namespaceCounter, 1))
+}
+
+func TestExpvarFactory_CounterAndGauge(t *testing.T) {
+	// GIVEN
+	namespace := uniqueNamespace("expvartest")
+	factory := NewExpvarFactory(namespace, "cosmos")
+
+	// WHEN
+	counter := factory.Counter("requests_total", "help")
+	counter.Inc()
+	counter.Add(2)
+	gauge := factory.Gauge("charge", "help")
+	gauge.Set(5)
+	gauge.Add(-1.5)
+
+	// THEN
+	assert.Equal(t, "3", expvar.Get(namespace+"_cosmos_requests_total").String())
+	assert.Equal(t, "3.5", expvar.Get(namespace+"_cosmos_charge").String())
+}
+
+func TestExpvarFactory_ReusesPublishedVariables(t *testing.T) {
+	// GIVEN
+	namespace := uniqueNamespace("expvarreuse")
+	counter1 := NewExpvarFactory(namespace, "").Counter("errors_total", "help")
+	counter2 := NewExpvarFactory(namespace, "").Counter("errors_total", "help")
+
+	// WHEN
+	counter1.Inc()
+	counter2.Inc()
+
+	// THEN
+	assert.Equal(t, "2", expvar.Get(namespace+"_errors_total").String())
+	assert.Panics(t, func() { NewExpvarFactory(namespace, "").Histogram("errors_total", "help", nil) })
+}
+
+func TestExpvarFactory_CounterVec(t *testing.T) {
+	// GIVEN
+	namespace := uniqueNamespace("expvarvec")
+	factory := NewExpvarFactory(namespace, "cosmos")
+	counterVec := factory.CounterVec("statuscode_total", "help", []string{"code"})
+
+	// WHEN
+	counterVec.WithLabelValues("200").Inc()
+	counterVec.WithLabelValues("200").Inc()
+	counterVec.WithLabelValues("429").Add(3)
+
+	// THEN
+	values := map[string]float64{}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get(namespace+"_cosmos_statuscode_total").String()), &values))
+	assert.Equal(t, map[string]float64{"200": 2, "429": 3}, values)
+}
+
+func TestExpvarFactory_Histogram(t *testing.T) {
+	// GIVEN
+	namespace := uniqueNamespace("expvarhistogram")
+	factory := NewExpvarFactory(namespace, "cosmos")
+	histogram := factory.Histogram("latency_ms", "help", []float64{10, 100})
+	histogramVec := factory.HistogramVec("latency_per_operation_ms", "help", []float64{10}, []string{"operation", "outcome"})
+
+	// WHEN
+	histogram.Observe(5)
+	histogram.Observe(50)
+	histogram.Observe(500)
+	histogramVec.WithLabelValues("get-user", "success").Observe(1)
+
+	// THEN
+	type histogramData struct {
+		Count   uint64            `json:"count"`
+		Sum     float64           `json:"sum"`
+		Buckets map[string]uint64 `json:"buckets"`
+	}
+	var data histogramData
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get(namespace+"_cosmos_latency_ms").String()), &data))
+	assert.Equal(t, histogramData{Count: 3, Sum: 555, Buckets: map[string]uint64{"10": 1, "100": 2, "+Inf": 3}}, data)
+
+	vecData := map[string]histogramData{}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get(namespace+"_cosmos_latency_per_operation_ms").String()), &vecData))
+	assert.Equal(t, map[string]histogramData{"get-user,success": {Count: 1, Sum: 1, Buckets: map[string]uint64{"10": 1, "+Inf": 1}}}, vecData)
+}
@@ -26,3 +26,19 @@ type CounterVec interface {
 type Histogram interface {
 	Observe(float64)
 }
+
+// HistogramVec represents a vector of labelled histograms
+type HistogramVec interface {
+	WithLabelValues(lvs ...string) Histogram
+}
+
+// Factory creates the metrics of a metrics collection for a specific metrics backend (e.g. prometheus, expvar or statsd).
+// The names are given without namespace, it is up to the factory to add it.
+// The buckets of histograms are a hint, they are ignored by backends that don't support them.
+type Factory interface {
+	Counter(name, help string) Counter
+	Gauge(name, help string) Gauge
+	Histogram(name, help string, buckets []float64) Histogram
+	CounterVec(name, help string, labelNames []string) CounterVec
+	HistogramVec(name, help string, buckets []float64, labelNames []string) HistogramVec
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusFactory creates prometheus metrics and registers them at a registerer
+type PrometheusFactory struct {
+	registerer  prometheus.Registerer
+	namespace   string
+	subsystem   string
+	constLabels prometheus.Labels
+	// err is the first error that occurred while registering a metric
+	err error
+}
+
+// NewPrometheusFactory creates a factory for prometheus metrics that are registered at the given registerer.
+// The given constant labels are added to all metrics. Metrics that are already registered with the same name and labels are reused.
+func NewPrometheusFactory(registerer prometheus.Registerer, namespace, subsystem string, constLabels prometheus.Labels) *PrometheusFactory {
+	return &PrometheusFactory{registerer: registerer, namespace: namespace, subsystem: subsystem, constLabels: constLabels}
+}
+
+// Err returns the first error that occurred while registering the created metrics (e.g. a metric with the same name
+// but different labels or help is already registered), nil otherwise.
+func (f *PrometheusFactory) Err() error {
+	return f.err
+}
+
+// register registers the given collector. In case an equal collector is already registered, the existing one is returned.
+// In case the collector can't be registered, the error is kept (see Err) and the unregistered collector is returned.
+func (f *PrometheusFactory) register(collector prometheus.Collector) prometheus.Collector {
+	err := f.registerer.Register(collector)
+	if err == nil {
+		return collector
+	}
+
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		return alreadyRegistered.ExistingCollector
+	}
+	if f.err == nil {
+		f.err = fmt.Errorf("registering prometheus metric: %w", err)
+	}
+	return collector
+}
+
+// Counter creates and registers a prometheus counter
+func (f *PrometheusFactory) Counter(name, help string) Counter {
+	return f.register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   f.namespace,
+		Subsystem:   f.subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.constLabels,
+	})).(prometheus.Counter)
+}
+
+// Gauge creates and registers a prometheus gauge
+func (f *PrometheusFactory) Gauge(name, help string) Gauge {
+	return f.register(prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   f.namespace,
+		Subsystem:   f.subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.constLabels,
+	})).(prometheus.Gauge)
+}
+
+// Histogram creates and registers a prometheus histogram
+func (f *PrometheusFactory) Histogram(name, help string, buckets []float64) Histogram {
+	return f.register(prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   f.namespace,
+		Subsystem:   f.subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.constLabels,
+		Buckets:     buckets,
+	})).(prometheus.Histogram)
+}
+
+// CounterVec creates and registers a prometheus CounterVec
+func (f *PrometheusFactory) CounterVec(name, help string, labelNames []string) CounterVec {
+	return WrapCounterVec(f.register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   f.namespace,
+		Subsystem:   f.subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.constLabels,
+	}, labelNames)).(*prometheus.CounterVec))
+}
+
+// HistogramVec creates and registers a prometheus HistogramVec
+func (f *PrometheusFactory) HistogramVec(name, help string, buckets []float64, labelNames []string) HistogramVec {
+	return WrapHistogramVec(f.register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   f.namespace,
+		Subsystem:   f.subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: f.constLabels,
+		Buckets:     buckets,
+	}, labelNames)).(*prometheus.HistogramVec))
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusFactory(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+	factory := NewPrometheusFactory(registry, "gremcos", "cosmos", prometheus.Labels{"graph": "users"})
+
+	// WHEN
+	factory.Counter("request_errors_total", "help").Inc()
+	factory.Gauge("request_charge_per_query", "help").Set(1)
+	factory.Histogram("retry_after_ms", "help", []float64{10}).Observe(1)
+	factory.CounterVec("statuscode_total", "help", []string{"code"}).WithLabelValues("200").Inc()
+	factory.HistogramVec("request_latency_ms", "help", []float64{10}, []string{"operation"}).WithLabelValues("get-user").Observe(1)
+
+	// THEN
+	assert.NoError(t, factory.Err())
+	count, err := testutil.GatherAndCount(registry)
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
+
+func TestPrometheusFactory_ReusesRegisteredMetrics(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+	counter1 := NewPrometheusFactory(registry, "gremcos", "cosmos", nil).Counter("request_errors_total", "help")
+	counter2 := NewPrometheusFactory(registry, "gremcos", "cosmos", nil).Counter("request_errors_total", "help")
+
+	// WHEN
+	counter1.Inc()
+	counter2.Inc()
+
+	// THEN
+	assert.Equal(t, float64(2), testutil.ToFloat64(counter1.(prometheus.Counter)))
+}
+
+func TestPrometheusFactory_RegistrationError(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+	factory := NewPrometheusFactory(registry, "gremcos", "cosmos", nil)
+	factory.Gauge("request_errors_total", "help")
+
+	// WHEN
+	counter := factory.CounterVec("request_errors_total", "help", []string{"code"})
+	counter.WithLabelValues("200").Inc()
+
+	// THEN
+	require.Error(t, factory.Err())
+	assert.NotNil(t, counter)
+	count, err := testutil.GatherAndCount(registry)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
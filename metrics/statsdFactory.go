@@ -0,0 +1,230 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StatsDFactory creates metrics that are sent to a StatsD server via UDP.
+// Each update of a metric is sent immediately as one datagram. Errors while sending are ignored, like it is common for StatsD.
+// The metrics are named <namespace>.<subsystem>.<name>.
+type StatsDFactory struct {
+	conn      net.Conn
+	prefix    string
+	dogStatsD bool
+	// tags are the constant tags in DogStatsD format (key:value) added to all metrics
+	tags []string
+}
+
+// StatsDOption is the struct for defining optional parameters for the StatsDFactory
+type StatsDOption func(*StatsDFactory)
+
+// DogStatsDTags enables the DogStatsD extensions: labels are sent as tags (instead of being appended to the metric name)
+// and histograms are sent as histograms (instead of timers). The given constant tags are added to all metrics.
+func DogStatsDTags(constTags map[string]string) StatsDOption {
+	return func(f *StatsDFactory) {
+		f.dogStatsD = true
+		f.tags = make([]string, 0, len(constTags))
+		for key, value := range constTags {
+			f.tags = append(f.tags, key+":"+value)
+		}
+		sort.Strings(f.tags)
+	}
+}
+
+// NewStatsDFactory creates a factory for metrics that are sent to the StatsD server at the given address (host:port) via UDP.
+// Per default the label values are appended to the metric name (e.g. gremcos.cosmos.statuscode_total.200)
+// and histograms are sent as timers, since plain StatsD supports neither tags nor histograms (see DogStatsDTags).
+func NewStatsDFactory(address, namespace, subsystem string, options ...StatsDOption) (*StatsDFactory, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to statsd at %s: %v", address, err)
+	}
+
+	parts := make([]string, 0, 2)
+	for _, part := range []string{namespace, subsystem} {
+		if len(part) > 0 {
+			parts = append(parts, part)
+		}
+	}
+
+	f := &StatsDFactory{conn: conn, prefix: strings.Join(parts, ".")}
+	for _, opt := range options {
+		opt(f)
+	}
+	return f, nil
+}
+
+// Close closes the connection to the StatsD server
+func (f *StatsDFactory) Close() error {
+	return f.conn.Close()
+}
+
+// Counter creates a counter (type c)
+func (f *StatsDFactory) Counter(name, _ string) Counter {
+	return &statsDCounter{statsDMetric{factory: f, name: f.name(name)}}
+}
+
+// Gauge creates a gauge (type g)
+func (f *StatsDFactory) Gauge(name, _ string) Gauge {
+	return &statsDGauge{statsDMetric{factory: f, name: f.name(name)}}
+}
+
+// Histogram creates a histogram (type h for DogStatsD, ms otherwise)
+func (f *StatsDFactory) Histogram(name, _ string, _ []float64) Histogram {
+	return &statsDHistogram{statsDMetric{factory: f, name: f.name(name)}}
+}
+
+// CounterVec creates a vector of counters
+func (f *StatsDFactory) CounterVec(name, _ string, labelNames []string) CounterVec {
+	return &statsDCounterVec{statsDVec{factory: f, name: f.name(name), labelNames: labelNames}}
+}
+
+// HistogramVec creates a vector of histograms
+func (f *StatsDFactory) HistogramVec(name, _ string, _ []float64, labelNames []string) HistogramVec {
+	return &statsDHistogramVec{statsDVec{factory: f, name: f.name(name), labelNames: labelNames}}
+}
+
+// name returns the fully qualified name of the metric
+func (f *StatsDFactory) name(name string) string {
+	if len(f.prefix) == 0 {
+		return name
+	}
+	return f.prefix + "." + name
+}
+
+// send sends one metric update to the StatsD server
+func (f *StatsDFactory) send(name, value, metricType string, tags []string) {
+	var line strings.Builder
+	line.WriteString(name)
+	line.WriteString(":")
+	line.WriteString(value)
+	line.WriteString("|")
+	line.WriteString(metricType)
+
+	if f.dogStatsD && len(f.tags)+len(tags) > 0 {
+		line.WriteString("|#")
+		line.WriteString(strings.Join(append(append([]string{}, f.tags...), tags...), ","))
+	}
+
+	// errors are ignored, the metrics are sent fire and forget
+	_, _ = f.conn.Write([]byte(line.String()))
+}
+
+// histogramType returns the type used to send histograms
+func (f *StatsDFactory) histogramType() string {
+	if f.dogStatsD {
+		return "h"
+	}
+	return "ms"
+}
+
+func formatStatsDValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// statsDMetric contains the name and tags of a metric that is sent to StatsD
+type statsDMetric struct {
+	factory *StatsDFactory
+	name    string
+	tags    []string
+}
+
+// send sends the given value of the metric
+func (m statsDMetric) send(value, metricType string) {
+	m.factory.send(m.name, value, metricType, m.tags)
+}
+
+// statsDCounter is a counter that is sent to StatsD
+type statsDCounter struct {
+	statsDMetric
+}
+
+// Inc increments the counter by 1
+func (c *statsDCounter) Inc() {
+	c.send("1", "c")
+}
+
+// Add adds the given value to the counter
+func (c *statsDCounter) Add(value float64) {
+	c.send(formatStatsDValue(value), "c")
+}
+
+// statsDGauge is a gauge that is sent to StatsD
+type statsDGauge struct {
+	statsDMetric
+}
+
+// Set sets the gauge to the given value
+func (g *statsDGauge) Set(value float64) {
+	// a negative value would be interpreted as decrement, hence the gauge is reset to 0 first
+	if value < 0 {
+		g.send("0", "g")
+	}
+	g.send(formatStatsDValue(value), "g")
+}
+
+// Add adds the given value to the gauge (relative gauge update)
+func (g *statsDGauge) Add(value float64) {
+	if value < 0 {
+		g.send(formatStatsDValue(value), "g")
+		return
+	}
+	g.send("+"+formatStatsDValue(value), "g")
+}
+
+// statsDHistogram is a histogram that is sent to StatsD
+type statsDHistogram struct {
+	statsDMetric
+}
+
+// Observe adds the given value to the histogram
+func (h *statsDHistogram) Observe(value float64) {
+	h.send(formatStatsDValue(value), h.factory.histogramType())
+}
+
+// statsDVec is a vector of labelled metrics that are sent to StatsD
+type statsDVec struct {
+	factory    *StatsDFactory
+	name       string
+	labelNames []string
+}
+
+// metric returns the metric for the given label values. For DogStatsD the labels are sent as tags,
+// otherwise the label values are appended to the name of the metric.
+func (v *statsDVec) metric(lvs []string) statsDMetric {
+	if !v.factory.dogStatsD {
+		return statsDMetric{factory: v.factory, name: strings.Join(append([]string{v.name}, lvs...), ".")}
+	}
+
+	tags := make([]string, 0, len(lvs))
+	for i, value := range lvs {
+		if i < len(v.labelNames) {
+			tags = append(tags, v.labelNames[i]+":"+value)
+		}
+	}
+	return statsDMetric{factory: v.factory, name: v.name, tags: tags}
+}
+
+// statsDCounterVec is a vector of labelled counters that are sent to StatsD
+type statsDCounterVec struct {
+	statsDVec
+}
+
+// WithLabelValues returns the counter for the given label values
+func (v *statsDCounterVec) WithLabelValues(lvs ...string) Counter {
+	return &statsDCounter{v.metric(lvs)}
+}
+
+// statsDHistogramVec is a vector of labelled histograms that are sent to StatsD
+type statsDHistogramVec struct {
+	statsDVec
+}
+
+// WithLabelValues returns the histogram for the given label values
+func (v *statsDHistogramVec) WithLabelValues(lvs ...string) Histogram {
+	return &statsDHistogram{v.metric(lvs)}
+}
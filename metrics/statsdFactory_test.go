@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenStatsD starts a udp listener that receives the datagrams sent by the StatsDFactory
+func listenStatsD(t *testing.T) (net.PacketConn, func() string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	receive := func() string {
+		buffer := make([]byte, 1024)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(buffer)
+		require.NoError(t, err)
+		return string(buffer[:n])
+	}
+	return conn, receive
+}
+
+func TestStatsDFactory(t *testing.T) {
+	// GIVEN
+	conn, receive := listenStatsD(t)
+	defer conn.Close()
+	factory, err := NewStatsDFactory(conn.LocalAddr().String(), "gremcos", "cosmos")
+	require.NoError(t, err)
+	defer factory.Close()
+
+	// WHEN + THEN
+	factory.Counter("request_errors_total", "help").Inc()
+	assert.Equal(t, "gremcos.cosmos.request_errors_total:1|c", receive())
+
+	factory.Counter("request_charge_total", "help").Add(2.5)
+	assert.Equal(t, "gremcos.cosmos.request_charge_total:2.5|c", receive())
+
+	gauge := factory.Gauge("ru_limiter_tokens", "help")
+	gauge.Set(-3)
+	assert.Equal(t, "gremcos.cosmos.ru_limiter_tokens:0|g", receive())
+	assert.Equal(t, "gremcos.cosmos.ru_limiter_tokens:-3|g", receive())
+	gauge.Add(1)
+	assert.Equal(t, "gremcos.cosmos.ru_limiter_tokens:+1|g", receive())
+
+	factory.Histogram("retry_after_ms", "help", nil).Observe(33)
+	assert.Equal(t, "gremcos.cosmos.retry_after_ms:33|ms", receive())
+
+	factory.CounterVec("statuscode_total", "help", []string{"code"}).WithLabelValues("429").Inc()
+	assert.Equal(t, "gremcos.cosmos.statuscode_total.429:1|c", receive())
+
+	factory.HistogramVec("request_latency_ms", "help", nil, []string{"operation", "outcome"}).WithLabelValues("get-user", "success").Observe(12)
+	assert.Equal(t, "gremcos.cosmos.request_latency_ms.get-user.success:12|ms", receive())
+}
+
+func TestStatsDFactory_DogStatsD(t *testing.T) {
+	// GIVEN
+	conn, receive := listenStatsD(t)
+	defer conn.Close()
+	factory, err := NewStatsDFactory(conn.LocalAddr().String(), "gremcos", "", DogStatsDTags(map[string]string{"graph": "users", "account": "test"}))
+	require.NoError(t, err)
+	defer factory.Close()
+
+	// WHEN + THEN
+	factory.Counter("request_errors_total", "help").Inc()
+	assert.Equal(t, "gremcos.request_errors_total:1|c|#account:test,graph:users", receive())
+
+	factory.HistogramVec("request_latency_ms", "help", nil, []string{"operation", "outcome"}).WithLabelValues("get-user", "error").Observe(12)
+	assert.Equal(t, "gremcos.request_latency_ms:12|h|#account:test,graph:users,operation:get-user,outcome:error", receive())
+}
+
+func TestNewStatsDFactory_InvalidAddress(t *testing.T) {
+	// WHEN
+	factory, err := NewStatsDFactory("invalid-address", "gremcos", "cosmos")
+
+	// THEN
+	assert.Error(t, err)
+	assert.Nil(t, factory)
+}
@@ -38,3 +38,34 @@ func NewWrappedCounterVec(opts prometheus.CounterOpts, labelNames []string) *Wra
 		prom: promauto.NewCounterVec(opts, labelNames),
 	}
 }
+
+// WrapCounterVec wraps the given prometheus CounterVec
+func WrapCounterVec(vec *prometheus.CounterVec) *WrappedCounterVec {
+	return &WrappedCounterVec{
+		prom: vec,
+	}
+}
+
+// WrappedHistogramVec wraps a prometheus HistogramVec
+type WrappedHistogramVec struct {
+	prom *prometheus.HistogramVec
+}
+
+// WithLabelValues implements the WithLabelValues to meet the HistogramVec interface
+func (wH *WrappedHistogramVec) WithLabelValues(lvs ...string) Histogram {
+	return wH.prom.WithLabelValues(lvs...)
+}
+
+// NewWrappedHistogramVec creates a prometheus HistogramVec that is wrapped
+func NewWrappedHistogramVec(opts prometheus.HistogramOpts, labelNames []string) *WrappedHistogramVec {
+	return &WrappedHistogramVec{
+		prom: promauto.NewHistogramVec(opts, labelNames),
+	}
+}
+
+// WrapHistogramVec wraps the given prometheus HistogramVec
+func WrapHistogramVec(vec *prometheus.HistogramVec) *WrappedHistogramVec {
+	return &WrappedHistogramVec{
+		prom: vec,
+	}
+}
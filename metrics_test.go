@@ -1,10 +1,17 @@
 package gremcos
 
 import (
+	"expvar"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	m "github.com/supplyon/gremcos/metrics"
 	mock_metrics "github.com/supplyon/gremcos/test/mocks/metrics"
 )
 
@@ -21,6 +28,18 @@ type MetricsMocks struct {
 	requestErrorsTotal               *mock_metrics.MockCounter
 	requestRetiesTotal               *mock_metrics.MockCounter
 	requestRetryTimeoutsTotal        *mock_metrics.MockCounter
+	connectionEvictionsTotal         *mock_metrics.MockCounter
+	ruLimiterRate                    *mock_metrics.MockGauge
+	ruLimiterTokens                  *mock_metrics.MockGauge
+	ruLimiterWaitMS                  *mock_metrics.MockHistogram
+	requestHedgesIssuedTotal         *mock_metrics.MockCounter
+	requestHedgesWonTotal            *mock_metrics.MockCounter
+	requestLatencyMS                 *mock_metrics.MockHistogramVec
+	requestChargePerOperation        *mock_metrics.MockHistogramVec
+	serverTimePerOperationMS         *mock_metrics.MockHistogramVec
+	queryCacheHitsTotal              *mock_metrics.MockCounter
+	queryCacheMissesTotal            *mock_metrics.MockCounter
+	requestCoalescedTotal            *mock_metrics.MockCounter
 }
 
 // NewMockedMetrics creates and returns mocked metrics that can be used
@@ -44,6 +63,18 @@ func NewMockedMetrics(mockCtrl *gomock.Controller) (*Metrics, *MetricsMocks) {
 	mRequestErrorsTotal := mock_metrics.NewMockCounter(mockCtrl)
 	mRequestRetiesTotal := mock_metrics.NewMockCounter(mockCtrl)
 	mRequestRetryTimeoutsTotal := mock_metrics.NewMockCounter(mockCtrl)
+	mConnectionEvictionsTotal := mock_metrics.NewMockCounter(mockCtrl)
+	mRULimiterRate := mock_metrics.NewMockGauge(mockCtrl)
+	mRULimiterTokens := mock_metrics.NewMockGauge(mockCtrl)
+	mRULimiterWaitMS := mock_metrics.NewMockHistogram(mockCtrl)
+	mRequestHedgesIssuedTotal := mock_metrics.NewMockCounter(mockCtrl)
+	mRequestHedgesWonTotal := mock_metrics.NewMockCounter(mockCtrl)
+	mRequestLatencyMS := mock_metrics.NewMockHistogramVec(mockCtrl)
+	mRequestChargePerOperation := mock_metrics.NewMockHistogramVec(mockCtrl)
+	mServerTimePerOperationMS := mock_metrics.NewMockHistogramVec(mockCtrl)
+	mQueryCacheHitsTotal := mock_metrics.NewMockCounter(mockCtrl)
+	mQueryCacheMissesTotal := mock_metrics.NewMockCounter(mockCtrl)
+	mRequestCoalescedTotal := mock_metrics.NewMockCounter(mockCtrl)
 
 	metrics := &Metrics{
 		statusCodeTotal:                  mStatusCodeTotal,
@@ -58,6 +89,18 @@ func NewMockedMetrics(mockCtrl *gomock.Controller) (*Metrics, *MetricsMocks) {
 		requestErrorsTotal:               mRequestErrorsTotal,
 		requestRetiesTotal:               mRequestRetiesTotal,
 		requestRetryTimeoutsTotal:        mRequestRetryTimeoutsTotal,
+		connectionEvictionsTotal:         mConnectionEvictionsTotal,
+		ruLimiterRate:                    mRULimiterRate,
+		ruLimiterTokens:                  mRULimiterTokens,
+		ruLimiterWaitMS:                  mRULimiterWaitMS,
+		requestHedgesIssuedTotal:         mRequestHedgesIssuedTotal,
+		requestHedgesWonTotal:            mRequestHedgesWonTotal,
+		requestLatencyMS:                 mRequestLatencyMS,
+		requestChargePerOperation:        mRequestChargePerOperation,
+		serverTimePerOperationMS:         mServerTimePerOperationMS,
+		queryCacheHitsTotal:              mQueryCacheHitsTotal,
+		queryCacheMissesTotal:            mQueryCacheMissesTotal,
+		requestCoalescedTotal:            mRequestCoalescedTotal,
 	}
 
 	mocks := &MetricsMocks{
@@ -73,6 +116,18 @@ func NewMockedMetrics(mockCtrl *gomock.Controller) (*Metrics, *MetricsMocks) {
 		requestErrorsTotal:               mRequestErrorsTotal,
 		requestRetiesTotal:               mRequestRetiesTotal,
 		requestRetryTimeoutsTotal:        mRequestRetryTimeoutsTotal,
+		connectionEvictionsTotal:         mConnectionEvictionsTotal,
+		ruLimiterRate:                    mRULimiterRate,
+		ruLimiterTokens:                  mRULimiterTokens,
+		ruLimiterWaitMS:                  mRULimiterWaitMS,
+		requestHedgesIssuedTotal:         mRequestHedgesIssuedTotal,
+		requestHedgesWonTotal:            mRequestHedgesWonTotal,
+		requestLatencyMS:                 mRequestLatencyMS,
+		requestChargePerOperation:        mRequestChargePerOperation,
+		serverTimePerOperationMS:         mServerTimePerOperationMS,
+		queryCacheHitsTotal:              mQueryCacheHitsTotal,
+		queryCacheMissesTotal:            mQueryCacheMissesTotal,
+		requestCoalescedTotal:            mRequestCoalescedTotal,
 	}
 
 	return metrics, mocks
@@ -92,4 +147,133 @@ func Test_NewMetrics(t *testing.T) {
 	assert.NotNil(t, metrics.requestErrorsTotal)
 	assert.NotNil(t, metrics.requestRetiesTotal)
 	assert.NotNil(t, metrics.requestRetryTimeoutsTotal)
+	assert.NotNil(t, metrics.connectionEvictionsTotal)
+	assert.NotNil(t, metrics.ruLimiterRate)
+	assert.NotNil(t, metrics.ruLimiterTokens)
+	assert.NotNil(t, metrics.ruLimiterWaitMS)
+	assert.NotNil(t, metrics.requestHedgesIssuedTotal)
+	assert.NotNil(t, metrics.requestHedgesWonTotal)
+	assert.NotNil(t, metrics.requestLatencyMS)
+	assert.NotNil(t, metrics.requestChargePerOperation)
+	assert.NotNil(t, metrics.serverTimePerOperationMS)
+	assert.NotNil(t, metrics.queryCacheHitsTotal)
+	assert.NotNil(t, metrics.queryCacheMissesTotal)
+	assert.NotNil(t, metrics.requestCoalescedTotal)
+}
+
+// newMetricsWith returns the metrics collection registered at the given registry
+func newMetricsWith(t *testing.T, registry *prometheus.Registry, namespace string, constLabels prometheus.Labels) *Metrics {
+	metrics, err := NewMetricsWith(registry, namespace, constLabels)
+	require.NoError(t, err)
+	return metrics
+}
+
+// gatherAndCount returns the number of time series of the metric with the given name
+func gatherAndCount(t *testing.T, registry *prometheus.Registry, name string) int {
+	count, err := testutil.GatherAndCount(registry, name)
+	require.NoError(t, err)
+	return count
+}
+
+func Test_NewMetrics_ReusesRegisteredMetrics(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+	metrics1 := newMetricsWith(t, registry, "gremcos", nil)
+
+	// WHEN
+	metrics2 := newMetricsWith(t, registry, "gremcos", nil)
+	metrics1.requestErrorsTotal.Inc()
+	metrics2.requestErrorsTotal.Inc()
+
+	// THEN
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics1.requestErrorsTotal.(prometheus.Counter)))
+}
+
+func Test_NewMetricsWith_IndependentInstances(t *testing.T) {
+	// GIVEN
+	registry1 := prometheus.NewRegistry()
+	registry2 := prometheus.NewRegistry()
+
+	// WHEN
+	metrics1 := newMetricsWith(t, registry1, "service1", nil)
+	metrics2 := newMetricsWith(t, registry2, "service2", nil)
+	metrics3 := newMetricsWith(t, registry2, "service3", nil)
+	metrics1.requestErrorsTotal.Inc()
+
+	// THEN
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics1.requestErrorsTotal.(prometheus.Counter)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics2.requestErrorsTotal.(prometheus.Counter)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics3.requestErrorsTotal.(prometheus.Counter)))
+	assert.Equal(t, 1, gatherAndCount(t, registry1, "service1_cosmos_request_errors_total"))
+	assert.Equal(t, 1, gatherAndCount(t, registry2, "service2_cosmos_request_errors_total"))
+	assert.Equal(t, 1, gatherAndCount(t, registry2, "service3_cosmos_request_errors_total"))
+}
+
+func Test_NewMetricsWith_ConstLabels(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+
+	// WHEN
+	metricsUsers := newMetricsWith(t, registry, "gremcos", prometheus.Labels{"graph": "users"})
+	metricsOrders := newMetricsWith(t, registry, "gremcos", prometheus.Labels{"graph": "orders"})
+	metricsUsers.requestChargeTotal.Add(10)
+	metricsOrders.requestChargeTotal.Add(5)
+
+	// THEN
+	assert.Equal(t, float64(10), testutil.ToFloat64(metricsUsers.requestChargeTotal.(prometheus.Counter)))
+	assert.Equal(t, float64(5), testutil.ToFloat64(metricsOrders.requestChargeTotal.(prometheus.Counter)))
+	assert.Equal(t, 2, gatherAndCount(t, registry, "gremcos_cosmos_request_charge_total"))
+}
+
+func Test_NewMetricsWith_RegistrationError(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+	conflicting := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: "gremcos", Subsystem: "cosmos", Name: "request_errors_total", Help: "other"}, []string{"kind"})
+	require.NoError(t, registry.Register(conflicting))
+
+	// WHEN
+	metrics, err := NewMetricsWith(registry, "gremcos", nil)
+
+	// THEN
+	assert.Error(t, err)
+	assert.Nil(t, metrics)
+}
+
+func Test_NewMetricsFromFactory(t *testing.T) {
+	// GIVEN
+	// the expvar variables are process-global, hence a unique namespace is used per run (e.g. -count=2)
+	namespace := fmt.Sprintf("gremcosexpvar%d", time.Now().UnixNano())
+	factory := m.NewExpvarFactory(namespace, "cosmos")
+
+	// WHEN
+	metrics, err := NewMetricsFromFactory(factory)
+	require.NoError(t, err)
+	metrics.requestErrorsTotal.Inc()
+	metrics.incrementConnectivityErrorCount()
+	metrics.incrementConnectionUsageCount(connectionUsageKindWrite, false)
+
+	// THEN
+	assert.Equal(t, "1", expvar.Get(namespace+"_cosmos_request_errors_total").String())
+	assert.Equal(t, "1", expvar.Get(namespace+"_cosmos_connectivity_errors_total").String())
+	assert.Equal(t, `{"WRITE,false": 1}`, expvar.Get(namespace+"_cosmos_connection_usage_total").String())
+}
+
+func Test_NewMetricsWith_ConnectivityErrorsIsGauge(t *testing.T) {
+	// GIVEN
+	registry := prometheus.NewRegistry()
+	metrics := newMetricsWith(t, registry, "gremcos", nil)
+
+	// WHEN
+	metrics.incrementConnectivityErrorCount()
+
+	// THEN
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	var gauge float64
+	for _, family := range families {
+		if family.GetName() == "gremcos_cosmos_connectivity_errors_total" {
+			gauge = family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, float64(1), gauge)
 }
@@ -0,0 +1,257 @@
+package gremcos
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// Observer is notified about the lifecycle of connections and requests. It allows to integrate tracing (e.g. OpenTelemetry)
+// or logging without a dependency of gremcos to these libraries. The events of one call of the connector (e.g. Execute or ExecuteAsync)
+// are correlated by their CallID, including the pool waits, the retries and the attempts, which are numbered by Attempt.
+// The events of one gremlin request (one attempt) are correlated by their RequestID.
+// The methods are called synchronously on the goroutine that processes the request or connection, hence they should return quickly.
+// Embed NopObserver to implement only the methods of interest.
+type Observer interface {
+	// OnPoolWait is called after a connection was obtained from the pool (or obtaining one failed)
+	OnPoolWait(event PoolWaitEvent)
+	// OnDial is called after a new connection to the server was established (or dialing failed)
+	OnDial(event DialEvent)
+	// OnAuth is called after the server requested authentication and the credentials were sent
+	OnAuth(event AuthEvent)
+	// OnRequestStart is called after a request was handed over to the connection for sending it to the server
+	OnRequestStart(event RequestStartEvent)
+	// OnChunk is called for each response received from the server, e.g. for each partial content (206) response
+	OnChunk(event ChunkEvent)
+	// OnRetry is called before a request is retried
+	OnRetry(event RetryEvent)
+	// OnRequestEnd is called after the last response of a request was received (or the request failed)
+	OnRequestEnd(event RequestEndEvent)
+	// OnConnectionClosed is called after a connection was closed
+	OnConnectionClosed(event ConnectionClosedEvent)
+}
+
+// PoolWaitEvent describes how a connection was obtained from the pool
+type PoolWaitEvent struct {
+	// CallID is the id of the call the connection was obtained for (see Request.CallID), empty if it is unknown
+	CallID string
+	// Attempt is the number of the attempt of the call (starting at 1), 0 if the call is unknown
+	Attempt int
+	// Wait is the time it took to obtain the connection, including the time for dialing a new one
+	Wait time.Duration
+	// Blocked is true in case the maximum of active connections was reached and the request had to wait for a free connection
+	Blocked bool
+	// Err is the error in case no connection could be obtained
+	Err error
+}
+
+// DialEvent describes the establishment of a new connection
+type DialEvent struct {
+	// Duration is the time it took to connect to the server
+	Duration time.Duration
+	// Err is the error in case the connection could not be established
+	Err error
+}
+
+// AuthEvent describes the authentication that was requested by the server for a request
+type AuthEvent struct {
+	// CallID is the id of the call the request belongs to (see Request.CallID), empty if it is unknown
+	CallID string
+	// RequestID is the id of the request the server demanded the authentication for
+	RequestID string
+	// Err is the error in case the credentials could not be sent
+	Err error
+}
+
+// RequestStartEvent describes a request that is sent to the server
+type RequestStartEvent struct {
+	// CallID is the id of the call the request belongs to (see Request.CallID), empty if it is unknown
+	CallID string
+	// Attempt is the number of the attempt of the call (starting at 1), 0 if the call is unknown
+	Attempt int
+	// RequestID is the id of the gremlin request. Each attempt of a request (see OnRetry) has its own id.
+	RequestID string
+	// Query is the gremlin query of the request
+	Query string
+}
+
+// ChunkEvent describes one response received from the server.
+// The cosmos specific information is only available in case the server is a cosmos db.
+type ChunkEvent struct {
+	// CallID is the id of the call the response belongs to (see Request.CallID), empty if it is unknown
+	CallID string
+	// Attempt is the number of the attempt of the call (starting at 1), 0 if the call is unknown
+	Attempt int
+	// RequestID is the id of the gremlin request the response belongs to
+	RequestID string
+	// StatusCode is the gremlin status code of the response, e.g. 206 for partial content
+	StatusCode int
+	// Final is true in case this is the last response of the request
+	Final bool
+	// CosmosStatusCode is the cosmos status code (x-ms-status-code)
+	CosmosStatusCode int
+	// SubStatusCode is the cosmos sub status code (x-ms-substatus-code)
+	SubStatusCode int
+	// RequestCharge is the amount of request units (RU) consumed for this response (x-ms-request-charge)
+	RequestCharge float32
+	// ServerTime is the time spent on the server for this response (x-ms-server-time-ms)
+	ServerTime time.Duration
+	// ActivityID is the cosmos activity ID of this response (x-ms-activity-id)
+	ActivityID string
+	// Err is the error contained in the response
+	Err error
+}
+
+// RetryEvent describes an attempt of a request that is going to be retried
+type RetryEvent struct {
+	RetryAttempt
+	// CallID is the id of the call that is retried (see Request.CallID)
+	CallID string
+	// RequestID is the id of the gremlin request that is retried, empty if no response was received
+	RequestID string
+	// Wait is the time to wait before the request is retried
+	Wait time.Duration
+}
+
+// RequestEndEvent describes a finished request
+type RequestEndEvent struct {
+	// CallID is the id of the call the request belongs to (see Request.CallID), empty if it is unknown
+	CallID string
+	// Attempt is the number of the attempt of the call (starting at 1), 0 if the call is unknown
+	Attempt int
+	// RequestID is the id of the gremlin request
+	RequestID string
+	// Duration is the time between sending the request and receiving the last response
+	Duration time.Duration
+	// Chunks is the number of responses received for the request
+	Chunks int
+	// Err is the error in case the request failed
+	Err error
+}
+
+// ConnectionClosedEvent describes a closed connection
+type ConnectionClosedEvent struct {
+	// Reason is the last error of the connection, nil if it was closed regularly (e.g. evicted from the pool)
+	Reason error
+	// Err is the error that occurred while closing the connection
+	Err error
+}
+
+// NopObserver implements Observer and ignores all events
+type NopObserver struct{}
+
+func (NopObserver) OnPoolWait(_ PoolWaitEvent)                 {}
+func (NopObserver) OnDial(_ DialEvent)                         {}
+func (NopObserver) OnAuth(_ AuthEvent)                         {}
+func (NopObserver) OnRequestStart(_ RequestStartEvent)         {}
+func (NopObserver) OnChunk(_ ChunkEvent)                       {}
+func (NopObserver) OnRetry(_ RetryEvent)                       {}
+func (NopObserver) OnRequestEnd(_ RequestEndEvent)             {}
+func (NopObserver) OnConnectionClosed(_ ConnectionClosedEvent) {}
+
+// WithObserver sets the observer that is notified about the lifecycle of connections and requests.
+// It can be used to build tracing or logging adapters, e.g. creating a span per request with an event per chunk.
+func WithObserver(observer Observer) Option {
+	return func(c *cosmosImpl) {
+		c.observer = observer
+	}
+}
+
+// SetObserver sets the observer that is notified about the lifecycle of the connection and its requests
+func SetObserver(observer Observer) clientOption {
+	return func(c *client) {
+		c.observer = observer
+	}
+}
+
+// PoolObserver sets the observer that is notified when connections are obtained from the pool
+func PoolObserver(observer Observer) poolOption {
+	return func(p *pool) {
+		p.observer = observer
+	}
+}
+
+// observerOrNop returns the given observer or the NopObserver in case none is set
+func observerOrNop(observer Observer) Observer {
+	if observer == nil {
+		return NopObserver{}
+	}
+	return observer
+}
+
+// callTrace identifies the call and the attempt the events of a gremlin request belong to
+type callTrace struct {
+	// id is the id of the call, empty if the request is not issued by a call of the connector
+	id string
+	// attempt is the number of the attempt of the call (starting at 1)
+	attempt int
+}
+
+// tracedExecutor is implemented by query executors that attribute the events of their requests to a call (see client)
+type tracedExecutor interface {
+	executeFor(call callTrace, query string, bindings, rebindings map[string]interface{}) ([]interfaces.Response, error)
+	executeAsyncFor(call callTrace, query string, responseChannel chan interfaces.AsyncResponse) error
+}
+
+// newCallID returns a unique id for a call of the connector, empty in case it can't be generated
+func newCallID() string {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
+
+// callObserver adds the id of the call to the retry events of the wrapped observer
+type callObserver struct {
+	Observer
+	callID string
+}
+
+func (o callObserver) OnRetry(event RetryEvent) {
+	event.CallID = o.callID
+	o.Observer.OnRetry(event)
+}
+
+// observerFor returns the observer notified about the retries of the call with the given id, nil if there is no observer
+func (c *cosmosImpl) observerFor(callID string) Observer {
+	if c.observer == nil {
+		return nil
+	}
+	return callObserver{Observer: c.observer, callID: callID}
+}
+
+// newChunkEvent creates the event for the given response of a request of the given call. The cosmos specific information
+// is taken from the status attributes in case they are present.
+func newChunkEvent(resp interfaces.Response, err error, call callTrace) ChunkEvent {
+	event := ChunkEvent{
+		CallID:     call.id,
+		Attempt:    call.attempt,
+		RequestID:  resp.RequestID,
+		StatusCode: resp.Status.Code,
+		Final:      resp.Status.Code != interfaces.StatusPartialContent,
+		Err:        err,
+	}
+
+	respInfo, parseErr := parseAttributeMap(resp.Status.Attributes)
+	if parseErr != nil {
+		return event
+	}
+	event.CosmosStatusCode = respInfo.statusCode
+	event.SubStatusCode = respInfo.subStatusCode
+	event.RequestCharge = respInfo.requestCharge
+	event.ServerTime = respInfo.serverTime
+	event.ActivityID = respInfo.activityID
+	return event
+}
+
+// firstRequestID returns the request id of the first of the given responses, empty if there is none
+func firstRequestID(responses []interfaces.Response) string {
+	for _, response := range responses {
+		if len(response.RequestID) > 0 {
+			return response.RequestID
+		}
+	}
+	return ""
+}
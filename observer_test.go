@@ -0,0 +1,284 @@
+package gremcos
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/supplyon/gremcos/interfaces"
+	mock_interfaces "github.com/supplyon/gremcos/test/mocks/interfaces"
+)
+
+// recordingObserver records all events it was notified about
+type recordingObserver struct {
+	mux    sync.Mutex
+	events []interface{}
+}
+
+func (r *recordingObserver) record(event interface{}) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recordingObserver) recorded() []interface{} {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]interface{}{}, r.events...)
+}
+
+func (r *recordingObserver) OnPoolWait(event PoolWaitEvent)                 { r.record(event) }
+func (r *recordingObserver) OnDial(event DialEvent)                         { r.record(event) }
+func (r *recordingObserver) OnAuth(event AuthEvent)                         { r.record(event) }
+func (r *recordingObserver) OnRequestStart(event RequestStartEvent)         { r.record(event) }
+func (r *recordingObserver) OnChunk(event ChunkEvent)                       { r.record(event) }
+func (r *recordingObserver) OnRetry(event RetryEvent)                       { r.record(event) }
+func (r *recordingObserver) OnRequestEnd(event RequestEndEvent)             { r.record(event) }
+func (r *recordingObserver) OnConnectionClosed(event ConnectionClosedEvent) { r.record(event) }
+
+func TestObserver_ClientRequest(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedDialer := mock_interfaces.NewMockDialer(mockCtrl)
+	observer := &recordingObserver{}
+	client := newClient(mockedDialer, SetObserver(observer))
+	mockedDialer.EXPECT().IsConnected().Return(true)
+
+	// WHEN
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := client.Execute("g.V()")
+		assert.NoError(t, err)
+	}()
+
+	req, err := packedRequest2Request(<-client.requests)
+	require.NoError(t, err)
+
+	partial := interfaces.Response{RequestID: req.RequestID, Status: interfaces.Status{Code: interfaces.StatusPartialContent, Attributes: map[string]interface{}{
+		"x-ms-status-code":    200,
+		"x-ms-request-charge": 1.5,
+		"x-ms-activity-id":    "activity-1",
+	}}}
+	final := interfaces.Response{RequestID: req.RequestID, Status: interfaces.Status{Code: interfaces.StatusSuccess}}
+	for _, response := range []interfaces.Response{partial, final} {
+		packet, err := json.Marshal(response)
+		require.NoError(t, err)
+		require.NoError(t, client.handleResponse(packet))
+	}
+	wg.Wait()
+
+	// THEN
+	events := observer.recorded()
+	require.Len(t, events, 4)
+	assert.Equal(t, RequestStartEvent{RequestID: req.RequestID, Query: "g.V()"}, events[0])
+	assert.Equal(t, ChunkEvent{RequestID: req.RequestID, StatusCode: interfaces.StatusPartialContent, CosmosStatusCode: 200, RequestCharge: 1.5, ActivityID: "activity-1"}, events[1])
+	assert.Equal(t, ChunkEvent{RequestID: req.RequestID, StatusCode: interfaces.StatusSuccess, Final: true}, events[2])
+	end, ok := events[3].(RequestEndEvent)
+	require.True(t, ok)
+	assert.Equal(t, req.RequestID, end.RequestID)
+	assert.Equal(t, 2, end.Chunks)
+	assert.NoError(t, end.Err)
+}
+
+func TestObserver_Authenticate(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedDialer := mock_interfaces.NewMockDialer(mockCtrl)
+	observer := &recordingObserver{}
+	client := newClient(mockedDialer, SetObserver(observer))
+
+	// WHEN
+	err := client.authenticate("reqID")
+
+	// THEN
+	assert.Error(t, err)
+	events := observer.recorded()
+	require.Len(t, events, 1)
+	assert.Equal(t, AuthEvent{RequestID: "reqID", Err: err}, events[0])
+}
+
+func TestObserver_DialAndClose(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedDialer := mock_interfaces.NewMockDialer(mockCtrl)
+	mockedDialer.EXPECT().Connect().Return(nil)
+	mockedDialer.EXPECT().Read()
+	mockedDialer.EXPECT().Close().Return(nil)
+	errChan := make(chan error, 100)
+	observer := &recordingObserver{}
+
+	// WHEN
+	client, err := Dial(mockedDialer, errChan, SetObserver(observer))
+	require.NoError(t, err)
+	closeErr := client.Close()
+	close(errChan)
+
+	// THEN
+	assert.NoError(t, closeErr)
+	events := observer.recorded()
+	require.Len(t, events, 2)
+	dial, ok := events[0].(DialEvent)
+	require.True(t, ok)
+	assert.NoError(t, dial.Err)
+	assert.Equal(t, ConnectionClosedEvent{}, events[1])
+}
+
+func TestObserver_Retry(t *testing.T) {
+	// GIVEN
+	observer := &recordingObserver{}
+	throttled := interfaces.Response{RequestID: "req-1", Status: interfaces.Status{Code: interfaces.StatusServerError, Attributes: map[string]interface{}{
+		"x-ms-status-code":    429,
+		"x-ms-substatus-code": 3200,
+		"x-ms-retry-after-ms": "00:00:00.001",
+	}}}
+	success := interfaces.Response{RequestID: "req-2", Status: interfaces.Status{Code: interfaces.StatusSuccess}}
+	attempts := 0
+	retryFn := func() ([]interfaces.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return []interfaces.Response{throttled}, nil
+		}
+		return []interfaces.Response{success}, nil
+	}
+
+	// WHEN
+	responses, err := retryLoop(retryFn, DefaultRetryPolicy(1), time.Second, false, true, newStubbedMetrics(), observer, zerolog.Nop())
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, []interfaces.Response{success}, responses)
+	events := observer.recorded()
+	require.Len(t, events, 1)
+	retry, ok := events[0].(RetryEvent)
+	require.True(t, ok)
+	assert.Equal(t, "req-1", retry.RequestID)
+	assert.Equal(t, 1, retry.Attempt)
+	assert.Equal(t, 429, retry.StatusCode)
+	assert.Equal(t, time.Millisecond, retry.Wait)
+}
+
+func TestObserver_PoolWait(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	observer := &recordingObserver{}
+	mockedQueryExecutor := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	clientFactory := func() (interfaces.QueryExecutor, error) {
+		return mockedQueryExecutor, nil
+	}
+	pool, err := NewPool(clientFactory, 1, time.Second*30, zerolog.Nop(), PoolObserver(observer))
+	require.NoError(t, err)
+
+	// WHEN
+	pc, err := pool.Get()
+
+	// THEN
+	require.NoError(t, err)
+	require.NotNil(t, pc)
+	events := observer.recorded()
+	require.Len(t, events, 1)
+	wait, ok := events[0].(PoolWaitEvent)
+	require.True(t, ok)
+	assert.False(t, wait.Blocked)
+	assert.NoError(t, wait.Err)
+}
+
+func TestObserver_EventsOfOneCall(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedDialer := mock_interfaces.NewMockDialer(mockCtrl)
+	mockedDialer.EXPECT().IsConnected().AnyTimes().Return(true)
+	observer := &recordingObserver{}
+	client := newClient(mockedDialer, SetObserver(observer))
+	clientFactory := func() (interfaces.QueryExecutor, error) {
+		return client, nil
+	}
+	pool, err := NewPool(clientFactory, 1, time.Second*30, zerolog.Nop(), PoolObserver(observer))
+	require.NoError(t, err)
+	cosmos := cosmosImpl{
+		logger:       zerolog.Nop(),
+		pool:         pool,
+		metrics:      newStubbedMetrics(),
+		maxRetries:   1,
+		retryTimeout: time.Second * 2,
+		observer:     observer,
+	}
+
+	throttled := interfaces.Status{Code: interfaces.StatusServerError, Attributes: map[string]interface{}{
+		"x-ms-status-code":    429,
+		"x-ms-substatus-code": 3200,
+		"x-ms-retry-after-ms": "00:00:00.001",
+	}}
+	success := interfaces.Status{Code: interfaces.StatusSuccess}
+
+	// WHEN
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := cosmos.Execute("g.V()")
+		assert.NoError(t, err)
+	}()
+
+	requestIDs := make([]string, 0, 2)
+	for _, status := range []interfaces.Status{throttled, success} {
+		req, err := packedRequest2Request(<-client.requests)
+		require.NoError(t, err)
+		requestIDs = append(requestIDs, req.RequestID)
+		packet, err := json.Marshal(interfaces.Response{RequestID: req.RequestID, Status: status})
+		require.NoError(t, err)
+		// the error contained in the throttled response is handed over to the request as well
+		_ = client.handleResponse(packet)
+	}
+	wg.Wait()
+
+	// THEN
+	events := observer.recorded()
+	require.Len(t, events, 9)
+	callID := events[0].(PoolWaitEvent).CallID
+	assert.NotEmpty(t, callID)
+	for i, attempt := range []int{1, 2} {
+		offset := i * 5
+		wait, ok := events[offset].(PoolWaitEvent)
+		require.True(t, ok)
+		assert.Equal(t, callID, wait.CallID)
+		assert.Equal(t, attempt, wait.Attempt)
+		assert.Equal(t, RequestStartEvent{CallID: callID, Attempt: attempt, RequestID: requestIDs[i], Query: "g.V()"}, events[offset+1])
+		chunk, ok := events[offset+2].(ChunkEvent)
+		require.True(t, ok)
+		assert.Equal(t, callID, chunk.CallID)
+		assert.Equal(t, attempt, chunk.Attempt)
+		end, ok := events[offset+3].(RequestEndEvent)
+		require.True(t, ok)
+		assert.Equal(t, callID, end.CallID)
+		assert.Equal(t, attempt, end.Attempt)
+	}
+	retry, ok := events[4].(RetryEvent)
+	require.True(t, ok)
+	assert.Equal(t, callID, retry.CallID)
+	assert.Equal(t, 1, retry.Attempt)
+	assert.Equal(t, requestIDs[0], retry.RequestID)
+}
+
+func TestNewChunkEvent_WithoutCosmosAttributes(t *testing.T) {
+	// GIVEN
+	response := interfaces.Response{RequestID: "req", Status: interfaces.Status{Code: interfaces.StatusPartialContent}}
+
+	// WHEN
+	event := newChunkEvent(response, nil, callTrace{})
+
+	// THEN
+	assert.Equal(t, ChunkEvent{RequestID: "req", StatusCode: interfaces.StatusPartialContent}, event)
+}
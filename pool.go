@@ -30,22 +30,81 @@ type pool struct {
 	// idleConnections list of idle connections
 	idleConnections []*idleConnection
 
-	// active is the number of currently active connections
+	// active is the number of currently active connections.
+	// In multiplexing mode this is the number of open connections.
 	active int
 
+	// maxConcurrentRequestsPerConnection is the maximum number of requests that are executed on one connection at the same time.
+	// If this value is set to 0, each connection is used exclusively by one request (no multiplexing).
+	maxConcurrentRequestsPerConnection int
+
+	// sharedConnections list of connections that are shared between requests (multiplexing mode)
+	sharedConnections []*sharedConnection
+
+	// healthCheckInterval is the interval in which the idle connections are checked in the background.
+	// Idle connections that failed the ping, have an error or exceeded the idleTimeout are removed from the pool.
+	// If this interval is set to 0, the background health check is disabled and idle connections are only
+	// inspected when a connection is obtained via Get.
+	healthCheckInterval time.Duration
+
+	// validateOnBorrow defines whether an idle connection is pinged before it is handed out by Get.
+	validateOnBorrow bool
+
+	// metrics is used to count connections that are evicted from the pool (optional)
+	metrics *Metrics
+
+	// observer is notified when connections are obtained from the pool (optional)
+	observer Observer
+
+	// quitChannel notifies the maintenance worker to stop
+	quitChannel chan struct{}
+	// stopOnce ensures that the maintenance worker is only stopped once
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
 	closed bool
 	cond   *sync.Cond
 	mu     sync.RWMutex
 }
 
+// poolOption is the struct for defining optional parameters for the pool
+type poolOption func(*pool)
+
+// PoolHealthCheckInterval sets the interval in which idle connections are checked in the background.
+// Idle connections that failed the ping, have an error or exceeded the idle timeout are removed from the pool.
+// Per default (interval = 0) the background health check is disabled.
+func PoolHealthCheckInterval(interval time.Duration) poolOption {
+	return func(p *pool) {
+		p.healthCheckInterval = interval
+	}
+}
+
+// PoolValidateOnBorrow defines whether an idle connection is pinged before it is handed out.
+// Connections that fail this validation are closed and the next idle connection is used or a new one is dialed.
+func PoolValidateOnBorrow(validate bool) poolOption {
+	return func(p *pool) {
+		p.validateOnBorrow = validate
+	}
+}
+
+// PoolMetrics sets the metrics that are updated by the pool, e.g. the number of evicted connections.
+func PoolMetrics(metrics *Metrics) poolOption {
+	return func(p *pool) {
+		p.metrics = metrics
+	}
+}
+
 // pooledConnection represents a shared and reusable connection.
 type pooledConnection struct {
 	pool   *pool
 	client interfaces.QueryExecutor
+
+	// shared is the according shared connection in case the pool is in multiplexing mode
+	shared *sharedConnection
 }
 
 // NewPool creates a new pool which is a QueryExecutor
-func NewPool(createQueryExecutor QueryExecutorFactoryFunc, maxActiveConnections int, idleTimeout time.Duration, logger zerolog.Logger) (*pool, error) {
+func NewPool(createQueryExecutor QueryExecutorFactoryFunc, maxActiveConnections int, idleTimeout time.Duration, logger zerolog.Logger, options ...poolOption) (*pool, error) {
 
 	if createQueryExecutor == nil {
 		return nil, fmt.Errorf("Given createQueryExecutor is nil")
@@ -59,7 +118,7 @@ func NewPool(createQueryExecutor QueryExecutorFactoryFunc, maxActiveConnections
 		return nil, fmt.Errorf("maxActiveConnections has to be >=0")
 	}
 
-	return &pool{
+	p := &pool{
 		createQueryExecutor: createQueryExecutor,
 		maxActive:           maxActiveConnections,
 		active:              0,
@@ -67,7 +126,27 @@ func NewPool(createQueryExecutor QueryExecutorFactoryFunc, maxActiveConnections
 		idleTimeout:         idleTimeout,
 		idleConnections:     make([]*idleConnection, 0),
 		logger:              logger,
-	}, nil
+		quitChannel:         make(chan struct{}),
+	}
+
+	for _, opt := range options {
+		opt(p)
+	}
+
+	if p.healthCheckInterval < 0 {
+		return nil, fmt.Errorf("healthCheckInterval has to be >=0")
+	}
+
+	if p.maxConcurrentRequestsPerConnection < 0 {
+		return nil, fmt.Errorf("maxConcurrentRequestsPerConnection has to be >=0")
+	}
+
+	if p.healthCheckInterval > 0 {
+		p.wg.Add(1)
+		go p.maintenanceWorker(p.healthCheckInterval)
+	}
+
+	return p, nil
 }
 
 type idleConnection struct {
@@ -80,6 +159,10 @@ type idleConnection struct {
 // IsConnected returns true in case at least one (idle or active) connection
 // managed by the pool is connected.
 func (p *pool) IsConnected() bool {
+	if p.isMultiplexing() {
+		return p.isSharedConnected()
+	}
+
 	p.mu.RLock()
 	// in case we have at least one active connection
 	// --> we can return immediately with status connected
@@ -116,7 +199,28 @@ func (p *pool) LastError() error {
 // Get will return an available pooled connection. Either an idle connection or
 // by dialing a new one if the pool does not currently have a maximum number
 // of active connections.
-func (p *pool) Get() (*pooledConnection, error) {
+func (p *pool) Get() (pc *pooledConnection, err error) {
+	return p.get(callTrace{}, nil)
+}
+
+// get returns an available pooled connection for the given call like Get. In multiplexing mode the shared connections in used
+// are avoided if possible and the returned connection is added to them.
+func (p *pool) get(call callTrace, used *usedConnections) (pc *pooledConnection, err error) {
+	start := time.Now()
+	blocked := false
+	defer func() {
+		observerOrNop(p.observer).OnPoolWait(PoolWaitEvent{CallID: call.id, Attempt: call.attempt, Wait: time.Since(start), Blocked: blocked, Err: err})
+	}()
+
+	if p.isMultiplexing() {
+		return p.getShared(&blocked, used)
+	}
+	return p.getExclusive(&blocked)
+}
+
+// getExclusive returns an idle connection or dials a new one. The connection is used exclusively by one request.
+// In case the caller had to wait for a free connection, blocked is set to true.
+func (p *pool) getExclusive(blocked *bool) (*pooledConnection, error) {
 	// Lock the pool to keep the kids out.
 	p.mu.Lock()
 
@@ -134,10 +238,23 @@ func (p *pool) Get() (*pooledConnection, error) {
 			// Remove the connection from the idle slice
 			p.idleConnections = append(p.idleConnections[:0], p.idleConnections[1:]...)
 			p.active++
+			validateOnBorrow := p.validateOnBorrow
 			p.mu.Unlock()
+
+			// Unlocked during validation, so that other callers don't have to wait for the ping.
+			if validateOnBorrow {
+				if err := validateConnection(conn.pc.client); err != nil {
+					p.logger.Info().Err(err).Msg("Remove connection from pool which failed the validation on borrow")
+					conn.pc.client.Close()
+
+					p.mu.Lock()
+					p.release()
+					continue
+				}
+			}
+
 			pc := &pooledConnection{pool: p, client: conn.pc.client}
 			return pc, nil
-
 		}
 
 		// No idle connections, try dialing a new one
@@ -167,17 +284,32 @@ func (p *pool) Get() (*pooledConnection, error) {
 		}
 
 		p.logger.Info().Int("active", p.active).Int("maxActive", p.maxActive).Int("idle", len(p.idleConnections)).Msg("Wait for new connections")
+		*blocked = true
 		p.cond.Wait()
 	}
 }
 
 // put pushes the supplied pooledConnection to the top of the idle slice to be reused.
+// Connections that have an error or are not connected any more are closed instead.
 // It is not threadsafe. The caller should manage locking the pool.
 func (p *pool) put(pc *pooledConnection) {
 	if p.closed {
 		pc.client.Close()
 		return
 	}
+
+	// don't pool connections that broke while they were borrowed (e.g. failed the health check in the meantime)
+	if err := pc.client.LastError(); err != nil {
+		p.logger.Info().Err(err).Msg("Close returned connection due to an error instead of pooling it")
+		pc.client.Close()
+		return
+	}
+	if !pc.client.IsConnected() {
+		p.logger.Info().Msg("Close returned connection which is not connected instead of pooling it")
+		pc.client.Close()
+		return
+	}
+
 	idle := &idleConnection{pc: pc, idleSince: time.Now()}
 	// Prepend the connection to the front of the slice
 	p.idleConnections = append([]*idleConnection{idle}, p.idleConnections...)
@@ -261,14 +393,105 @@ func (p *pool) first() *idleConnection {
 	return p.idleConnections[0]
 }
 
+// validateConnection returns an error in case the given connection has an error or does not respond to a ping.
+func validateConnection(client interfaces.QueryExecutor) error {
+	if err := client.LastError(); err != nil {
+		return err
+	}
+	return client.Ping()
+}
+
+// maintenanceWorker periodically checks the idle connections until the pool is closed.
+func (p *pool) maintenanceWorker(interval time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if p.isMultiplexing() {
+				p.checkSharedConnections()
+				continue
+			}
+			p.checkIdleConnections()
+		case <-p.quitChannel:
+			p.logger.Debug().Msg("Pool maintenance worker stopped")
+			return
+		}
+	}
+}
+
+// checkIdleConnections removes expired idle connections and pings the remaining ones.
+// Connections that have an error or failed the ping are closed and removed from the pool.
+func (p *pool) checkIdleConnections() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.purge()
+
+	// copy the idle connections to be able to ping them without holding the lock
+	idleConnectionsCopy := make([]*idleConnection, len(p.idleConnections))
+	copy(idleConnectionsCopy, p.idleConnections)
+	p.mu.Unlock()
+
+	failed := make(map[*idleConnection]struct{})
+	for _, idleConnection := range idleConnectionsCopy {
+		if err := validateConnection(idleConnection.pc.client); err != nil {
+			p.logger.Info().Err(err).Msg("(during health check) Remove connection from pool which failed the health check")
+			failed[idleConnection] = struct{}{}
+		}
+	}
+
+	if len(failed) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	idleConnectionsAfterCheck := make([]*idleConnection, 0, len(p.idleConnections))
+	var evicted []*idleConnection
+	for _, idleConnection := range p.idleConnections {
+		if _, ok := failed[idleConnection]; ok {
+			evicted = append(evicted, idleConnection)
+			continue
+		}
+		idleConnectionsAfterCheck = append(idleConnectionsAfterCheck, idleConnection)
+	}
+	p.idleConnections = idleConnectionsAfterCheck
+	p.mu.Unlock()
+
+	// Only close the connections that are still owned by the pool. Connections that were
+	// borrowed in the meantime are removed on return since they are not connected any more.
+	for _, idleConnection := range evicted {
+		idleConnection.pc.client.Close()
+	}
+}
+
+// stopMaintenance stops the maintenance worker and waits until it is finished.
+func (p *pool) stopMaintenance() {
+	p.stopOnce.Do(func() {
+		if p.quitChannel != nil {
+			close(p.quitChannel)
+		}
+	})
+	p.wg.Wait()
+}
+
 // Close closes the pool.
 func (p *pool) Close() error {
+	// stop the maintenance worker before locking the pool, since it needs the lock to finish its work
+	p.stopMaintenance()
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	for _, c := range p.idleConnections {
 		c.pc.client.Close()
 	}
+	p.closeShared()
 
 	p.closed = true
 	return nil
@@ -280,7 +503,8 @@ func (p *pool) ExecuteWithBindings(query string, bindings, rebindings map[string
 	if err != nil {
 		return nil, err
 	}
-	defer pc.Close()
+	// put the connection back into the idle pool or evict it if it is not usable any more
+	defer func() { pc.release(resp) }()
 	return pc.client.ExecuteWithBindings(query, bindings, rebindings)
 }
 
@@ -290,21 +514,62 @@ func (p *pool) Execute(query string) (resp []interfaces.Response, err error) {
 	if err != nil {
 		return nil, err
 	}
-	// put the connection back into the idle pool
-	defer pc.Close()
+	// put the connection back into the idle pool or evict it if it is not usable any more
+	defer func() { pc.release(resp) }()
 
 	return pc.client.Execute(query)
 }
 
+// ExecuteAsync grabs a connection from the pool and streams the responses into the given channel.
+// The connection is kept until all responses are received. Afterwards it is put back into the idle pool
+// or evicted if it is not usable any more.
 func (p *pool) ExecuteAsync(query string, responseChannel chan interfaces.AsyncResponse) (err error) {
-	pc, err := p.Get()
+	return p.executeAsyncFor(callTrace{}, query, responseChannel)
+}
+
+// executeAsyncFor executes the given query like ExecuteAsync. The events of the request are attributed to the given call (see Observer).
+func (p *pool) executeAsyncFor(call callTrace, query string, responseChannel chan interfaces.AsyncResponse) (err error) {
+	pc, err := p.get(call, nil)
 	if err != nil {
 		return err
 	}
-	// put the connection back into the idle pool
-	defer pc.Close()
 
-	return pc.client.ExecuteAsync(query, responseChannel)
+	intermediateChannel := make(chan interfaces.AsyncResponse, cap(responseChannel))
+	if traced, ok := pc.client.(tracedExecutor); ok {
+		err = traced.executeAsyncFor(call, query, intermediateChannel)
+	} else {
+		err = pc.client.ExecuteAsync(query, intermediateChannel)
+	}
+	if err != nil {
+		// put the connection back into the idle pool
+		pc.Close()
+		return err
+	}
+
+	go func() {
+		defer close(responseChannel)
+
+		responses := make([]interfaces.Response, 0, 5)
+		for resp := range intermediateChannel {
+			responses = append(responses, resp.Response)
+			responseChannel <- resp
+		}
+		pc.release(responses)
+	}()
+
+	return nil
+}
+
+// executeFor executes the given function of the given call on a pooled connection, avoiding the shared connections in used (see get).
+func (p *pool) executeFor(call callTrace, used *usedConnections, execute func(client interfaces.QueryExecutor) ([]interfaces.Response, error)) (resp []interfaces.Response, err error) {
+	pc, err := p.get(call, used)
+	if err != nil {
+		return nil, err
+	}
+	// put the connection back into the idle pool or evict it if it is not usable any more
+	defer func() { pc.release(resp) }()
+
+	return execute(pc.client)
 }
 
 func (p *pool) ExecuteFile(path string) (resp []interfaces.Response, err error) {
@@ -312,8 +577,8 @@ func (p *pool) ExecuteFile(path string) (resp []interfaces.Response, err error)
 	if err != nil {
 		return nil, err
 	}
-	// put the connection back into the idle pool
-	defer pc.Close()
+	// put the connection back into the idle pool or evict it if it is not usable any more
+	defer func() { pc.release(resp) }()
 
 	return pc.client.ExecuteFile(path)
 }
@@ -323,18 +588,55 @@ func (p *pool) ExecuteFileWithBindings(path string, bindings, rebindings map[str
 	if err != nil {
 		return nil, err
 	}
-	// put the connection back into the idle pool
-	defer pc.Close()
+	// put the connection back into the idle pool or evict it if it is not usable any more
+	defer func() { pc.release(resp) }()
 
 	return pc.client.ExecuteFileWithBindings(path, bindings, rebindings)
 }
 
+// release puts the connection back into the idle pool. In case the given responses indicate
+// that the connection is not usable any more (e.g. cosmos status codes 1007, 1008), the
+// connection is evicted instead. This ensures that a retry is done on a different connection.
+func (pc *pooledConnection) release(responses []interfaces.Response) {
+	if requiresNewConnection(responses) {
+		pc.evict()
+		return
+	}
+	pc.Close()
+}
+
+// evict closes the connection and removes it from the pool instead of putting it back into the idle pool.
+func (pc *pooledConnection) evict() {
+	pc.pool.mu.Lock()
+	defer pc.pool.mu.Unlock()
+
+	pc.pool.logger.Info().Msg("Evict connection from pool since a retry on a new connection is suggested")
+	if pc.pool.metrics != nil {
+		pc.pool.metrics.connectionEvictionsTotal.Inc()
+	}
+
+	if pc.shared != nil {
+		// the shared connection is closed as soon as the last request using it has finished
+		pc.pool.removeShared(pc.shared)
+		pc.pool.releaseShared(pc.shared)
+		return
+	}
+
+	pc.client.Close()
+	pc.pool.release()
+}
+
 // Close signals that the caller is finished with the connection and should be
 // returned to the pool for future use.
 func (pc *pooledConnection) Close() {
 	pc.pool.mu.Lock()
 	defer pc.pool.mu.Unlock()
 
+	if pc.shared != nil {
+		pc.pool.releaseShared(pc.shared)
+		return
+	}
+
 	pc.pool.put(pc)
 	pc.pool.release()
 }
@@ -0,0 +1,295 @@
+package gremcos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// sharedConnection is a connection that is used by multiple requests at the same time (multiplexing mode).
+type sharedConnection struct {
+	client interfaces.QueryExecutor
+
+	// inFlight is the number of requests that are currently executed using this connection
+	inFlight int
+
+	// idleSince is the time the last request that was using this connection has finished
+	idleSince time.Time
+
+	// removed marks a connection that is not handed out any more.
+	// It is closed as soon as the last request that is using it has finished.
+	removed bool
+}
+
+// usedConnections are the shared connections the original and the hedged request are executed on (see HedgeReadRequests).
+// Since a hedged request on the same (slow) connection is pointless, the requests avoid the connections in use by each other.
+// It is not threadsafe. It is only accessed while the pool is locked.
+type usedConnections struct {
+	connections []*sharedConnection
+}
+
+// contains returns true in case the given shared connection is used already
+func (u *usedConnections) contains(sc *sharedConnection) bool {
+	if u == nil {
+		return false
+	}
+	for _, used := range u.connections {
+		if used == sc {
+			return true
+		}
+	}
+	return false
+}
+
+// add marks the given shared connection as used
+func (u *usedConnections) add(sc *sharedConnection) {
+	if u == nil {
+		return
+	}
+	u.connections = append(u.connections, sc)
+}
+
+// PoolMaxConcurrentRequestsPerConnection enables the multiplexing mode of the pool.
+// Per default (maxConcurrentRequests = 0) each request uses a connection exclusively.
+// In multiplexing mode the connections are shared between requests. Each request is executed on the least
+// loaded connection as long as it serves less than maxConcurrentRequests requests. Only if all connections
+// are fully loaded a new one is dialed (up to the maximum number of active connections).
+func PoolMaxConcurrentRequestsPerConnection(maxConcurrentRequests int) poolOption {
+	return func(p *pool) {
+		p.maxConcurrentRequestsPerConnection = maxConcurrentRequests
+	}
+}
+
+// isMultiplexing returns true in case the connections are shared between requests
+func (p *pool) isMultiplexing() bool {
+	return p.maxConcurrentRequestsPerConnection > 0
+}
+
+// getShared returns the least loaded shared connection. A new connection is dialed
+// in case all connections are fully loaded and the maximum number of active connections is not yet reached.
+// In case the caller had to wait for free capacity, blocked is set to true.
+// The connections in used are avoided, unless no other connection can be used or dialed. The returned connection is added to used.
+func (p *pool) getShared(blocked *bool, used *usedConnections) (*pooledConnection, error) {
+	p.mu.Lock()
+
+	p.purgeShared()
+
+	for {
+		p.logger.Debug().Int("active", p.active).Int("maxActive", p.maxActive).Int("shared", len(p.sharedConnections)).Msg("Pool-Get (multiplexing)")
+
+		if sc := p.leastLoaded(used); sc != nil && sc.inFlight < p.maxConcurrentRequestsPerConnection {
+			sc.inFlight++
+			used.add(sc)
+			p.mu.Unlock()
+			return &pooledConnection{pool: p, client: sc.client, shared: sc}, nil
+		}
+
+		// All connections are fully loaded, try dialing a new one.
+		// In multiplexing mode active is the number of open connections (including the ones that are currently dialed).
+		if p.maxActive == 0 || p.active < p.maxActive {
+			p.active++
+			createQueryExecutor := p.createQueryExecutor
+
+			// Unlock here so that requests can be executed on the other connections while dialing.
+			p.mu.Unlock()
+
+			dc, err := createQueryExecutor()
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if err != nil {
+				p.releaseConnectionSlot()
+				return nil, err
+			}
+
+			sc := &sharedConnection{client: dc, inFlight: 1}
+			p.sharedConnections = append(p.sharedConnections, sc)
+			used.add(sc)
+			return &pooledConnection{pool: p, client: dc, shared: sc}, nil
+		}
+
+		// There is no other connection and no further one can be dialed, hence the used ones are shared after all.
+		if p.leastLoaded(used) == nil {
+			if sc := p.leastLoaded(nil); sc != nil && sc.inFlight < p.maxConcurrentRequestsPerConnection {
+				sc.inFlight++
+				p.mu.Unlock()
+				return &pooledConnection{pool: p, client: sc.client, shared: sc}, nil
+			}
+		}
+
+		// All connections fully loaded and max active connections reached, let's wait.
+		if p.cond == nil {
+			p.cond = sync.NewCond(&p.mu)
+		}
+
+		p.logger.Info().Int("active", p.active).Int("maxActive", p.maxActive).Int("maxConcurrentRequestsPerConnection", p.maxConcurrentRequestsPerConnection).Msg("Wait for free capacity on shared connections")
+		*blocked = true
+		p.cond.Wait()
+	}
+}
+
+// leastLoaded returns the shared connection with the least requests in flight, apart from the given used ones.
+// It is not threadsafe. The caller should manage locking the pool.
+func (p *pool) leastLoaded(used *usedConnections) *sharedConnection {
+	var leastLoaded *sharedConnection
+	for _, sc := range p.sharedConnections {
+		if used.contains(sc) {
+			continue
+		}
+		if leastLoaded == nil || sc.inFlight < leastLoaded.inFlight {
+			leastLoaded = sc
+		}
+	}
+	return leastLoaded
+}
+
+// releaseShared marks one request on the given shared connection as finished.
+// It is not threadsafe. The caller should manage locking the pool.
+func (p *pool) releaseShared(sc *sharedConnection) {
+	if sc.inFlight > 0 {
+		sc.inFlight--
+	}
+
+	if sc.inFlight == 0 {
+		sc.idleSince = time.Now()
+
+		if sc.removed || p.closed {
+			sc.client.Close()
+		}
+	}
+
+	if p.cond != nil {
+		p.cond.Broadcast()
+	}
+}
+
+// removeShared removes the given connection from the pool. The connection is closed
+// immediately if it is not in use. Otherwise it is closed when the last request has finished.
+// It is not threadsafe. The caller should manage locking the pool.
+func (p *pool) removeShared(sc *sharedConnection) {
+	if sc.removed {
+		return
+	}
+	sc.removed = true
+
+	remaining := make([]*sharedConnection, 0, len(p.sharedConnections))
+	for _, candidate := range p.sharedConnections {
+		if candidate != sc {
+			remaining = append(remaining, candidate)
+		}
+	}
+	p.sharedConnections = remaining
+	p.releaseConnectionSlot()
+
+	if sc.inFlight == 0 {
+		sc.client.Close()
+	}
+}
+
+// releaseConnectionSlot decrements the number of open connections and alerts waiters.
+// It is not threadsafe. The caller should manage locking the pool.
+func (p *pool) releaseConnectionSlot() {
+	if p.active > 0 {
+		p.active--
+	}
+
+	if p.cond != nil {
+		p.cond.Broadcast()
+	}
+}
+
+// purgeShared removes broken and expired shared connections from the pool.
+// It is not threadsafe. The caller should manage locking the pool.
+func (p *pool) purgeShared() {
+	now := time.Now()
+
+	// iterate over a copy since removeShared modifies the slice
+	sharedConnections := make([]*sharedConnection, len(p.sharedConnections))
+	copy(sharedConnections, p.sharedConnections)
+
+	for _, sc := range sharedConnections {
+		if err := sc.client.LastError(); err != nil {
+			if _, ok := err.(socketClosedByServerError); !ok {
+				p.logger.Info().Err(err).Msgf("(during purge) Remove shared connection from pool due to an error [%s]", err.Error())
+			}
+			p.removeShared(sc)
+			continue
+		}
+
+		if !sc.client.IsConnected() {
+			p.logger.Info().Msg("(during purge) Remove shared connection from pool which is not connected")
+			p.removeShared(sc)
+			continue
+		}
+
+		if p.idleTimeout <= 0 || sc.inFlight > 0 {
+			continue
+		}
+
+		if deadline := sc.idleSince.Add(p.idleTimeout); !deadline.After(now) {
+			p.logger.Info().Time("deadline", deadline).Msg("(during purge) Remove shared connection from pool which is expired")
+			p.removeShared(sc)
+		}
+	}
+}
+
+// checkSharedConnections removes broken and expired shared connections and pings the remaining ones.
+// Connections that failed the ping are removed from the pool.
+func (p *pool) checkSharedConnections() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.purgeShared()
+
+	// copy the connections to be able to ping them without holding the lock
+	sharedConnections := make([]*sharedConnection, len(p.sharedConnections))
+	copy(sharedConnections, p.sharedConnections)
+	p.mu.Unlock()
+
+	var failed []*sharedConnection
+	for _, sc := range sharedConnections {
+		if err := sc.client.Ping(); err != nil {
+			p.logger.Info().Err(err).Msg("(during health check) Remove shared connection from pool which failed the health check")
+			failed = append(failed, sc)
+		}
+	}
+
+	if len(failed) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, sc := range failed {
+		p.removeShared(sc)
+	}
+}
+
+// isSharedConnected returns true in case at least one shared connection is connected.
+func (p *pool) isSharedConnected() bool {
+	p.mu.RLock()
+	sharedConnections := make([]*sharedConnection, len(p.sharedConnections))
+	copy(sharedConnections, p.sharedConnections)
+	p.mu.RUnlock()
+
+	for _, sc := range sharedConnections {
+		if sc.client.IsConnected() {
+			return true
+		}
+	}
+	return false
+}
+
+// closeShared closes all shared connections that are not in use.
+// The remaining ones are closed as soon as their last request has finished.
+// It is not threadsafe. The caller should manage locking the pool.
+func (p *pool) closeShared() {
+	for _, sc := range p.sharedConnections {
+		if sc.inFlight == 0 {
+			sc.client.Close()
+		}
+	}
+}
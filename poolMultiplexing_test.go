@@ -0,0 +1,277 @@
+package gremcos
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/interfaces"
+	mock_interfaces "github.com/supplyon/gremcos/test/mocks/interfaces"
+)
+
+func newMockedMultiplexingPool(t *testing.T, maxActive, maxConcurrentRequests int, clients ...*mock_interfaces.MockQueryExecutor) *pool {
+	dialed := 0
+	clientFactory := func() (interfaces.QueryExecutor, error) {
+		if dialed >= len(clients) {
+			return nil, fmt.Errorf("no more connections")
+		}
+		client := clients[dialed]
+		dialed++
+		return client, nil
+	}
+	p, err := NewPool(clientFactory, maxActive, time.Second*30, zerolog.Nop(), PoolMaxConcurrentRequestsPerConnection(maxConcurrentRequests))
+	require.NoError(t, err)
+	require.True(t, p.isMultiplexing())
+	return p
+}
+
+func TestGetSharedLeastLoaded(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client1 := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	client2 := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	p := newMockedMultiplexingPool(t, 2, 2, client1, client2)
+
+	client1.EXPECT().LastError().Return(nil).AnyTimes()
+	client1.EXPECT().IsConnected().Return(true).AnyTimes()
+	client2.EXPECT().LastError().Return(nil).AnyTimes()
+	client2.EXPECT().IsConnected().Return(true).AnyTimes()
+
+	// WHEN
+	pc1, err := p.Get()
+	require.NoError(t, err)
+	pc2, err := p.Get()
+	require.NoError(t, err)
+	pc3, err := p.Get()
+	require.NoError(t, err)
+	pc4, err := p.Get()
+	require.NoError(t, err)
+
+	// THEN
+	assert.Equal(t, client1, pc1.client)
+	assert.Equal(t, client1, pc2.client, "Expected the connection to be shared")
+	assert.Equal(t, client2, pc3.client, "Expected a new connection once the first one is fully loaded")
+	assert.Equal(t, client2, pc4.client, "Expected the least loaded connection")
+	assert.Equal(t, 2, p.active)
+	assert.Len(t, p.sharedConnections, 2)
+
+	// WHEN
+	pc1.Close()
+	pc5, err := p.Get()
+	require.NoError(t, err)
+
+	// THEN
+	assert.Equal(t, client1, pc5.client, "Expected the least loaded connection")
+	assert.Equal(t, 2, p.sharedConnections[0].inFlight)
+	assert.Equal(t, 2, p.sharedConnections[1].inFlight)
+}
+
+func TestGetSharedWaitsForCapacity(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	p := newMockedMultiplexingPool(t, 1, 1, client)
+
+	client.EXPECT().LastError().Return(nil).AnyTimes()
+	client.EXPECT().IsConnected().Return(true).AnyTimes()
+
+	pc1, err := p.Get()
+	require.NoError(t, err)
+
+	// WHEN
+	obtained := make(chan *pooledConnection)
+	go func() {
+		pc, err := p.Get()
+		assert.NoError(t, err)
+		obtained <- pc
+	}()
+
+	// THEN
+	select {
+	case <-obtained:
+		require.Fail(t, "Expected to wait for free capacity")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	pc1.Close()
+
+	select {
+	case pc2 := <-obtained:
+		assert.Equal(t, client, pc2.client)
+	case <-time.After(time.Second):
+		require.Fail(t, "Expected to obtain the connection after it was released")
+	}
+}
+
+func TestGetSharedAvoidsUsedConnections(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client1 := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	client2 := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	p := newMockedMultiplexingPool(t, 2, 2, client1, client2)
+
+	client1.EXPECT().LastError().Return(nil).AnyTimes()
+	client1.EXPECT().IsConnected().Return(true).AnyTimes()
+	client2.EXPECT().LastError().Return(nil).AnyTimes()
+	client2.EXPECT().IsConnected().Return(true).AnyTimes()
+
+	used := &usedConnections{}
+
+	// WHEN
+	original, err := p.get(callTrace{}, used)
+	require.NoError(t, err)
+	hedged, err := p.get(callTrace{}, used)
+	require.NoError(t, err)
+	unrelated, err := p.get(callTrace{}, nil)
+	require.NoError(t, err)
+
+	// THEN
+	assert.Equal(t, client1, original.client)
+	assert.Equal(t, client2, hedged.client, "Expected a new connection although the first one has free capacity")
+	assert.Equal(t, client1, unrelated.client, "Expected the least loaded connection")
+	assert.Len(t, used.connections, 2)
+}
+
+func TestGetSharedUsesUsedConnectionIfNoOtherIsAvailable(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	p := newMockedMultiplexingPool(t, 1, 2, client)
+
+	client.EXPECT().LastError().Return(nil).AnyTimes()
+	client.EXPECT().IsConnected().Return(true).AnyTimes()
+
+	used := &usedConnections{}
+	original, err := p.get(callTrace{}, used)
+	require.NoError(t, err)
+
+	// WHEN
+	hedged, err := p.get(callTrace{}, used)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, original.client, hedged.client)
+	assert.Equal(t, 2, p.sharedConnections[0].inFlight)
+}
+
+func TestPurgeSharedRemovesBrokenConnections(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientValid := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	clientWithError := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	clientInUse := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	clientExpired := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+
+	valid := &sharedConnection{client: clientValid, idleSince: time.Now()}
+	withError := &sharedConnection{client: clientWithError, idleSince: time.Now()}
+	inUse := &sharedConnection{client: clientInUse, inFlight: 1}
+	expired := &sharedConnection{client: clientExpired, idleSince: time.Now().Add(-time.Minute)}
+	p := &pool{idleTimeout: time.Second * 30, maxConcurrentRequestsPerConnection: 2, active: 4, logger: zerolog.Nop()}
+	p.sharedConnections = []*sharedConnection{valid, withError, inUse, expired}
+
+	clientValid.EXPECT().LastError().Return(nil)
+	clientValid.EXPECT().IsConnected().Return(true)
+	clientWithError.EXPECT().LastError().Return(fmt.Errorf("ERROR"))
+	clientWithError.EXPECT().Close().Return(nil)
+	clientInUse.EXPECT().LastError().Return(nil)
+	clientInUse.EXPECT().IsConnected().Return(false)
+	clientExpired.EXPECT().LastError().Return(nil)
+	clientExpired.EXPECT().IsConnected().Return(true)
+	clientExpired.EXPECT().Close().Return(nil)
+
+	// WHEN
+	p.purgeShared()
+
+	// THEN
+	require.Len(t, p.sharedConnections, 1)
+	assert.Equal(t, valid, p.sharedConnections[0])
+	assert.Equal(t, 1, p.active)
+	assert.True(t, inUse.removed)
+
+	// WHEN - the last request on the removed connection finished
+	clientInUse.EXPECT().Close().Return(nil)
+	(&pooledConnection{pool: p, client: clientInUse, shared: inUse}).Close()
+
+	// THEN
+	assert.Equal(t, 0, inUse.inFlight)
+}
+
+func TestCheckSharedConnections(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientValid := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	clientPingFailed := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+
+	valid := &sharedConnection{client: clientValid, inFlight: 1}
+	pingFailed := &sharedConnection{client: clientPingFailed, idleSince: time.Now()}
+	p := &pool{idleTimeout: time.Second * 30, maxConcurrentRequestsPerConnection: 2, active: 2, logger: zerolog.Nop()}
+	p.sharedConnections = []*sharedConnection{valid, pingFailed}
+
+	clientValid.EXPECT().LastError().Return(nil)
+	clientValid.EXPECT().IsConnected().Return(true)
+	clientValid.EXPECT().Ping().Return(nil)
+	clientPingFailed.EXPECT().LastError().Return(nil)
+	clientPingFailed.EXPECT().IsConnected().Return(true)
+	clientPingFailed.EXPECT().Ping().Return(ErrNoConnection)
+	clientPingFailed.EXPECT().Close().Return(nil)
+
+	// WHEN
+	p.checkSharedConnections()
+
+	// THEN
+	require.Len(t, p.sharedConnections, 1)
+	assert.Equal(t, valid, p.sharedConnections[0])
+	assert.Equal(t, 1, p.active)
+}
+
+func TestCloseMultiplexingPool(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client1 := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	client2 := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	p := newMockedMultiplexingPool(t, 2, 1, client1, client2)
+
+	client1.EXPECT().LastError().Return(nil).AnyTimes()
+	client1.EXPECT().IsConnected().Return(true).AnyTimes()
+
+	pc1, err := p.Get()
+	require.NoError(t, err)
+	pc2, err := p.Get()
+	require.NoError(t, err)
+	pc1.Close()
+	assert.True(t, p.IsConnected())
+
+	// WHEN
+	client1.EXPECT().Close().Return(nil)
+	err = p.Close()
+
+	// THEN - connections in use are closed as soon as they are released
+	assert.NoError(t, err)
+	client2.EXPECT().Close().Return(nil)
+	pc2.Close()
+}
+
+func TestNewPoolFailNegativeMaxConcurrentRequests(t *testing.T) {
+	// GIVEN
+	clientFactory := func() (interfaces.QueryExecutor, error) {
+		return nil, nil
+	}
+
+	// WHEN
+	pool, err := NewPool(clientFactory, 10, time.Second*30, zerolog.Nop(), PoolMaxConcurrentRequestsPerConnection(-1))
+
+	// THEN
+	require.Error(t, err)
+	require.Nil(t, pool)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/supplyon/gremcos/interfaces"
 	mock_interfaces "github.com/supplyon/gremcos/test/mocks/interfaces"
+	"go.uber.org/goleak"
 )
 
 func TestIsConnectedRace(t *testing.T) {
@@ -114,6 +115,8 @@ func TestIsConnectedIdleConnection(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, pConn)
 	// put back the active connection to the idlepool
+	mockedQueryExecutor.EXPECT().LastError().Return(nil)
+	mockedQueryExecutor.EXPECT().IsConnected().Return(true)
 	pConn.Close()
 	mockedQueryExecutor.EXPECT().IsConnected().Return(true)
 
@@ -143,6 +146,8 @@ func TestIsConnectedIdleAndFaulty(t *testing.T) {
 	require.NotNil(t, pConn2)
 
 	// put back the active connections to the idlepool
+	mockedQueryExecutor.EXPECT().LastError().Return(nil).Times(2)
+	mockedQueryExecutor.EXPECT().IsConnected().Return(true).Times(2)
 	pConn1.Close()
 	pConn2.Close()
 	mockedQueryExecutor.EXPECT().IsConnected().Return(false)
@@ -380,8 +385,13 @@ func TestPurgeOnClosedConnection(t *testing.T) {
 
 func TestPooledConnectionClose(t *testing.T) {
 	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedQueryExecutor := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	mockedQueryExecutor.EXPECT().LastError().Return(nil)
+	mockedQueryExecutor.EXPECT().IsConnected().Return(true)
 	pool := &pool{}
-	pc := &pooledConnection{pool: pool}
+	pc := &pooledConnection{pool: pool, client: mockedQueryExecutor}
 	assert.Len(t, pool.idleConnections, 0, "Expected 0 idle connections")
 
 	// WHEN
@@ -394,6 +404,28 @@ func TestPooledConnectionClose(t *testing.T) {
 	assert.False(t, idled.idleSince.IsZero(), "Expected an idled time")
 }
 
+func TestPooledConnectionCloseBrokenConnection(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	faulty := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	faulty.EXPECT().LastError().Return(fmt.Errorf("ping failed"))
+	faulty.EXPECT().Close().Return(nil)
+	disconnected := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	disconnected.EXPECT().LastError().Return(nil)
+	disconnected.EXPECT().IsConnected().Return(false)
+	disconnected.EXPECT().Close().Return(nil)
+	pool := &pool{active: 2}
+
+	// WHEN
+	(&pooledConnection{pool: pool, client: faulty}).Close()
+	(&pooledConnection{pool: pool, client: disconnected}).Close()
+
+	// THEN
+	assert.Len(t, pool.idleConnections, 0, "Expected broken connections to be closed instead of pooled")
+	assert.Equal(t, 0, pool.active)
+}
+
 func TestFirst(t *testing.T) {
 	// GIVEN
 	mockCtrl := gomock.NewController(t)
@@ -455,6 +487,8 @@ func TestGetAndDial(t *testing.T) {
 	assert.Equal(t, 1, pool.active, "Expected 1 active connections")
 
 	// Close the connection and ensure it was returned to the idle pool
+	mockedQueryExecutor2.EXPECT().LastError().Return(nil)
+	mockedQueryExecutor2.EXPECT().IsConnected().Return(true)
 	conn.Close()
 
 	assert.Len(t, pool.idleConnections, 1, "Expected connection to be returned to idle pool")
@@ -477,3 +511,264 @@ func newMockedPool(mockCtrl *gomock.Controller) (*mock_interfaces.MockQueryExecu
 	pool, err := NewPool(clientFactory, 2, time.Second*30, logger)
 	return mockedQueryExecutor, pool, err
 }
+
+func TestHealthCheckRemovesFailedConnections(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedQueryExecutorValid := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	mockedQueryExecutorPingFailed := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	mockedQueryExecutorWithError := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+
+	n := time.Now()
+	p := &pool{idleTimeout: time.Second * 30, logger: zerolog.Nop()}
+	valid := &idleConnection{idleSince: n, pc: &pooledConnection{pool: p, client: mockedQueryExecutorValid}}
+	pingFailed := &idleConnection{idleSince: n, pc: &pooledConnection{pool: p, client: mockedQueryExecutorPingFailed}}
+	withError := &idleConnection{idleSince: n, pc: &pooledConnection{pool: p, client: mockedQueryExecutorWithError}}
+	p.idleConnections = []*idleConnection{valid, pingFailed, withError}
+
+	// purge
+	mockedQueryExecutorValid.EXPECT().LastError().Return(nil).Times(2)
+	mockedQueryExecutorValid.EXPECT().IsConnected().Return(true)
+	mockedQueryExecutorPingFailed.EXPECT().LastError().Return(nil).Times(2)
+	mockedQueryExecutorPingFailed.EXPECT().IsConnected().Return(true)
+	mockedQueryExecutorWithError.EXPECT().LastError().Return(nil)
+	mockedQueryExecutorWithError.EXPECT().IsConnected().Return(true)
+	// health check
+	mockedQueryExecutorValid.EXPECT().Ping().Return(nil)
+	mockedQueryExecutorPingFailed.EXPECT().Ping().Return(ErrNoConnection)
+	mockedQueryExecutorPingFailed.EXPECT().Close().Return(nil)
+	mockedQueryExecutorWithError.EXPECT().LastError().Return(fmt.Errorf("ERROR"))
+	mockedQueryExecutorWithError.EXPECT().Close().Return(nil)
+
+	// WHEN
+	p.checkIdleConnections()
+
+	// THEN
+	require.Len(t, p.idleConnections, 1, "Expected 1 idle connection after health check")
+	assert.Equal(t, valid, p.idleConnections[0], "Expected the valid connection to remain in idle pool")
+}
+
+func TestHealthCheckRemovesExpiredConnections(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedQueryExecutorExpired := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+
+	p := &pool{idleTimeout: time.Second * 30, logger: zerolog.Nop()}
+	expired := &idleConnection{idleSince: time.Now().Add(-time.Minute), pc: &pooledConnection{pool: p, client: mockedQueryExecutorExpired}}
+	p.idleConnections = []*idleConnection{expired}
+
+	mockedQueryExecutorExpired.EXPECT().LastError().Return(nil)
+	mockedQueryExecutorExpired.EXPECT().IsConnected().Return(true)
+	mockedQueryExecutorExpired.EXPECT().Close().Return(nil)
+
+	// WHEN
+	p.checkIdleConnections()
+
+	// THEN
+	assert.Empty(t, p.idleConnections, "Expected the expired connection to be removed")
+}
+
+func TestHealthCheckWorker(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedQueryExecutor := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	clientFactory := func() (interfaces.QueryExecutor, error) {
+		return mockedQueryExecutor, nil
+	}
+	pinged := make(chan struct{})
+	var once sync.Once
+	mockedQueryExecutor.EXPECT().LastError().Return(nil).AnyTimes()
+	mockedQueryExecutor.EXPECT().IsConnected().Return(true).AnyTimes()
+	mockedQueryExecutor.EXPECT().Ping().DoAndReturn(func() error {
+		once.Do(func() { close(pinged) })
+		return fmt.Errorf("ping failed")
+	}).MinTimes(1)
+	mockedQueryExecutor.EXPECT().Close().Return(nil)
+
+	pool, err := NewPool(clientFactory, 2, time.Second*30, zerolog.Nop(), PoolHealthCheckInterval(time.Millisecond*10))
+	require.NoError(t, err)
+
+	// WHEN
+	pc, err := pool.Get()
+	require.NoError(t, err)
+	pc.Close()
+
+	select {
+	case <-pinged:
+	case <-time.After(time.Second):
+		require.Fail(t, "Expected the idle connection to be pinged")
+	}
+	// THEN - the worker is stopped on close (verified by goleak)
+	assert.NoError(t, pool.Close())
+	assert.Empty(t, pool.idleConnections)
+}
+
+func TestNewPoolFailNegativeHealthCheckInterval(t *testing.T) {
+	// GIVEN
+	clientFactory := func() (interfaces.QueryExecutor, error) {
+		return nil, nil
+	}
+
+	// WHEN
+	pool, err := NewPool(clientFactory, 10, time.Second*30, zerolog.Nop(), PoolHealthCheckInterval(-time.Second))
+
+	// THEN
+	require.Error(t, err)
+	require.Nil(t, pool)
+}
+
+func TestGetValidateOnBorrow(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedQueryExecutorBroken := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	mockedQueryExecutorNew := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+
+	p := &pool{idleTimeout: time.Second * 30, maxActive: 1, validateOnBorrow: true, logger: zerolog.Nop()}
+	broken := &idleConnection{idleSince: time.Now(), pc: &pooledConnection{pool: p, client: mockedQueryExecutorBroken}}
+	p.idleConnections = []*idleConnection{broken}
+	p.createQueryExecutor = func() (interfaces.QueryExecutor, error) {
+		return mockedQueryExecutorNew, nil
+	}
+
+	mockedQueryExecutorBroken.EXPECT().LastError().Return(nil).Times(2)
+	mockedQueryExecutorBroken.EXPECT().IsConnected().Return(true)
+	mockedQueryExecutorBroken.EXPECT().Ping().Return(ErrNoConnection)
+	mockedQueryExecutorBroken.EXPECT().Close().Return(nil)
+
+	// WHEN
+	conn, err := p.Get()
+
+	// THEN
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+	assert.Equal(t, mockedQueryExecutorNew, conn.client, "Expected a newly dialed connection")
+	assert.Empty(t, p.idleConnections)
+	assert.Equal(t, 1, p.active, "Expected 1 active connection")
+}
+
+func newRetryOnNewConnectionResponse() interfaces.Response {
+	return interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusServerError,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code": 1007,
+			},
+		},
+	}
+}
+
+func TestExecuteEvictsConnection(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	client1 := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	client2 := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	clients := []interfaces.QueryExecutor{client1, client2}
+	clientFactory := func() (interfaces.QueryExecutor, error) {
+		client := clients[0]
+		clients = clients[1:]
+		return client, nil
+	}
+	pool, err := NewPool(clientFactory, 1, time.Second*30, zerolog.Nop(), PoolMetrics(metrics))
+	require.NoError(t, err)
+
+	client1.EXPECT().Execute("g.V()").Return([]interfaces.Response{newRetryOnNewConnectionResponse()}, nil)
+	client1.EXPECT().Close().Return(nil)
+	metricMocks.connectionEvictionsTotal.EXPECT().Inc()
+	client2.EXPECT().LastError().Return(nil).AnyTimes()
+	client2.EXPECT().IsConnected().Return(true).AnyTimes()
+	client2.EXPECT().Execute("g.V()").Return([]interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusSuccess}}}, nil).Times(2)
+
+	// WHEN
+	_, err = pool.Execute("g.V()")
+	require.NoError(t, err)
+
+	// THEN
+	assert.Equal(t, 0, pool.active)
+	assert.Len(t, pool.idleConnections, 0, "Expected the connection to be evicted")
+
+	// WHEN - the retry is done on a new connection which is kept afterwards
+	_, err = pool.Execute("g.V()")
+	require.NoError(t, err)
+	_, err = pool.Execute("g.V()")
+	require.NoError(t, err)
+
+	// THEN
+	require.Len(t, pool.idleConnections, 1)
+	assert.Equal(t, client2, pool.idleConnections[0].pc.client)
+}
+
+func TestExecuteEvictsSharedConnection(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	client := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	clientFactory := func() (interfaces.QueryExecutor, error) {
+		return client, nil
+	}
+	pool, err := NewPool(clientFactory, 1, time.Second*30, zerolog.Nop(), PoolMetrics(metrics), PoolMaxConcurrentRequestsPerConnection(2))
+	require.NoError(t, err)
+
+	client.EXPECT().ExecuteWithBindings("g.V()", nil, nil).Return([]interfaces.Response{newRetryOnNewConnectionResponse()}, nil)
+	client.EXPECT().Close().Return(nil)
+	metricMocks.connectionEvictionsTotal.EXPECT().Inc()
+
+	// WHEN
+	_, err = pool.ExecuteWithBindings("g.V()", nil, nil)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, 0, pool.active)
+	assert.Len(t, pool.sharedConnections, 0, "Expected the connection to be evicted")
+}
+
+func TestExecuteAsyncEvictsConnection(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	client := mock_interfaces.NewMockQueryExecutor(mockCtrl)
+	clientFactory := func() (interfaces.QueryExecutor, error) {
+		return client, nil
+	}
+	pool, err := NewPool(clientFactory, 1, time.Second*30, zerolog.Nop(), PoolMetrics(metrics))
+	require.NoError(t, err)
+
+	client.EXPECT().ExecuteAsync("g.V()", gomock.Any()).DoAndReturn(func(query string, responseChannel chan interfaces.AsyncResponse) error {
+		go func() {
+			responseChannel <- interfaces.AsyncResponse{Response: interfaces.Response{Status: interfaces.Status{Code: interfaces.StatusPartialContent}}}
+			responseChannel <- interfaces.AsyncResponse{Response: newRetryOnNewConnectionResponse()}
+			close(responseChannel)
+		}()
+		return nil
+	})
+	client.EXPECT().Close().Return(nil)
+	metricMocks.connectionEvictionsTotal.EXPECT().Inc()
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	err = pool.ExecuteAsync("g.V()", responseChannel)
+	require.NoError(t, err)
+
+	responses := make([]interfaces.AsyncResponse, 0)
+	for resp := range responseChannel {
+		responses = append(responses, resp)
+	}
+
+	// THEN
+	assert.Len(t, responses, 2)
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	assert.Equal(t, 0, pool.active)
+	assert.Len(t, pool.idleConnections, 0, "Expected the connection to be evicted")
+}
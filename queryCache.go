@@ -0,0 +1,357 @@
+package gremcos
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+const (
+	// labelTagPrefix is the prefix of the tags that represent a vertex or edge label
+	labelTagPrefix = "label:"
+	// vertexTagPrefix is the prefix of the tags that represent a vertex id
+	vertexTagPrefix = "vertex:"
+	// unlabelledTag is the tag of cached queries that don't refer to a label, they are invalidated by every mutation
+	unlabelledTag = labelTagPrefix + "*"
+	// traversingTag is the tag of cached queries that traverse edges. They might reach elements of any label,
+	// hence they are invalidated by every mutation.
+	traversingTag = "traversal:*"
+)
+
+var (
+	// labelSteps matches the steps that refer to vertex or edge labels and captures their arguments
+	labelSteps = regexp.MustCompile(`\b(?:hasLabel|addV|addE|out|in|both|outE|inE|bothE)\(([^()]*)\)`)
+	// traversalSteps matches the steps that traverse from vertices to edges or neighbours and vice versa
+	traversalSteps = regexp.MustCompile(`\b(?:out|in|both|outE|inE|bothE|outV|inV|bothV|otherV)\(`)
+	// vertexSteps matches the V step and captures its arguments (the vertex ids)
+	vertexSteps = regexp.MustCompile(`\bV\(([^()]*)\)`)
+	// quotedLiteral matches a single or double quoted string literal and captures its content
+	quotedLiteral = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"|'((?:[^'\\]|\\.)*)'`)
+)
+
+// QueryCache is a read-through cache for the responses of read-only queries, keyed by the query and its bindings.
+// The cached queries are tagged by the labels and vertex ids they refer to. Mutations that are executed via the same
+// cosmos connector invalidate the cached queries that refer to the same labels. Mutations without label (e.g. g.V("id").drop())
+// flush the whole cache. Since a query that traverses edges (e.g. out, inE, otherV) can reach elements of any label,
+// such queries are invalidated by every mutation. Queries that reach changed elements otherwise, e.g. via the ids
+// of vertices stored in properties, are not detected. Neither are changes done by other processes, hence the ttl
+// should be chosen accordingly and CacheTags and Invalidate can be used for such dependencies.
+type QueryCache struct {
+	// ttl is the time a response is kept in the cache
+	ttl time.Duration
+	// maxEntries is the maximum number of cached queries, the least recently used one is evicted if exceeded
+	maxEntries int
+
+	// metrics is used to count cache hits and misses (optional)
+	metrics *Metrics
+
+	mux sync.Mutex
+	// entries are the cached queries by key
+	entries map[string]*list.Element
+	// lru contains the cached queries, the most recently used one is at the front
+	lru *list.List
+	// tagged contains the keys of the cached queries per tag
+	tagged map[string]map[string]struct{}
+	// generation is incremented on each invalidation, responses of queries started in an older generation are not cached
+	generation uint64
+}
+
+// queryCacheEntry is one cached query
+type queryCacheEntry struct {
+	key       string
+	responses []interfaces.Response
+	expires   time.Time
+	tags      []string
+}
+
+// NewQueryCache creates a cache that keeps the responses of read-only queries for the given ttl.
+// At most maxEntries queries are cached. The cache is enabled via WithQueryCache.
+//
+//	cache := gremcos.NewQueryCache(time.Minute, 1000)
+//	cosmos, err := gremcos.New(host, gremcos.WithQueryCache(cache))
+//	...
+//	cache.Invalidate(gremcos.LabelTag("country"))
+func NewQueryCache(ttl time.Duration, maxEntries int) *QueryCache {
+	return &QueryCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		tagged:     make(map[string]map[string]struct{}),
+	}
+}
+
+// WithQueryCache enables caching of the responses of read-only queries using the given cache.
+// The cache is applied after all interceptors (see WithInterceptors), hence it sees the requests as modified by them.
+// Asynchronous requests (ExecuteAsync) are not cached, but mutations executed asynchronously invalidate the cache as well
+// as soon as all their responses were received.
+func WithQueryCache(cache *QueryCache) Option {
+	return func(c *cosmosImpl) {
+		c.queryCache = cache
+	}
+}
+
+// CacheTags adds the given tags to the cached responses of the request, which allows to invalidate them via QueryCache.Invalidate.
+func CacheTags(tags ...string) RequestOption {
+	return func(r *requestOptions) {
+		r.cacheTags = append(r.cacheTags, tags...)
+	}
+}
+
+// BypassCache executes the request without looking up or storing its responses in the query cache.
+func BypassCache() RequestOption {
+	return func(r *requestOptions) {
+		r.bypassCache = true
+	}
+}
+
+// LabelTag returns the tag of cached queries that refer to the given vertex or edge label
+func LabelTag(label string) string {
+	return labelTagPrefix + label
+}
+
+// VertexTag returns the tag of cached queries that refer to the vertex with the given id (e.g. g.V("id"))
+func VertexTag(id string) string {
+	return vertexTagPrefix + id
+}
+
+// validate returns an error in case the cache is not configured properly
+func (q *QueryCache) validate() error {
+	if q == nil {
+		return nil
+	}
+	if q.ttl <= 0 {
+		return fmt.Errorf("the ttl of the query cache has to be >0")
+	}
+	if q.maxEntries <= 0 {
+		return fmt.Errorf("the maximum number of entries of the query cache has to be >0")
+	}
+	return nil
+}
+
+// Len returns the number of cached queries
+func (q *QueryCache) Len() int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return len(q.entries)
+}
+
+// Invalidate removes all cached queries that have one of the given tags (see LabelTag, VertexTag and CacheTags)
+func (q *QueryCache) Invalidate(tags ...string) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	q.generation++
+	for _, tag := range tags {
+		for key := range q.tagged[tag] {
+			if element, ok := q.entries[key]; ok {
+				q.remove(element)
+			}
+		}
+	}
+}
+
+// Flush removes all cached queries
+func (q *QueryCache) Flush() {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	q.generation++
+	q.entries = make(map[string]*list.Element)
+	q.lru.Init()
+	q.tagged = make(map[string]map[string]struct{})
+}
+
+// intercept is the interceptor that answers read-only queries from the cache and invalidates the cache on mutations
+func (q *QueryCache) intercept(next ExecuteFunc) ExecuteFunc {
+	return func(request *Request) ([]interfaces.Response, error) {
+		reqOptions := newRequestOptions(request.Options...)
+
+		if !isReadOnlyQuery(request.Query) {
+			if request.IsAsync() {
+				// the asynchronous mutation is only done as soon as the response channel is closed
+				request.ResponseChannel = q.invalidateWhenDone(request.Query, request.ResponseChannel)
+				return next(request)
+			}
+			defer q.invalidateMutation(request.Query)
+			return next(request)
+		}
+
+		if request.IsAsync() || reqOptions.bypassCache {
+			return next(request)
+		}
+
+		key, err := cacheKey(request)
+		if err != nil {
+			return next(request)
+		}
+
+		responses, generation, hit := q.get(key)
+		if hit {
+			q.count(true)
+			return responses, nil
+		}
+		q.count(false)
+
+		responses, err = next(request)
+		if err != nil || extractFirstError(responses) != nil {
+			return responses, err
+		}
+
+		q.put(key, responses, append(queryTags(request.Query), reqOptions.cacheTags...), generation)
+		return responses, err
+	}
+}
+
+// count updates the hit or miss metric
+func (q *QueryCache) count(hit bool) {
+	if q.metrics == nil {
+		return
+	}
+	if hit {
+		q.metrics.queryCacheHitsTotal.Inc()
+		return
+	}
+	q.metrics.queryCacheMissesTotal.Inc()
+}
+
+// get returns the cached responses for the given key and the current generation of the cache
+func (q *QueryCache) get(key string) (responses []interfaces.Response, generation uint64, hit bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	element, ok := q.entries[key]
+	if !ok {
+		return nil, q.generation, false
+	}
+
+	entry := element.Value.(*queryCacheEntry)
+	if time.Now().After(entry.expires) {
+		q.remove(element)
+		return nil, q.generation, false
+	}
+
+	q.lru.MoveToFront(element)
+	return append([]interfaces.Response{}, entry.responses...), q.generation, true
+}
+
+// put caches the given responses, unless the cache was invalidated since the query was started (given generation)
+func (q *QueryCache) put(key string, responses []interfaces.Response, tags []string, generation uint64) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	if generation != q.generation {
+		return
+	}
+
+	if element, ok := q.entries[key]; ok {
+		q.remove(element)
+	}
+
+	entry := &queryCacheEntry{key: key, responses: responses, expires: time.Now().Add(q.ttl), tags: tags}
+	q.entries[key] = q.lru.PushFront(entry)
+	for _, tag := range tags {
+		if q.tagged[tag] == nil {
+			q.tagged[tag] = make(map[string]struct{})
+		}
+		q.tagged[tag][key] = struct{}{}
+	}
+
+	for len(q.entries) > q.maxEntries {
+		q.remove(q.lru.Back())
+	}
+}
+
+// remove removes the given cached query.
+// It is not threadsafe. The caller should manage locking the cache.
+func (q *QueryCache) remove(element *list.Element) {
+	entry := element.Value.(*queryCacheEntry)
+	q.lru.Remove(element)
+	delete(q.entries, entry.key)
+	for _, tag := range entry.tags {
+		delete(q.tagged[tag], entry.key)
+		if len(q.tagged[tag]) == 0 {
+			delete(q.tagged, tag)
+		}
+	}
+}
+
+// invalidateWhenDone returns a channel that forwards the responses of the given asynchronous mutation to the given channel.
+// The cache is invalidated as soon as the returned channel is closed, before the given channel is closed.
+func (q *QueryCache) invalidateWhenDone(query string, responseChannel chan interfaces.AsyncResponse) chan interfaces.AsyncResponse {
+	forwarded := make(chan interfaces.AsyncResponse, cap(responseChannel))
+	go func() {
+		defer close(responseChannel)
+		for response := range forwarded {
+			responseChannel <- response
+		}
+		q.invalidateMutation(query)
+	}()
+	return forwarded
+}
+
+// invalidateMutation removes the cached queries that might be affected by the given mutation.
+// These are the ones referring to the same labels or vertices, the ones without label and the ones traversing edges.
+// In case the labels touched by the mutation are unknown, the whole cache is flushed.
+func (q *QueryCache) invalidateMutation(query string) {
+	tags := queryTags(query)
+	if tags[0] == unlabelledTag {
+		q.Flush()
+		return
+	}
+	q.Invalidate(append(tags, unlabelledTag, traversingTag)...)
+}
+
+// queryTags returns the tags of the labels and vertex ids the given query refers to.
+// In case the query refers to no label, the first tag is the unlabelledTag. Queries traversing edges get the traversingTag.
+func queryTags(query string) []string {
+	var tags []string
+	for _, match := range labelSteps.FindAllStringSubmatch(query, -1) {
+		for _, label := range quotedLiterals(match[1]) {
+			tags = append(tags, LabelTag(label))
+		}
+	}
+
+	if len(tags) == 0 {
+		tags = append(tags, unlabelledTag)
+	}
+
+	for _, match := range vertexSteps.FindAllStringSubmatch(query, -1) {
+		for _, id := range quotedLiterals(match[1]) {
+			tags = append(tags, VertexTag(id))
+		}
+	}
+
+	if traversalSteps.MatchString(query) {
+		tags = append(tags, traversingTag)
+	}
+	return tags
+}
+
+// quotedLiterals returns the contents of the string literals in the given arguments of a step
+func quotedLiterals(arguments string) []string {
+	var literals []string
+	for _, match := range quotedLiteral.FindAllStringSubmatch(arguments, -1) {
+		if len(match[1]) > 0 {
+			literals = append(literals, match[1])
+			continue
+		}
+		literals = append(literals, match[2])
+	}
+	return literals
+}
+
+// cacheKey returns the key of the given request consisting of the query and its bindings
+func cacheKey(request *Request) (string, error) {
+	// maps are marshalled with sorted keys, hence the key is deterministic
+	bindings, err := json.Marshal([]map[string]interface{}{request.Bindings, request.Rebindings})
+	if err != nil {
+		return "", err
+	}
+	return request.Query + "\x00" + string(bindings), nil
+}
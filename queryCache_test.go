@@ -0,0 +1,358 @@
+package gremcos
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/supplyon/gremcos/api"
+	"github.com/supplyon/gremcos/interfaces"
+	mock_interfaces "github.com/supplyon/gremcos/test/mocks/interfaces"
+	mock_metrics "github.com/supplyon/gremcos/test/mocks/metrics"
+)
+
+func newQueryCacheTestCosmos(t *testing.T, mockCtrl *gomock.Controller, cache *QueryCache) (*cosmosImpl, *mock_interfaces.MockQueryExecutor) {
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl)
+	WithQueryCache(cache)(cosmos)
+	cache.metrics = cosmos.metrics
+	cosmos.interceptors = append(cosmos.interceptors, cache.intercept)
+	return cosmos, queryExecutor
+}
+
+func successResponses(requestID string) []interfaces.Response {
+	return []interfaces.Response{{RequestID: requestID, Status: interfaces.Status{Code: interfaces.StatusSuccess}}}
+}
+
+func TestQueryCache_Validate(t *testing.T) {
+	var disabled *QueryCache
+	assert.NoError(t, disabled.validate())
+	assert.NoError(t, NewQueryCache(time.Minute, 10).validate())
+	assert.Error(t, NewQueryCache(0, 10).validate())
+	assert.Error(t, NewQueryCache(time.Minute, 0).validate())
+}
+
+func TestNewWithInvalidQueryCache(t *testing.T) {
+	// GIVEN
+	cache := NewQueryCache(0, 10)
+
+	// WHEN
+	cosmos, err := New("ws://host", WithQueryCache(cache))
+
+	// THEN
+	assert.Error(t, err)
+	assert.Nil(t, cosmos)
+}
+
+func TestQueryCache_Hit(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cache := NewQueryCache(time.Minute, 10)
+	cosmos, queryExecutor := newQueryCacheTestCosmos(t, mockCtrl, cache)
+	hits := mock_metrics.NewMockCounter(mockCtrl)
+	hits.EXPECT().Inc()
+	misses := mock_metrics.NewMockCounter(mockCtrl)
+	misses.EXPECT().Inc()
+	cosmos.metrics.queryCacheHitsTotal = hits
+	cosmos.metrics.queryCacheMissesTotal = misses
+	query := api.NewGraph("g").V().HasLabel("user")
+	queryExecutor.EXPECT().Execute(query.String()).Return(successResponses("1"), nil)
+
+	// WHEN
+	responses1, err1 := cosmos.ExecuteQuery(query)
+	responses2, err2 := cosmos.ExecuteQuery(query)
+
+	// THEN
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	assert.Equal(t, successResponses("1"), responses1)
+	assert.Equal(t, successResponses("1"), responses2)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestQueryCache_KeyContainsBindings(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cosmos, queryExecutor := newQueryCacheTestCosmos(t, mockCtrl, NewQueryCache(time.Minute, 10))
+	query := "g.V().has('name',name)"
+	queryExecutor.EXPECT().ExecuteWithBindings(query, map[string]interface{}{"name": "alice"}, nil).Return(successResponses("alice"), nil)
+	queryExecutor.EXPECT().ExecuteWithBindings(query, map[string]interface{}{"name": "bob"}, nil).Return(successResponses("bob"), nil)
+
+	// WHEN
+	alice, err1 := cosmos.ExecuteWithBindings(query, map[string]interface{}{"name": "alice"}, nil)
+	bob, err2 := cosmos.ExecuteWithBindings(query, map[string]interface{}{"name": "bob"}, nil)
+	aliceCached, err3 := cosmos.ExecuteWithBindings(query, map[string]interface{}{"name": "alice"}, nil)
+
+	// THEN
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	require.NoError(t, err3)
+	assert.Equal(t, "alice", alice[0].RequestID)
+	assert.Equal(t, "bob", bob[0].RequestID)
+	assert.Equal(t, "alice", aliceCached[0].RequestID)
+}
+
+func TestQueryCache_MutationInvalidatesSameLabel(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cache := NewQueryCache(time.Minute, 10)
+	cosmos, queryExecutor := newQueryCacheTestCosmos(t, mockCtrl, cache)
+	g := api.NewGraph("g")
+	users := g.V().HasLabel("user")
+	countries := g.V().HasLabel("country")
+	all := g.V()
+	mutation := g.AddV("user").Property("name", "alice")
+
+	queryExecutor.EXPECT().Execute(users.String()).Times(2).Return(successResponses("users"), nil)
+	queryExecutor.EXPECT().Execute(countries.String()).Times(1).Return(successResponses("countries"), nil)
+	queryExecutor.EXPECT().Execute(all.String()).Times(2).Return(successResponses("all"), nil)
+	queryExecutor.EXPECT().Execute(mutation.String()).Return(successResponses("mutation"), nil)
+
+	_, err := cosmos.ExecuteQuery(users)
+	require.NoError(t, err)
+	_, err = cosmos.ExecuteQuery(countries)
+	require.NoError(t, err)
+	_, err = cosmos.ExecuteQuery(all)
+	require.NoError(t, err)
+	require.Equal(t, 3, cache.Len())
+
+	// WHEN
+	_, err = cosmos.ExecuteQuery(mutation)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, 1, cache.Len())
+	_, err = cosmos.ExecuteQuery(users)
+	assert.NoError(t, err)
+	_, err = cosmos.ExecuteQuery(countries)
+	assert.NoError(t, err)
+	_, err = cosmos.ExecuteQuery(all)
+	assert.NoError(t, err)
+}
+
+func TestQueryCache_MutationWithoutLabelFlushes(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cache := NewQueryCache(time.Minute, 10)
+	cosmos, queryExecutor := newQueryCacheTestCosmos(t, mockCtrl, cache)
+	g := api.NewGraph("g")
+	countries := g.V().HasLabel("country")
+	mutation := g.VByStr("abc").Drop()
+	queryExecutor.EXPECT().Execute(countries.String()).Return(successResponses("countries"), nil)
+	queryExecutor.EXPECT().Execute(mutation.String()).Return(nil, fmt.Errorf("failed"))
+
+	_, err := cosmos.ExecuteQuery(countries)
+	require.NoError(t, err)
+	require.Equal(t, 1, cache.Len())
+
+	// WHEN
+	_, err = cosmos.ExecuteQuery(mutation)
+
+	// THEN
+	assert.Error(t, err)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestQueryCache_MutationInvalidatesTraversals(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cache := NewQueryCache(time.Minute, 10)
+	cosmos, queryExecutor := newQueryCacheTestCosmos(t, mockCtrl, cache)
+	g := api.NewGraph("g")
+	residents := g.V().HasLabel("country").In("lives_in")
+	countries := g.V().HasLabel("country")
+	mutation := g.V().HasLabel("user").Property("name", "alice")
+
+	queryExecutor.EXPECT().Execute(residents.String()).Times(2).Return(successResponses("residents"), nil)
+	queryExecutor.EXPECT().Execute(countries.String()).Times(1).Return(successResponses("countries"), nil)
+	queryExecutor.EXPECT().Execute(mutation.String()).Return(successResponses("mutation"), nil)
+
+	_, err := cosmos.ExecuteQuery(residents)
+	require.NoError(t, err)
+	_, err = cosmos.ExecuteQuery(countries)
+	require.NoError(t, err)
+	require.Equal(t, 2, cache.Len())
+
+	// WHEN
+	_, err = cosmos.ExecuteQuery(mutation)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, 1, cache.Len())
+	_, err = cosmos.ExecuteQuery(residents)
+	assert.NoError(t, err)
+	_, err = cosmos.ExecuteQuery(countries)
+	assert.NoError(t, err)
+}
+
+func TestQueryCache_AsyncMutationInvalidatesWhenDone(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cache := NewQueryCache(time.Minute, 10)
+	cosmos, queryExecutor := newQueryCacheTestCosmos(t, mockCtrl, cache)
+	users := "g.V().hasLabel('user')"
+	mutation := "g.V().hasLabel('user').drop()"
+	queryExecutor.EXPECT().Execute(users).Return(successResponses("users"), nil)
+
+	release := make(chan struct{})
+	queryExecutor.EXPECT().ExecuteAsync(mutation, gomock.Any()).DoAndReturn(func(q string, resp chan interfaces.AsyncResponse) error {
+		go func() {
+			resp <- interfaces.AsyncResponse{Response: successResponses("mutation")[0]}
+			<-release
+			close(resp)
+		}()
+		return nil
+	})
+
+	_, err := cosmos.Execute(users)
+	require.NoError(t, err)
+	require.Equal(t, 1, cache.Len())
+
+	// WHEN
+	responseChannel := make(chan interfaces.AsyncResponse, 10)
+	err = cosmos.ExecuteAsync(mutation, responseChannel)
+	require.NoError(t, err)
+	lenWhileStreaming := cache.Len()
+	close(release)
+
+	var responses []interfaces.Response
+	for response := range responseChannel {
+		responses = append(responses, response.Response)
+	}
+
+	// THEN
+	assert.Equal(t, 1, lenWhileStreaming)
+	assert.Equal(t, successResponses("mutation"), responses)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestQueryCache_ErrorsAndBypassAreNotCached(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cache := NewQueryCache(time.Minute, 10)
+	cosmos, queryExecutor := newQueryCacheTestCosmos(t, mockCtrl, cache)
+	failure := []interfaces.Response{{Status: interfaces.Status{Code: interfaces.StatusServerError}}}
+	queryExecutor.EXPECT().Execute("g.V().hasLabel('a')").Return(failure, nil)
+	queryExecutor.EXPECT().Execute("g.V().hasLabel('b')").Return(nil, fmt.Errorf("failed"))
+	queryExecutor.EXPECT().Execute("g.V().hasLabel('c')").Return(successResponses("c"), nil)
+
+	// WHEN
+	_, err1 := cosmos.Execute("g.V().hasLabel('a')")
+	_, err2 := cosmos.Execute("g.V().hasLabel('b')")
+	_, err3 := cosmos.Execute("g.V().hasLabel('c')", BypassCache())
+
+	// THEN
+	assert.Error(t, err1)
+	assert.Error(t, err2)
+	assert.NoError(t, err3)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestQueryCache_Invalidate(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cache := NewQueryCache(time.Minute, 10)
+	cosmos, queryExecutor := newQueryCacheTestCosmos(t, mockCtrl, cache)
+	queryExecutor.EXPECT().Execute(gomock.Any()).Times(3).Return(successResponses("1"), nil)
+
+	_, err := cosmos.Execute("g.V('abc')")
+	require.NoError(t, err)
+	_, err = cosmos.Execute("g.V().hasLabel('user')", CacheTags("users"))
+	require.NoError(t, err)
+	_, err = cosmos.Execute("g.V().hasLabel('country')")
+	require.NoError(t, err)
+	require.Equal(t, 3, cache.Len())
+
+	// WHEN + THEN
+	cache.Invalidate(VertexTag("abc"))
+	assert.Equal(t, 2, cache.Len())
+	cache.Invalidate("users", LabelTag("unknown"))
+	assert.Equal(t, 1, cache.Len())
+	cache.Invalidate(LabelTag("country"))
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestQueryCache_TTL(t *testing.T) {
+	// GIVEN
+	cache := NewQueryCache(time.Millisecond*10, 10)
+	_, generation, _ := cache.get("key")
+	cache.put("key", successResponses("1"), nil, generation)
+
+	// WHEN
+	_, _, hitBefore := cache.get("key")
+	time.Sleep(time.Millisecond * 20)
+	_, _, hitAfter := cache.get("key")
+
+	// THEN
+	assert.True(t, hitBefore)
+	assert.False(t, hitAfter)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestQueryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	// GIVEN
+	cache := NewQueryCache(time.Minute, 2)
+	cache.put("a", successResponses("a"), []string{LabelTag("a")}, 0)
+	cache.put("b", successResponses("b"), []string{LabelTag("b")}, 0)
+	_, _, _ = cache.get("a")
+
+	// WHEN
+	cache.put("c", successResponses("c"), []string{LabelTag("c")}, 0)
+
+	// THEN
+	assert.Equal(t, 2, cache.Len())
+	_, _, hitA := cache.get("a")
+	_, _, hitB := cache.get("b")
+	_, _, hitC := cache.get("c")
+	assert.True(t, hitA)
+	assert.False(t, hitB)
+	assert.True(t, hitC)
+	assert.NotContains(t, cache.tagged, LabelTag("b"))
+}
+
+func TestQueryCache_NotCachedIfInvalidatedWhileRunning(t *testing.T) {
+	// GIVEN
+	cache := NewQueryCache(time.Minute, 10)
+	_, generation, _ := cache.get("key")
+
+	// WHEN
+	cache.Invalidate(LabelTag("user"))
+	cache.put("key", successResponses("1"), []string{LabelTag("user")}, generation)
+
+	// THEN
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestQueryTags(t *testing.T) {
+	g := api.NewGraph("g")
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{g.V().String(), []string{unlabelledTag}},
+		{g.VByStr("abc").String(), []string{unlabelledTag, VertexTag("abc")}},
+		{g.V().HasLabel("user", "admin").Out("knows").String(), []string{LabelTag("user"), LabelTag("admin"), LabelTag("knows"), traversingTag}},
+		{g.AddV("user").Property("name", "x").String(), []string{LabelTag("user")}},
+		{"g.V().hasLabel('user').addE('knows').to(g.V('b'))", []string{LabelTag("user"), LabelTag("knows"), VertexTag("b")}},
+		{"g.V().has('name',within('a','b'))", []string{unlabelledTag}},
+		{"g.V('a').outE().inV()", []string{unlabelledTag, VertexTag("a"), traversingTag}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, queryTags(test.query), test.query)
+	}
+}
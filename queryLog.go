@@ -0,0 +1,99 @@
+package gremcos
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/supplyon/gremcos/api"
+)
+
+// defaultSensitiveBindingKeys are the parts of binding keys whose values are redacted in the query log
+var defaultSensitiveBindingKeys = []string{"password", "passwd", "secret", "token", "key", "credential", "auth"}
+
+const redacted = "<redacted>"
+
+// queryLogger emits a log event for each query that was slow, expensive or failed
+type queryLogger struct {
+	logger zerolog.Logger
+
+	// latencyThreshold queries that took longer are logged (0 = disabled)
+	latencyThreshold time.Duration
+	// requestChargeThreshold queries that consumed more request units are logged (0 = disabled)
+	requestChargeThreshold float32
+	// sensitiveBindingKeys values of bindings whose key contains one of these (case insensitive) are redacted
+	sensitiveBindingKeys []string
+}
+
+// QueryLog enables logging of queries that took longer than latencyThreshold or consumed more
+// request units (RU) than requestChargeThreshold. Failed queries are logged as well.
+// A threshold of 0 disables the according check. The log events are emitted using the logger set via WithLogger.
+// The events contain a fingerprint of the query (all literals are replaced by '?') and the bindings of the query.
+// The values of bindings whose key contains one of the sensitiveBindingKeys (case insensitive) are redacted.
+// The values of the bindings generated by ExecuteQueryWithBindings (_b0, _b1, ...) are always redacted, like the literals
+// of the fingerprint, since their names don't tell which property they belong to (e.g. Property("password", "...")).
+// If no sensitiveBindingKeys are given, a default list (e.g. password, secret, token, key) is used.
+func QueryLog(latencyThreshold time.Duration, requestChargeThreshold float32, sensitiveBindingKeys ...string) Option {
+	return func(c *cosmosImpl) {
+		if len(sensitiveBindingKeys) == 0 {
+			sensitiveBindingKeys = defaultSensitiveBindingKeys
+		}
+
+		c.queryLog = &queryLogger{
+			latencyThreshold:       latencyThreshold,
+			requestChargeThreshold: requestChargeThreshold,
+			sensitiveBindingKeys:   sensitiveBindingKeys,
+		}
+	}
+}
+
+// log emits a log event in case the query was slow, expensive or failed
+func (q *queryLogger) log(query string, bindings map[string]interface{}, diagnostics *Diagnostics, err error) {
+	if q == nil || diagnostics == nil {
+		return
+	}
+
+	var event *zerolog.Event
+	switch {
+	case err != nil:
+		event = q.logger.Error().Err(err).Str("reason", "failed")
+	case q.latencyThreshold > 0 && diagnostics.Latency > q.latencyThreshold:
+		event = q.logger.Warn().Str("reason", "slow")
+	case q.requestChargeThreshold > 0 && diagnostics.RequestCharge > q.requestChargeThreshold:
+		event = q.logger.Warn().Str("reason", "expensive")
+	default:
+		return
+	}
+
+	event = event.Str("fingerprint", queryFingerprint(query))
+	if len(bindings) > 0 {
+		event = event.Interface("bindings", q.redact(bindings))
+	}
+
+	event.Float32("request_charge", diagnostics.RequestCharge).
+		Dur("server_time", diagnostics.ServerTime).
+		Dur("latency", diagnostics.Latency).
+		Int("retries", diagnostics.Retries).
+		Strs("activity_ids", diagnostics.ActivityIDs).
+		Msg("Query log")
+}
+
+// redact returns a copy of the given bindings where the values of sensitive keys and of generated bindings are replaced
+func (q *queryLogger) redact(bindings map[string]interface{}) map[string]interface{} {
+	redactedBindings := make(map[string]interface{}, len(bindings))
+	for key, value := range bindings {
+		redactedBindings[key] = value
+		if api.IsGeneratedBinding(key) {
+			redactedBindings[key] = redacted
+			continue
+		}
+		lowerKey := strings.ToLower(key)
+		for _, sensitiveKey := range q.sensitiveBindingKeys {
+			if strings.Contains(lowerKey, strings.ToLower(sensitiveKey)) {
+				redactedBindings[key] = redacted
+				break
+			}
+		}
+	}
+	return redactedBindings
+}
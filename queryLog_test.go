@@ -0,0 +1,123 @@
+package gremcos
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/supplyon/gremcos/api"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+func TestQueryLogRedact(t *testing.T) {
+	// GIVEN
+	queryLog := &queryLogger{sensitiveBindingKeys: defaultSensitiveBindingKeys}
+	bindings := map[string]interface{}{
+		"name":        "Max",
+		"Password":    "secret",
+		"apiKey":      "abc",
+		"accessToken": "xyz",
+	}
+
+	// WHEN
+	redactedBindings := queryLog.redact(bindings)
+
+	// THEN
+	assert.Equal(t, map[string]interface{}{
+		"name":        "Max",
+		"Password":    redacted,
+		"apiKey":      redacted,
+		"accessToken": redacted,
+	}, redactedBindings)
+	assert.Equal(t, "secret", bindings["Password"], "the given bindings must not be modified")
+}
+
+func TestQueryLogRedactGeneratedBindings(t *testing.T) {
+	// GIVEN
+	queryLog := &queryLogger{sensitiveBindingKeys: defaultSensitiveBindingKeys}
+	query := api.NewGraph("g").AddV("user").Property("name", "Max").Property("password", "secret")
+	_, bindings := query.(interfaces.QueryBuilderWithBindings).StringWithBindings()
+	bindings["name"] = "Max"
+
+	// WHEN
+	redactedBindings := queryLog.redact(bindings)
+
+	// THEN
+	assert.Equal(t, map[string]interface{}{
+		"_b0":  redacted,
+		"_b1":  redacted,
+		"_b2":  redacted,
+		"_b3":  redacted,
+		"_b4":  redacted,
+		"name": "Max",
+	}, redactedBindings)
+}
+
+func TestQueryLog(t *testing.T) {
+	// GIVEN
+	diagnostics := func(latency time.Duration, requestCharge float32) *Diagnostics {
+		return &Diagnostics{Latency: latency, RequestCharge: requestCharge, ActivityIDs: []string{"activity-1"}}
+	}
+	tests := []struct {
+		name           string
+		diagnostics    *Diagnostics
+		err            error
+		expectedLevel  string
+		expectedReason string
+	}{
+		{name: "fast and cheap", diagnostics: diagnostics(time.Millisecond, 1)},
+		{name: "slow", diagnostics: diagnostics(time.Second, 1), expectedLevel: "warn", expectedReason: "slow"},
+		{name: "expensive", diagnostics: diagnostics(time.Millisecond, 100), expectedLevel: "warn", expectedReason: "expensive"},
+		{name: "failed", diagnostics: diagnostics(time.Millisecond, 1), err: errors.New("failed"), expectedLevel: "error", expectedReason: "failed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			queryLog := &queryLogger{
+				logger:                 zerolog.New(&buf),
+				latencyThreshold:       time.Millisecond * 100,
+				requestChargeThreshold: 50,
+				sensitiveBindingKeys:   defaultSensitiveBindingKeys,
+			}
+
+			// WHEN
+			queryLog.log(`g.V().has("name", name).has("password", pw)`, map[string]interface{}{"name": "Max", "pw": "abc", "password": "abc"}, test.diagnostics, test.err)
+
+			// THEN
+			if len(test.expectedLevel) == 0 {
+				assert.Empty(t, buf.String())
+				return
+			}
+
+			event := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+			assert.Equal(t, test.expectedLevel, event["level"])
+			assert.Equal(t, test.expectedReason, event["reason"])
+			assert.Equal(t, "g.V().has(?, name).has(?, pw)", event["fingerprint"])
+			assert.Equal(t, map[string]interface{}{"name": "Max", "pw": "abc", "password": redacted}, event["bindings"])
+			assert.Equal(t, []interface{}{"activity-1"}, event["activity_ids"])
+			assert.Equal(t, "Query log", event["message"])
+		})
+	}
+}
+
+func TestQueryLogNil(t *testing.T) {
+	// GIVEN
+	var queryLog *queryLogger
+	var buf bytes.Buffer
+	withoutDiagnostics := &queryLogger{logger: zerolog.New(&buf)}
+
+	// WHEN + THEN
+	assert.NotPanics(t, func() {
+		queryLog.log("g.V()", nil, &Diagnostics{}, errors.New("failed"))
+	})
+	withoutDiagnostics.log("g.V()", nil, nil, errors.New("failed"))
+	assert.Empty(t, buf.String())
+}
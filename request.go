@@ -8,8 +8,16 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	// MimeTypeGraphSONv1 is the mime type of GraphSON 1.0. The gremlin server renders results untyped in this format,
+	// like cosmos does, hence they can be parsed via api.ToVertices, api.ToEdges etc.
+	MimeTypeGraphSONv1 = "application/vnd.gremlin-v1.0+json"
+	// MimeTypeGraphSONv2 is the mime type of GraphSON 2.0, which is the format to be used for cosmos.
+	MimeTypeGraphSONv2 = "application/vnd.gremlin-v2.0+json"
+)
+
 // MimeType used for communication with the gremlin server.
-var MimeType = []byte("application/vnd.gremlin-v2.0+json")
+var MimeType = []byte(MimeTypeGraphSONv2)
 
 // request is a container for all evaluation request parameters to be sent to the Gremlin Server.
 type request struct {
@@ -84,14 +92,19 @@ func prepareAuthRequest(requestID string, username string, password string) requ
 
 // formatMessage takes a request type and formats it into being able to be delivered to Gremlin Server
 func packageRequest(req request) ([]byte, error) {
+	return packageRequestAs(req, MimeType)
+}
+
+// packageRequestAs formats the given request like packageRequest, using the given mime type
+func packageRequestAs(req request, mimeType []byte) ([]byte, error) {
 	j, err := json.Marshal(req) // Formats request into byte format
 	if err != nil {
 		return nil, errors.Wrap(err, "marshalling request")
 	}
-	lenMimeType := byte(len(MimeType))
+	lenMimeType := byte(len(mimeType))
 
 	//lenMimeType is the fixed length of mimeType in hex
-	msg := append([]byte{lenMimeType}, MimeType...)
+	msg := append([]byte{lenMimeType}, mimeType...)
 	msg = append(msg, j...)
 
 	return msg, nil
@@ -0,0 +1,100 @@
+package gremcos
+
+import "regexp"
+
+// unnamedOperation is the operation label of requests that were sent without OperationName
+const unnamedOperation = "unnamed"
+
+// mutatingSteps matches the gremlin steps that modify the graph
+var mutatingSteps = regexp.MustCompile(`(^|[^A-Za-z0-9_])(addV|addE|property|drop|mergeV|mergeE|sideEffect)\s*\(`)
+
+// RequestOption is the struct for defining optional parameters for a single request
+type RequestOption func(*requestOptions)
+
+// requestOptions contains the settings that apply to one request only
+type requestOptions struct {
+	// idempotent marks a request that can be issued multiple times without changing the result beyond the initial execution.
+	idempotent bool
+	// readOnly marks a request that does not modify the graph.
+	readOnly bool
+	// retryPolicy overrides the retry policy configured for the cosmos connector (optional)
+	retryPolicy RetryPolicy
+	// diagnostics is filled with the information gathered while executing the request (optional)
+	diagnostics *Diagnostics
+	// operation is the name of the operation the request belongs to, used as label of the metrics (optional)
+	operation string
+	// metadata are values the caller attached to the request for the interceptors (optional)
+	metadata map[string]interface{}
+	// cacheTags are additional tags of the cached responses of the request (optional)
+	cacheTags []string
+	// bypassCache skips the query cache for the request
+	bypassCache bool
+}
+
+// Idempotent marks the request as idempotent. This means that executing the request multiple times
+// has the same effect as executing it once (e.g. a read-only query or an upsert with a fixed id).
+// Only idempotent requests are retried on connectivity errors (see RetryOnConnectivityErrors), since it is
+// unknown whether the server has already executed a request that failed due to a connectivity issue.
+func Idempotent() RequestOption {
+	return func(r *requestOptions) {
+		r.idempotent = true
+	}
+}
+
+// ReadOnly marks the request as read-only. This means that the request does not modify the graph, hence it
+// can be retried safely (see Idempotent). Requests that do not contain any mutating step (e.g. addV, addE,
+// property, drop) are regarded as read-only anyway.
+func ReadOnly() RequestOption {
+	return func(r *requestOptions) {
+		r.readOnly = true
+	}
+}
+
+// UseRetryPolicy overrides the retry policy (see WithRetryPolicy) for this request.
+//
+//	cosmos.Execute("g.V().hasLabel('user')", gremcos.UseRetryPolicy(gremcos.DefaultRetryPolicy(0)))
+func UseRetryPolicy(policy RetryPolicy) RequestOption {
+	return func(r *requestOptions) {
+		r.retryPolicy = policy
+	}
+}
+
+// OperationName sets the name of the operation (e.g. the endpoint) the request belongs to.
+// The latency, request charge and server time of the request are recorded labelled by this name, which allows
+// to attribute the costs to operations. Hint: Use a small, fixed set of names to keep the number of time series low.
+//
+//	cosmos.Execute("g.V().hasLabel('user')", gremcos.OperationName("list_users"))
+func OperationName(name string) RequestOption {
+	return func(r *requestOptions) {
+		r.operation = name
+	}
+}
+
+// operationName returns the name of the operation the request belongs to, "unnamed" if none was set
+func (r requestOptions) operationName() string {
+	if len(r.operation) == 0 {
+		return unnamedOperation
+	}
+	return r.operation
+}
+
+// safeToRetry returns true in case the given request can be retried without the risk of applying a mutation twice.
+// This is the case if the request is marked as read-only or idempotent or if the query does not contain any mutating step.
+func (r requestOptions) safeToRetry(query string) bool {
+	return r.readOnly || r.idempotent || isReadOnlyQuery(query)
+}
+
+// isReadOnlyQuery returns true in case the given query does not contain any step that modifies the graph.
+// The check is conservative, e.g. a query containing the string 'drop()' in a literal is regarded as mutating.
+func isReadOnlyQuery(query string) bool {
+	return !mutatingSteps.MatchString(query)
+}
+
+// newRequestOptions creates the request settings based on the given options
+func newRequestOptions(options ...RequestOption) requestOptions {
+	reqOptions := requestOptions{}
+	for _, opt := range options {
+		opt(&reqOptions)
+	}
+	return reqOptions
+}
@@ -0,0 +1,49 @@
+package gremcos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	// GIVEN
+	queries := map[string]bool{
+		"g.V()": true,
+		"g.V().hasLabel('user').properties('name')":      true,
+		"g.V().values('property')":                       true,
+		"g.V().has('dropped', true)":                     true,
+		"g.addV('user')":                                 false,
+		"g.V('1').addE('knows').to(g.V('2'))":            false,
+		"g.V('1').property('name', 'Max')":               false,
+		"g.V('1').drop()":                                false,
+		"g.V().coalesce(__.has('id','1'), addV('user'))": false,
+		"g.mergeV([(T.id): '1'])":                        false,
+		"g.V().sideEffect(drop ())":                      false,
+	}
+
+	for query, expected := range queries {
+		// WHEN
+		readOnly := isReadOnlyQuery(query)
+
+		// THEN
+		assert.Equal(t, expected, readOnly, query)
+	}
+}
+
+func TestSafeToRetry(t *testing.T) {
+	// GIVEN
+	mutation := "g.addV('user')"
+
+	// WHEN + THEN
+	assert.False(t, newRequestOptions().safeToRetry(mutation))
+	assert.True(t, newRequestOptions(Idempotent()).safeToRetry(mutation))
+	assert.True(t, newRequestOptions(ReadOnly()).safeToRetry(mutation))
+	assert.True(t, newRequestOptions().safeToRetry("g.V().count()"))
+}
+
+func TestOperationName(t *testing.T) {
+	// WHEN + THEN
+	assert.Equal(t, "unnamed", newRequestOptions().operationName())
+	assert.Equal(t, "list_users", newRequestOptions(OperationName("list_users")).operationName())
+}
@@ -63,6 +63,20 @@ func TestRequestPackaging(t *testing.T) {
 	assert.Equal(t, msg, expected)
 }
 
+func TestRequestPackagingAs(t *testing.T) {
+	// GIVEN
+	testRequest := request{RequestID: "1d6d02bd-8e56-421d-9438-3bd6d0079ff1", Op: "eval"}
+
+	// WHEN
+	msg, err := packageRequestAs(testRequest, []byte(MimeTypeGraphSONv1))
+
+	// THEN
+	require.NoError(t, err)
+	lenMimeType := len(MimeTypeGraphSONv1)
+	assert.Equal(t, byte(lenMimeType), msg[0])
+	assert.Equal(t, MimeTypeGraphSONv1, string(msg[1:lenMimeType+1]))
+}
+
 // TestRequestDispatch tests the ability for a requester to send a request to the client for writing to Gremlin Server
 func TestRequestDispatch(t *testing.T) {
 	// GIVEN
@@ -33,6 +33,8 @@ func marshalResponse(msg []byte) (interfaces.Response, error) {
 
 // saveResponse makes the response available for retrieval by the requester. Mutexes are used for thread safety.
 func (c *client) saveResponse(resp interfaces.Response, err error) {
+	observerOrNop(c.observer).OnChunk(newChunkEvent(resp, err, c.callOf(resp.RequestID)))
+
 	c.mux.Lock()
 	defer c.mux.Unlock()
 	var container []interface{}
@@ -67,7 +69,8 @@ func (c *client) saveResponse(resp interfaces.Response, err error) {
 }
 
 // retrieveResponseAsync retrieves the response saved by saveResponse and send the retrieved repose to the channel .
-func (c *client) retrieveResponseAsync(id string, responseChannel chan interfaces.AsyncResponse) {
+// It returns the number of responses sent and the error of the final response.
+func (c *client) retrieveResponseAsync(id string, responseChannel chan interfaces.AsyncResponse) (chunks int, err error) {
 	var responseProcessedIndex int
 	responseNotifier, _ := c.responseNotifier.Load(id)
 	responseNotifierChannel := responseNotifier.(*safeCloseErrorChannel)
@@ -98,7 +101,7 @@ func (c *client) retrieveResponseAsync(id string, responseChannel chan interface
 		}
 
 		//Checks to see If there was an Error or will get nil when final response has been provided by cosmos
-		err := <-responseNotifierChannel.c
+		err = <-responseNotifierChannel.c
 
 		if dataI, ok := c.results.Load(id); ok {
 			d := dataI.([]interface{})
@@ -127,6 +130,7 @@ func (c *client) retrieveResponseAsync(id string, responseChannel chan interface
 	c.responseStatusNotifier.Delete(id)
 	c.deleteResponse(id)
 	close(responseChannel)
+	return responseProcessedIndex, err
 }
 
 func emptyIfNilOrError(err error) string {
@@ -0,0 +1,196 @@
+package gremcos
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryAttempt contains the information about a finished attempt to execute a request.
+// It is handed over to the RetryPolicy to decide whether the request shall be retried.
+type RetryAttempt struct {
+	// Attempt is the number of the attempt that has just finished, starting at 1 for the initial request.
+	Attempt int
+	// StatusCode is the cosmos status code (x-ms-status-code) of the response that suggests a retry, 0 if there is none.
+	StatusCode int
+	// SubStatusCode is the cosmos sub status code (x-ms-substatus-code) of the response that suggests a retry, 0 if there is none.
+	SubStatusCode int
+	// RetryAfter is the time cosmos suggests to wait before the request is retried (x-ms-retry-after-ms).
+	RetryAfter time.Duration
+	// Err is the error that occurred while executing the request, nil if the responses were received.
+	Err error
+	// Retryable is true in case the attempt failed in a way that allows a retry.
+	// This is the case if cosmos suggests to retry the request (e.g. status codes 409, 412, 429, 1007, 1008) or if the
+	// request failed due to a connectivity error and retries on connectivity errors are enabled for this request
+	// (see RetryOnConnectivityErrors and Idempotent).
+	Retryable bool
+}
+
+// RetryPolicy decides whether a request shall be retried and how long to wait before doing so.
+// The policy is consulted after each attempt, including successful ones.
+// Hint: Independent of the policy the overall duration of retries is limited by the timeout given in AutomaticRetries.
+type RetryPolicy interface {
+	// ShouldRetry returns true in case the request shall be retried after waiting for the returned duration.
+	ShouldRetry(attempt RetryAttempt) (retry bool, wait time.Duration)
+}
+
+const (
+	// connectivityRetryBaseDelay is the wait time of the default policy before the first retry of a request that failed due to a connectivity error
+	connectivityRetryBaseDelay = time.Millisecond * 100
+	// connectivityRetryMaxDelay is the maximum wait time of the default policy before retrying a request that failed due to a connectivity error
+	connectivityRetryMaxDelay = time.Second * 5
+)
+
+// DefaultRetryPolicy returns the policy that is used if no other policy is specified.
+// Retryable requests are retried up to maxRetries times. Before each retry the policy waits for
+// the duration suggested by cosmos (x-ms-retry-after-ms) or retries immediately if there is no such suggestion.
+// Requests that failed due to a connectivity error (see RetryOnConnectivityErrors) carry no suggestion, they are retried
+// with exponential backoff (100ms, 200ms, 400ms, ... up to 5s) to avoid hammering an unavailable server.
+func DefaultRetryPolicy(maxRetries int) RetryPolicy {
+	return &defaultRetryPolicy{maxRetries: maxRetries}
+}
+
+type defaultRetryPolicy struct {
+	maxRetries int
+}
+
+func (p *defaultRetryPolicy) ShouldRetry(attempt RetryAttempt) (bool, time.Duration) {
+	if !attempt.Retryable || attempt.Attempt > p.maxRetries {
+		return false, 0
+	}
+
+	if attempt.Err != nil && attempt.RetryAfter <= 0 {
+		return true, connectivityBackoff(attempt.Attempt)
+	}
+	return true, attempt.RetryAfter
+}
+
+// connectivityBackoff returns the wait time before retrying a request after the given attempt failed due to a connectivity error
+func connectivityBackoff(attempt int) time.Duration {
+	backoff := float64(connectivityRetryBaseDelay) * math.Pow(2, float64(attempt-1))
+	if backoff > float64(connectivityRetryMaxDelay) {
+		return connectivityRetryMaxDelay
+	}
+	return time.Duration(backoff)
+}
+
+// ExponentialBackoff returns a policy that retries retryable requests up to maxRetries times using
+// exponential backoff with full jitter. The wait time before the n-th retry is chosen randomly
+// between 0 and min(maxDelay, baseDelay * 2^(n-1)). If cosmos suggests to wait longer (x-ms-retry-after-ms),
+// the suggested duration is used instead.
+func ExponentialBackoff(maxRetries int, baseDelay, maxDelay time.Duration) RetryPolicy {
+	return &exponentialBackoffPolicy{
+		maxRetries: maxRetries,
+		baseDelay:  baseDelay,
+		maxDelay:   maxDelay,
+		random:     rand.Float64,
+	}
+}
+
+type exponentialBackoffPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+
+	// random returns a random number in [0.0,1.0)
+	random func() float64
+}
+
+func (p *exponentialBackoffPolicy) ShouldRetry(attempt RetryAttempt) (bool, time.Duration) {
+	if !attempt.Retryable || attempt.Attempt > p.maxRetries {
+		return false, 0
+	}
+
+	backoff := float64(p.baseDelay) * math.Pow(2, float64(attempt.Attempt-1))
+	if p.maxDelay > 0 && backoff > float64(p.maxDelay) {
+		backoff = float64(p.maxDelay)
+	}
+
+	wait := time.Duration(p.random() * backoff)
+	if wait < attempt.RetryAfter {
+		wait = attempt.RetryAfter
+	}
+	return true, wait
+}
+
+// RetryBudget limits the number of retries in relation to the number of requests.
+// It is meant to be shared by all requests of the process to prevent retry storms, e.g. in case
+// cosmos is overloaded. Each request deposits ratio tokens and each retry withdraws one token.
+// Hence in the long run at most ratio retries per request are done. The budget holds at most maxTokens
+// tokens, which allows bursts of retries after a phase without retries.
+type RetryBudget struct {
+	// the tokens are scaled by retryBudgetScale to avoid rounding errors
+	ratio     int64
+	maxTokens int64
+
+	tokens int64
+	mu     sync.Mutex
+}
+
+// retryBudgetScale is the number of units one token of the retry budget consists of
+const retryBudgetScale = 1000
+
+// NewRetryBudget creates a new retry budget that allows ratio retries per request (e.g. 0.1 = 10%) and
+// bursts of up to maxTokens retries. The budget is full initially.
+func NewRetryBudget(ratio float64, maxTokens int) *RetryBudget {
+	return &RetryBudget{
+		ratio:     int64(math.Round(ratio * retryBudgetScale)),
+		maxTokens: int64(maxTokens) * retryBudgetScale,
+		tokens:    int64(maxTokens) * retryBudgetScale,
+	}
+}
+
+// deposit adds the tokens for one request to the budget
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+// withdraw takes the token for one retry from the budget. It returns false in case the budget is exhausted.
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < retryBudgetScale {
+		return false
+	}
+	b.tokens -= retryBudgetScale
+	return true
+}
+
+// BudgetedRetryPolicy returns a policy that retries only if the given policy decides to do so and
+// the given budget is not exhausted.
+//
+//	budget := gremcos.NewRetryBudget(0.1, 10)
+//	New("wss://example.com", WithRetryPolicy(gremcos.BudgetedRetryPolicy(gremcos.ExponentialBackoff(3, time.Millisecond*100, time.Second*5), budget)))
+func BudgetedRetryPolicy(policy RetryPolicy, budget *RetryBudget) RetryPolicy {
+	return &budgetedRetryPolicy{policy: policy, budget: budget}
+}
+
+type budgetedRetryPolicy struct {
+	policy RetryPolicy
+	budget *RetryBudget
+}
+
+func (p *budgetedRetryPolicy) ShouldRetry(attempt RetryAttempt) (bool, time.Duration) {
+	// the initial attempt of each request is regarded as request
+	if attempt.Attempt == 1 {
+		p.budget.deposit()
+	}
+
+	retry, wait := p.policy.ShouldRetry(attempt)
+	if !retry {
+		return false, 0
+	}
+
+	if !p.budget.withdraw() {
+		return false, 0
+	}
+	return true, wait
+}
@@ -0,0 +1,163 @@
+package gremcos
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	// GIVEN
+	policy := DefaultRetryPolicy(2)
+
+	// WHEN + THEN
+	retry, wait := policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: false})
+	assert.False(t, retry)
+	assert.Equal(t, time.Duration(0), wait)
+
+	retry, wait = policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: true})
+	assert.True(t, retry)
+	assert.Equal(t, time.Duration(0), wait)
+
+	retry, wait = policy.ShouldRetry(RetryAttempt{Attempt: 2, Retryable: true, RetryAfter: time.Millisecond * 500})
+	assert.True(t, retry)
+	assert.Equal(t, time.Millisecond*500, wait)
+
+	retry, _ = policy.ShouldRetry(RetryAttempt{Attempt: 3, Retryable: true})
+	assert.False(t, retry, "Expected no retry after maxRetries")
+}
+
+func TestDefaultRetryPolicy_ConnectivityErrorsBackOff(t *testing.T) {
+	// GIVEN
+	policy := DefaultRetryPolicy(10)
+	connectivityErr := Error{Wrapped: fmt.Errorf("broken pipe"), Category: ErrorCategoryConnectivity}
+
+	// WHEN + THEN
+	retry, wait := policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: true, Err: connectivityErr})
+	assert.True(t, retry)
+	assert.Equal(t, time.Millisecond*100, wait)
+
+	_, wait = policy.ShouldRetry(RetryAttempt{Attempt: 2, Retryable: true, Err: connectivityErr})
+	assert.Equal(t, time.Millisecond*200, wait)
+
+	_, wait = policy.ShouldRetry(RetryAttempt{Attempt: 3, Retryable: true, Err: connectivityErr})
+	assert.Equal(t, time.Millisecond*400, wait)
+
+	_, wait = policy.ShouldRetry(RetryAttempt{Attempt: 10, Retryable: true, Err: connectivityErr})
+	assert.Equal(t, time.Second*5, wait, "Expected the wait time to be capped")
+
+	_, wait = policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: true, Err: connectivityErr, RetryAfter: time.Millisecond * 20})
+	assert.Equal(t, time.Millisecond*20, wait, "Expected the suggested wait time to be used")
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	// GIVEN
+	policy := ExponentialBackoff(10, time.Millisecond*100, time.Second).(*exponentialBackoffPolicy)
+	randomValue := 0.5
+	policy.random = func() float64 { return randomValue }
+
+	// WHEN + THEN
+	retry, _ := policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: false})
+	assert.False(t, retry)
+
+	retry, wait := policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: true})
+	assert.True(t, retry)
+	assert.Equal(t, time.Millisecond*50, wait)
+
+	retry, wait = policy.ShouldRetry(RetryAttempt{Attempt: 3, Retryable: true})
+	assert.True(t, retry)
+	assert.Equal(t, time.Millisecond*200, wait)
+
+	// capped by maxDelay
+	retry, wait = policy.ShouldRetry(RetryAttempt{Attempt: 8, Retryable: true})
+	assert.True(t, retry)
+	assert.Equal(t, time.Millisecond*500, wait)
+
+	// full jitter
+	randomValue = 0
+	retry, wait = policy.ShouldRetry(RetryAttempt{Attempt: 2, Retryable: true})
+	assert.True(t, retry)
+	assert.Equal(t, time.Duration(0), wait)
+
+	retry, _ = policy.ShouldRetry(RetryAttempt{Attempt: 11, Retryable: true})
+	assert.False(t, retry, "Expected no retry after maxRetries")
+}
+
+func TestExponentialBackoffRespectsRetryAfter(t *testing.T) {
+	// GIVEN
+	policy := ExponentialBackoff(3, time.Millisecond*100, time.Second)
+
+	// WHEN
+	retry, wait := policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: true, StatusCode: 429, RetryAfter: time.Second * 2})
+
+	// THEN
+	assert.True(t, retry)
+	assert.Equal(t, time.Second*2, wait)
+}
+
+func TestExponentialBackoffJitterBounds(t *testing.T) {
+	// GIVEN
+	policy := ExponentialBackoff(3, time.Millisecond*100, time.Second)
+
+	for i := 0; i < 100; i++ {
+		// WHEN
+		retry, wait := policy.ShouldRetry(RetryAttempt{Attempt: 2, Retryable: true, Err: fmt.Errorf("broken pipe")})
+
+		// THEN
+		assert.True(t, retry)
+		assert.GreaterOrEqual(t, wait, time.Duration(0))
+		assert.Less(t, wait, time.Millisecond*200)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	// GIVEN
+	budget := NewRetryBudget(0.5, 2)
+
+	// WHEN + THEN -- initially the budget is full
+	assert.True(t, budget.withdraw())
+	assert.True(t, budget.withdraw())
+	assert.False(t, budget.withdraw())
+
+	// WHEN + THEN -- two requests earn one retry
+	budget.deposit()
+	assert.False(t, budget.withdraw())
+	budget.deposit()
+	assert.True(t, budget.withdraw())
+
+	// WHEN + THEN -- the budget is capped
+	for i := 0; i < 10; i++ {
+		budget.deposit()
+	}
+	assert.True(t, budget.withdraw())
+	assert.True(t, budget.withdraw())
+	assert.False(t, budget.withdraw())
+}
+
+func TestBudgetedRetryPolicy(t *testing.T) {
+	// GIVEN
+	budget := NewRetryBudget(0.1, 1)
+	policy := BudgetedRetryPolicy(DefaultRetryPolicy(3), budget)
+
+	// WHEN + THEN -- not retryable, hence no token is used
+	retry, _ := policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: false})
+	assert.False(t, retry)
+
+	// WHEN + THEN -- the token of the initial budget is used
+	retry, wait := policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: true, RetryAfter: time.Millisecond * 10})
+	assert.True(t, retry)
+	assert.Equal(t, time.Millisecond*10, wait)
+
+	// WHEN + THEN -- budget is exhausted
+	retry, _ = policy.ShouldRetry(RetryAttempt{Attempt: 2, Retryable: true})
+	assert.False(t, retry)
+
+	// WHEN + THEN -- ten requests earn one retry
+	for i := 0; i < 10; i++ {
+		policy.ShouldRetry(RetryAttempt{Attempt: 1, Retryable: false})
+	}
+	retry, _ = policy.ShouldRetry(RetryAttempt{Attempt: 2, Retryable: true})
+	assert.True(t, retry)
+}
@@ -0,0 +1,260 @@
+package gremcos
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+const (
+	// ruLimiterEWMAWeight is the weight of a newly observed request charge for the learned costs of a query
+	ruLimiterEWMAWeight = 0.3
+	// ruLimiterMaxLearnedQueries limits the number of query fingerprints whose costs are learned individually
+	ruLimiterMaxLearnedQueries = 1000
+	// ruLimiterDecreaseFactor is the factor the rate is reduced by when cosmos throttles a request
+	ruLimiterDecreaseFactor = 0.5
+	// ruLimiterIncreaseFactor is the fraction of the configured rate the rate is increased by after each request that was not throttled
+	ruLimiterIncreaseFactor = 0.01
+	// ruLimiterMinRateFactor is the fraction of the configured rate the rate is never reduced below
+	ruLimiterMinRateFactor = 0.05
+)
+
+var (
+	// doubleQuotedLiteral matches string literals like "abc" (including escaped quotes)
+	doubleQuotedLiteral = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	// singleQuotedLiteral matches string literals like 'abc' (including escaped quotes)
+	singleQuotedLiteral = regexp.MustCompile(`'(?:[^'\\]|\\.)*'`)
+	// numericLiteral matches numbers like 12, -1.5 or 10L
+	numericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?[lLdDfF]?\b`)
+	// whitespaces matches consecutive whitespaces
+	whitespaces = regexp.MustCompile(`\s+`)
+)
+
+// ruLimiter is a token bucket that limits the request units (RU) consumed per second.
+// Since the costs of a query are only known after it was executed, the limiter learns the costs
+// per query fingerprint from the observed request charges and reserves the expected costs before a request is sent.
+// Queries that only differ in their literals (e.g. the ids inlined by the query builders) share the learned costs (see queryFingerprint).
+// The difference between the expected and the actual costs is settled afterwards.
+// In case cosmos throttles a request (429) the rate is reduced multiplicatively and increased additively
+// again with each request that was not throttled (AIMD).
+type ruLimiter struct {
+	// maxRate is the configured maximum of RU/s
+	maxRate float64
+	// rate is the currently allowed RU/s (adapted on throttling)
+	rate float64
+	// burst is the maximum number of RU that can be consumed at once
+	burst float64
+	// tokens are the currently available RU, a negative value represents RU that are already reserved
+	tokens     float64
+	lastRefill time.Time
+
+	// learnedCosts the expected costs per query fingerprint
+	learnedCosts map[string]float64
+	// defaultCosts the expected costs of queries that are not learned yet
+	defaultCosts float64
+
+	metrics *Metrics
+	now     func() time.Time
+	sleep   func(time.Duration)
+	mu      sync.Mutex
+}
+
+// ruReservation are the RU that were reserved for a request
+type ruReservation struct {
+	fingerprint  string
+	expectedCost float64
+}
+
+func newRULimiter(requestUnitsPerSecond float64, metrics *Metrics) *ruLimiter {
+	limiter := &ruLimiter{
+		maxRate:      requestUnitsPerSecond,
+		rate:         requestUnitsPerSecond,
+		burst:        requestUnitsPerSecond,
+		tokens:       requestUnitsPerSecond,
+		learnedCosts: make(map[string]float64),
+		defaultCosts: 1,
+		metrics:      metrics,
+		now:          time.Now,
+		sleep:        time.Sleep,
+	}
+	limiter.lastRefill = limiter.now()
+	return limiter
+}
+
+// refill adds the tokens for the time passed since the last refill.
+// It is not threadsafe. The caller should manage locking the limiter.
+func (l *ruLimiter) refill() {
+	now := l.now()
+	elapsed := now.Sub(l.lastRefill)
+	l.lastRefill = now
+
+	if elapsed <= 0 {
+		return
+	}
+
+	l.tokens += elapsed.Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// expectedCost returns the learned costs of the given query fingerprint.
+// It is not threadsafe. The caller should manage locking the limiter.
+func (l *ruLimiter) expectedCost(fingerprint string) float64 {
+	cost, ok := l.learnedCosts[fingerprint]
+	if !ok {
+		cost = l.defaultCosts
+	}
+
+	// a single request must not block the limiter longer than the burst allows
+	if cost > l.burst {
+		cost = l.burst
+	}
+	return cost
+}
+
+// acquire reserves the expected costs of the given query and blocks until the reserved RU are available.
+func (l *ruLimiter) acquire(query string) ruReservation {
+	if l == nil {
+		return ruReservation{}
+	}
+
+	fingerprint := queryFingerprint(query)
+
+	l.mu.Lock()
+	l.refill()
+	cost := l.expectedCost(fingerprint)
+	l.tokens -= cost
+
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.updateMetrics()
+	l.mu.Unlock()
+
+	if l.metrics != nil {
+		l.metrics.ruLimiterWaitMS.Observe(float64(wait.Milliseconds()))
+	}
+
+	if wait > 0 {
+		l.sleep(wait)
+	}
+
+	return ruReservation{fingerprint: fingerprint, expectedCost: cost}
+}
+
+// observe settles the given reservation with the request charge of the given responses and learns the costs of the query.
+// In case cosmos throttled the request, the rate is reduced.
+func (l *ruLimiter) observe(reservation ruReservation, responses []interfaces.Response) {
+	if l == nil {
+		return
+	}
+
+	var charge float32
+	var throttled bool
+	var retryAfter time.Duration
+	for _, response := range responses {
+		respInfo, err := parseAttributeMap(response.Status.Attributes)
+		if err != nil {
+			continue
+		}
+
+		// only take the largest value since cosmos already accumulates this value
+		if charge < respInfo.requestChargeTotal {
+			charge = respInfo.requestChargeTotal
+		}
+
+		if respInfo.statusCode == 429 {
+			throttled = true
+			if retryAfter < respInfo.retryAfter {
+				retryAfter = respInfo.retryAfter
+			}
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+
+	// settle the reservation with the actual costs
+	l.tokens += reservation.expectedCost - float64(charge)
+
+	if throttled {
+		l.tighten(retryAfter)
+	} else {
+		l.relax()
+		if charge > 0 {
+			l.learn(reservation.fingerprint, float64(charge))
+		}
+	}
+	l.updateMetrics()
+}
+
+// learn updates the expected costs of the given query fingerprint.
+// It is not threadsafe. The caller should manage locking the limiter.
+func (l *ruLimiter) learn(fingerprint string, charge float64) {
+	l.defaultCosts = ewma(l.defaultCosts, charge)
+
+	cost, ok := l.learnedCosts[fingerprint]
+	if !ok {
+		if len(l.learnedCosts) >= ruLimiterMaxLearnedQueries {
+			return
+		}
+		l.learnedCosts[fingerprint] = charge
+		return
+	}
+	l.learnedCosts[fingerprint] = ewma(cost, charge)
+}
+
+// tighten reduces the rate since cosmos throttled a request. No further RU are handed out until retryAfter has passed.
+// It is not threadsafe. The caller should manage locking the limiter.
+func (l *ruLimiter) tighten(retryAfter time.Duration) {
+	l.rate *= ruLimiterDecreaseFactor
+	if minRate := l.maxRate * ruLimiterMinRateFactor; l.rate < minRate {
+		l.rate = minRate
+	}
+
+	if blocked := -retryAfter.Seconds() * l.rate; l.tokens > blocked {
+		l.tokens = blocked
+	}
+}
+
+// relax increases the rate again up to the configured maximum.
+// It is not threadsafe. The caller should manage locking the limiter.
+func (l *ruLimiter) relax() {
+	l.rate += l.maxRate * ruLimiterIncreaseFactor
+	if l.rate > l.maxRate {
+		l.rate = l.maxRate
+	}
+}
+
+// updateMetrics publishes the state of the limiter.
+// It is not threadsafe. The caller should manage locking the limiter.
+func (l *ruLimiter) updateMetrics() {
+	if l.metrics == nil {
+		return
+	}
+	l.metrics.ruLimiterRate.Set(l.rate)
+	l.metrics.ruLimiterTokens.Set(l.tokens)
+}
+
+// ewma returns the exponentially weighted moving average based on the given average and the new value
+func ewma(average, value float64) float64 {
+	return ruLimiterEWMAWeight*value + (1-ruLimiterEWMAWeight)*average
+}
+
+// queryFingerprint returns the normalized query where all literals are replaced by '?'.
+// Hence queries that only differ in their parameters have the same fingerprint.
+// Example: g.V().has("user","name","Max").limit(10) --> g.V().has(?,?,?).limit(?)
+func queryFingerprint(query string) string {
+	fingerprint := doubleQuotedLiteral.ReplaceAllString(query, "?")
+	fingerprint = singleQuotedLiteral.ReplaceAllString(fingerprint, "?")
+	fingerprint = numericLiteral.ReplaceAllString(fingerprint, "?")
+	fingerprint = whitespaces.ReplaceAllString(fingerprint, " ")
+	return strings.TrimSpace(fingerprint)
+}
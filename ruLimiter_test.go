@@ -0,0 +1,224 @@
+package gremcos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+type fakeClock struct {
+	current time.Time
+	slept   []time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.current
+}
+
+func (c *fakeClock) sleep(d time.Duration) {
+	c.slept = append(c.slept, d)
+	c.current = c.current.Add(d)
+}
+
+func newTestRULimiter(requestUnitsPerSecond float64, metrics *Metrics) (*ruLimiter, *fakeClock) {
+	clock := &fakeClock{current: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := newRULimiter(requestUnitsPerSecond, metrics)
+	limiter.now = clock.now
+	limiter.sleep = clock.sleep
+	limiter.lastRefill = clock.now()
+	return limiter, clock
+}
+
+func newChargedResponse(statusCode int, charge float64, retryAfter string) interfaces.Response {
+	attributes := map[string]interface{}{
+		string(headerStatusCode):         statusCode,
+		string(headerRequestChargeTotal): charge,
+	}
+	if retryAfter != "" {
+		attributes[string(headerRetryAfterMS)] = retryAfter
+	}
+	return interfaces.Response{Status: interfaces.Status{Code: interfaces.StatusSuccess, Attributes: attributes}}
+}
+
+func TestQueryFingerprint(t *testing.T) {
+	// GIVEN
+	queries := map[string]string{
+		`g.V().has("user","name","Max").limit(10)`:       `g.V().has(?,?,?).limit(?)`,
+		`g.V().has('user', 'name', 'Max')`:               `g.V().has(?, ?, ?)`,
+		`g.V().has("name", "say \"hi\"")`:                `g.V().has(?, ?)`,
+		"g.V()\n  .has('age', gt(18.5)).range(0, 10L)":   `g.V() .has(?, gt(?)).range(?, ?)`,
+		`g.V().has("name", name).values("v1")`:           `g.V().has(?, name).values(?)`,
+		`g.V().hasLabel("user").out("knows").count()`:    `g.V().hasLabel(?).out(?).count()`,
+		`g.addV("user").property("id", "8c3e").next()  `: `g.addV(?).property(?, ?).next()`,
+	}
+
+	for query, expected := range queries {
+		// WHEN
+		fingerprint := queryFingerprint(query)
+
+		// THEN
+		assert.Equal(t, expected, fingerprint, query)
+	}
+}
+
+func TestRULimiterNil(t *testing.T) {
+	// GIVEN
+	var limiter *ruLimiter
+
+	// WHEN
+	reservation := limiter.acquire("g.V()")
+	limiter.observe(reservation, []interfaces.Response{newChargedResponse(200, 10, "")})
+
+	// THEN
+	assert.Equal(t, ruReservation{}, reservation)
+}
+
+func TestRULimiterLearnsCosts(t *testing.T) {
+	// GIVEN
+	limiter, clock := newTestRULimiter(100, newStubbedMetrics())
+
+	// WHEN
+	reservation := limiter.acquire("g.V()")
+	limiter.observe(reservation, []interfaces.Response{newChargedResponse(200, 5, ""), newChargedResponse(200, 20, "")})
+
+	// THEN
+	assert.Equal(t, 1.0, reservation.expectedCost, "Expected the default costs for an unknown query")
+	assert.Equal(t, 20.0, limiter.learnedCosts["g.V()"])
+	assert.InDelta(t, 80.0, limiter.tokens, 0.001)
+	assert.Empty(t, clock.slept)
+
+	// WHEN
+	reservation = limiter.acquire("g.V()")
+	limiter.observe(reservation, []interfaces.Response{newChargedResponse(200, 30, "")})
+
+	// THEN
+	assert.Equal(t, 20.0, reservation.expectedCost)
+	assert.InDelta(t, 23.0, limiter.learnedCosts["g.V()"], 0.001)
+	assert.InDelta(t, 50.0, limiter.tokens, 0.001)
+}
+
+func TestRULimiterLearnsCostsPerFingerprint(t *testing.T) {
+	// GIVEN
+	limiter, _ := newTestRULimiter(100, newStubbedMetrics())
+	reservation := limiter.acquire(`g.V("id-1").out("knows").limit(10)`)
+	limiter.observe(reservation, []interfaces.Response{newChargedResponse(200, 20, "")})
+
+	// WHEN
+	reservation = limiter.acquire(`g.V("id-2").out("knows").limit(20)`)
+
+	// THEN
+	assert.Equal(t, 20.0, reservation.expectedCost, "Expected the costs learned for a query that only differs in its literals")
+	assert.Equal(t, map[string]float64{"g.V(?).out(?).limit(?)": 20}, limiter.learnedCosts)
+}
+
+func TestRULimiterPacing(t *testing.T) {
+	// GIVEN
+	limiter, clock := newTestRULimiter(100, newStubbedMetrics())
+	limiter.learnedCosts["g.V()"] = 50
+
+	// WHEN -- the burst is consumed
+	limiter.acquire("g.V()")
+	limiter.acquire("g.V()")
+
+	// THEN
+	assert.Empty(t, clock.slept)
+
+	// WHEN -- the requests have to be paced
+	limiter.acquire("g.V()")
+	limiter.acquire("g.V()")
+
+	// THEN
+	require.Len(t, clock.slept, 2)
+	assert.Equal(t, time.Millisecond*500, clock.slept[0])
+	assert.Equal(t, time.Millisecond*500, clock.slept[1])
+}
+
+func TestRULimiterLimitsCostsToBurst(t *testing.T) {
+	// GIVEN
+	limiter, clock := newTestRULimiter(100, newStubbedMetrics())
+	limiter.learnedCosts["g.V()"] = 1000
+
+	// WHEN
+	reservation := limiter.acquire("g.V()")
+	limiter.acquire("g.V()")
+
+	// THEN
+	assert.Equal(t, 100.0, reservation.expectedCost)
+	require.Len(t, clock.slept, 1)
+	assert.Equal(t, time.Second, clock.slept[0])
+}
+
+func TestRULimiterTightensOnThrottling(t *testing.T) {
+	// GIVEN
+	limiter, clock := newTestRULimiter(100, newStubbedMetrics())
+
+	// WHEN
+	reservation := limiter.acquire("g.V()")
+	limiter.observe(reservation, []interfaces.Response{newChargedResponse(429, 0, "00:00:00.5000000")})
+
+	// THEN
+	assert.Equal(t, 50.0, limiter.rate)
+	assert.InDelta(t, -25.0, limiter.tokens, 0.001)
+	_, learned := limiter.learnedCosts["g.V()"]
+	assert.False(t, learned, "Expected throttled requests not to be learned")
+
+	// WHEN -- the next request has to wait for the suggested time
+	limiter.acquire("g.V()")
+
+	// THEN
+	require.Len(t, clock.slept, 1)
+	assert.Equal(t, time.Millisecond*520, clock.slept[0])
+
+	// WHEN -- the rate never drops below the minimum
+	for i := 0; i < 10; i++ {
+		limiter.observe(ruReservation{}, []interfaces.Response{newChargedResponse(429, 0, "")})
+	}
+
+	// THEN
+	assert.Equal(t, 5.0, limiter.rate)
+}
+
+func TestRULimiterRelaxes(t *testing.T) {
+	// GIVEN
+	limiter, _ := newTestRULimiter(100, newStubbedMetrics())
+	limiter.rate = 50
+
+	// WHEN
+	for i := 0; i < 10; i++ {
+		limiter.observe(ruReservation{}, []interfaces.Response{newChargedResponse(200, 0, "")})
+	}
+
+	// THEN
+	assert.Equal(t, 60.0, limiter.rate)
+
+	// WHEN
+	for i := 0; i < 100; i++ {
+		limiter.observe(ruReservation{}, []interfaces.Response{newChargedResponse(200, 0, "")})
+	}
+
+	// THEN
+	assert.Equal(t, 100.0, limiter.rate, "Expected the rate not to exceed the configured maximum")
+}
+
+func TestRULimiterMetrics(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	metrics, metricMocks := NewMockedMetrics(mockCtrl)
+	limiter, _ := newTestRULimiter(100, metrics)
+
+	// WHEN
+	metricMocks.ruLimiterRate.EXPECT().Set(100.0).Times(2)
+	metricMocks.ruLimiterTokens.EXPECT().Set(99.0)
+	metricMocks.ruLimiterTokens.EXPECT().Set(90.0)
+	metricMocks.ruLimiterWaitMS.EXPECT().Observe(0.0)
+	reservation := limiter.acquire("g.V()")
+	limiter.observe(reservation, []interfaces.Response{newChargedResponse(200, 10, "")})
+
+	// THEN
+	// expect the calls on the metrics specified above
+}
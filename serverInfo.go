@@ -0,0 +1,156 @@
+package gremcos
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/supplyon/gremcos/api"
+	"github.com/supplyon/gremcos/interfaces"
+)
+
+// ServerFlavour is the kind of gremlin server the connector is connected to
+type ServerFlavour string
+
+const (
+	// ServerFlavourUnknown the server was not (or could not be) detected
+	ServerFlavourUnknown ServerFlavour = "unknown"
+	// ServerFlavourCosmosDB the server is an Azure Cosmos DB (gremlin API)
+	ServerFlavourCosmosDB ServerFlavour = "cosmos"
+	// ServerFlavourTinkerPop the server is an apache tinkerpop gremlin server
+	ServerFlavourTinkerPop ServerFlavour = "tinkerpop"
+)
+
+const (
+	// serverProbeQuery is a cheap query used to find out whether the server is a cosmos db
+	serverProbeQuery = "g.inject(0)"
+	// serverVersionQuery returns the version of a tinkerpop gremlin server
+	serverVersionQuery = "Gremlin.version()"
+	// cosmosAttributePrefix is the prefix of the status attributes cosmos adds to each response
+	cosmosAttributePrefix = "x-ms-"
+	// cosmosTinkerPopVersion is the version of apache tinkerpop the gremlin API of cosmos is compatible with.
+	// Cosmos does not report its version and doesn't support Gremlin.version().
+	cosmosTinkerPopVersion = "3.4"
+)
+
+// ServerInfo describes the gremlin server the connector is connected to
+type ServerInfo struct {
+	// Flavour is the kind of the gremlin server
+	Flavour ServerFlavour
+	// Version is the version of the gremlin server, empty if unknown.
+	// For cosmos it is the version of apache tinkerpop its gremlin API is compatible with.
+	Version string
+}
+
+// QueryLanguage returns the query language (dialect) that is understood by the server.
+// For an unknown server the query language of cosmos is returned.
+//
+//	g := api.NewGraph("g", api.WithDialect(cosmos.ServerInfo().QueryLanguage()))
+func (s ServerInfo) QueryLanguage() api.QueryLanguage {
+	if s.Flavour == ServerFlavourTinkerPop {
+		return api.QueryLanguageTinkerpopGremlin
+	}
+	return api.QueryLanguageCosmosDB
+}
+
+// MimeType returns the mime type (serializer) whose responses can be parsed via api.ToVertices, api.ToEdges etc.
+// For an unknown server the mime type used for cosmos is returned.
+func (s ServerInfo) MimeType() string {
+	if s.Flavour == ServerFlavourTinkerPop {
+		return MimeTypeGraphSONv1
+	}
+	return MimeTypeGraphSONv2
+}
+
+// DetectServer enables the detection of the gremlin server (cosmos or tinkerpop gremlin server) while the connector is created.
+// Therefore a cheap query is sent to the server. The result is available via Cosmos.ServerInfo.
+// The query is sent directly to the server, hence it is neither passed to the interceptors nor recorded in the metrics.
+// The query language of the server is available via ServerInfo.QueryLanguage and can be passed to api.WithDialect.
+// In case configureSerializer is true, the connections of this connector use the mime type of the detected server
+// (see ServerInfo.MimeType). The previous mime type is kept in case the server does not accept it.
+func DetectServer(configureSerializer bool) Option {
+	return func(c *cosmosImpl) {
+		c.detectServer = true
+		c.configureSerializer = configureSerializer
+	}
+}
+
+// detectServerInfo probes the server to find out its flavour and version.
+// Cosmos is identified by the x-ms-* status attributes it adds to each response.
+func (c *cosmosImpl) detectServerInfo() ServerInfo {
+	responses, err := c.probe(serverProbeQuery)
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("Failed to detect the gremlin server")
+		return ServerInfo{Flavour: ServerFlavourUnknown}
+	}
+
+	if hasCosmosAttributes(responses) {
+		return ServerInfo{Flavour: ServerFlavourCosmosDB, Version: cosmosTinkerPopVersion}
+	}
+
+	info := ServerInfo{Flavour: ServerFlavourTinkerPop}
+	responses, err = c.probe(serverVersionQuery)
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("Failed to detect the version of the gremlin server")
+		return info
+	}
+	info.Version = firstStringResult(responses)
+	return info
+}
+
+// useSerializer replaces the pool of the connector by one whose connections use the given mime type.
+// In case the server does not accept the mime type, the previous pool is restored.
+func (c *cosmosImpl) useSerializer(mimeType string) error {
+	if mimeType == c.mimeType {
+		return nil
+	}
+
+	previousPool, previousMimeType := c.pool, c.mimeType
+	pool, err := c.newPool(mimeType)
+	if err != nil {
+		return err
+	}
+	c.pool, c.mimeType = pool, mimeType
+
+	if _, err := c.probe(serverProbeQuery); err != nil {
+		c.logger.Warn().Err(err).Str("mimeType", mimeType).Msg("The gremlin server does not accept the mime type, keeping the previous one")
+		c.pool, c.mimeType = previousPool, previousMimeType
+		return pool.Close()
+	}
+	return previousPool.Close()
+}
+
+// probe sends the given query directly to a pooled connection. Since it is an internal request of the connector,
+// it bypasses the interceptors, the query cache, the query log, the metrics and retries.
+func (c *cosmosImpl) probe(query string) ([]interfaces.Response, error) {
+	responses, err := c.pool.Execute(query)
+	if err != nil {
+		return nil, err
+	}
+	return responses, extractFirstError(responses)
+}
+
+// hasCosmosAttributes returns true in case one of the given responses contains the status attributes added by cosmos
+func hasCosmosAttributes(responses []interfaces.Response) bool {
+	for _, response := range responses {
+		for key := range response.Status.Attributes {
+			if strings.HasPrefix(key, cosmosAttributePrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// firstStringResult returns the first result of the given responses in case it is a string, empty otherwise
+func firstStringResult(responses []interfaces.Response) string {
+	for _, response := range responses {
+		var results []interface{}
+		if err := json.Unmarshal(response.Result.Data, &results); err != nil || len(results) == 0 {
+			continue
+		}
+		if result, ok := results[0].(string); ok {
+			return result
+		}
+	}
+	return ""
+}
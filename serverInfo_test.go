@@ -0,0 +1,180 @@
+package gremcos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supplyon/gremcos/api"
+	"github.com/supplyon/gremcos/interfaces"
+	"go.uber.org/goleak"
+)
+
+func TestDetectServerInfo_Cosmos(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl)
+
+	response := interfaces.Response{
+		Status: interfaces.Status{
+			Code: interfaces.StatusSuccess,
+			Attributes: map[string]interface{}{
+				"x-ms-status-code":          200,
+				"x-ms-total-request-charge": 0.1,
+			},
+		},
+		Result: interfaces.Result{Data: []byte("[0]")},
+	}
+	queryExecutor.EXPECT().Execute(serverProbeQuery).Return([]interfaces.Response{response}, nil)
+
+	// WHEN
+	info := cosmos.detectServerInfo()
+
+	// THEN
+	assert.Equal(t, ServerFlavourCosmosDB, info.Flavour)
+	assert.Equal(t, cosmosTinkerPopVersion, info.Version)
+	assert.Equal(t, api.QueryLanguageCosmosDB, info.QueryLanguage())
+	assert.Equal(t, MimeTypeGraphSONv2, info.MimeType())
+}
+
+func TestDetectServerInfo_TinkerPop(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl)
+
+	probeResponse := interfaces.Response{
+		Status: interfaces.Status{Code: interfaces.StatusSuccess, Attributes: map[string]interface{}{"host": "/127.0.0.1:53140"}},
+		Result: interfaces.Result{Data: []byte(`[{"@type":"g:Int32","@value":0}]`)},
+	}
+	versionResponse := interfaces.Response{
+		Status: interfaces.Status{Code: interfaces.StatusSuccess},
+		Result: interfaces.Result{Data: []byte(`["3.4.10"]`)},
+	}
+	gomock.InOrder(
+		queryExecutor.EXPECT().Execute(serverProbeQuery).Return([]interfaces.Response{probeResponse}, nil),
+		queryExecutor.EXPECT().Execute(serverVersionQuery).Return([]interfaces.Response{versionResponse}, nil),
+	)
+
+	// WHEN
+	info := cosmos.detectServerInfo()
+
+	// THEN
+	assert.Equal(t, ServerFlavourTinkerPop, info.Flavour)
+	assert.Equal(t, "3.4.10", info.Version)
+	assert.Equal(t, api.QueryLanguageTinkerpopGremlin, info.QueryLanguage())
+	assert.Equal(t, MimeTypeGraphSONv1, info.MimeType())
+}
+
+func TestDetectServerInfo_Failure(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl)
+
+	queryExecutor.EXPECT().Execute(serverProbeQuery).Return(nil, ErrNoConnection)
+
+	// WHEN
+	info := cosmos.detectServerInfo()
+
+	// THEN
+	assert.Equal(t, ServerFlavourUnknown, info.Flavour)
+	assert.Empty(t, info.Version)
+	assert.Equal(t, api.QueryLanguageCosmosDB, info.QueryLanguage())
+	assert.Equal(t, MimeTypeGraphSONv2, info.MimeType())
+}
+
+func TestDetectServerInfo_BypassesInterceptorsAndCache(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	intercepted := 0
+	interceptor := func(next ExecuteFunc) ExecuteFunc {
+		return func(request *Request) ([]interfaces.Response, error) {
+			intercepted++
+			return next(request)
+		}
+	}
+	cosmos, queryExecutor := newMockedCosmos(t, mockCtrl, WithInterceptors(interceptor))
+	cache := NewQueryCache(time.Minute, 10)
+	WithQueryCache(cache)(cosmos)
+	cosmos.interceptors = append(cosmos.interceptors, cache.intercept)
+	key, err := cacheKey(&Request{Query: serverProbeQuery})
+	require.NoError(t, err)
+	_, generation, _ := cache.get(key)
+	cache.put(key, successResponses("cached"), nil, generation)
+
+	queryExecutor.EXPECT().Execute(serverProbeQuery).Return(nil, ErrNoConnection)
+
+	// WHEN
+	info := cosmos.detectServerInfo()
+
+	// THEN
+	assert.Equal(t, ServerFlavourUnknown, info.Flavour)
+	assert.Equal(t, 0, intercepted)
+}
+
+func TestServerInfo_NotDetected(t *testing.T) {
+	// GIVEN
+	cosmos := cosmosImpl{}
+
+	// WHEN
+	info := cosmos.ServerInfo()
+
+	// THEN
+	assert.Equal(t, ServerFlavourUnknown, info.Flavour)
+}
+
+func TestDetectServerOption(t *testing.T) {
+	// GIVEN
+	cosmos := &cosmosImpl{}
+
+	// WHEN
+	DetectServer(true)(cosmos)
+
+	// THEN
+	assert.True(t, cosmos.detectServer)
+	assert.True(t, cosmos.configureSerializer)
+}
+
+func TestUseSerializer_SameMimeType(t *testing.T) {
+	// GIVEN
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	cosmos, _ := newMockedCosmos(t, mockCtrl)
+	cosmos.mimeType = MimeTypeGraphSONv1
+	pool := cosmos.pool
+
+	// WHEN
+	err := cosmos.useSerializer(MimeTypeGraphSONv1)
+
+	// THEN
+	assert.NoError(t, err)
+	assert.Equal(t, pool, cosmos.pool, "Expected the pool to be kept")
+}
+
+func TestDialUsesMimeType(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// GIVEN
+	cosmos, err := New("ws://host", withMetrics(newStubbedMetrics()), wsGenerator(websocketGenerator))
+	require.NoError(t, err)
+	cImpl := toCosmosImpl(t, cosmos)
+
+	// WHEN
+	defaultClient, err1 := cImpl.dial("")
+	graphSONv1Client, err2 := cImpl.dial(MimeTypeGraphSONv1)
+
+	// THEN
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	assert.Equal(t, MimeType, defaultClient.(*client).mimeType)
+	assert.Equal(t, []byte(MimeTypeGraphSONv1), graphSONv1Client.(*client).mimeType)
+	// Closing the QueryExecutors here because they are not pooled and would be ignored by cosmos.Stop
+	assert.NoError(t, defaultClient.Close())
+	assert.NoError(t, graphSONv1Client.Close())
+	assert.NoError(t, cosmos.Stop())
+}
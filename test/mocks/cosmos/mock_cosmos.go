@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	gremcos "github.com/supplyon/gremcos"
 	interfaces "github.com/supplyon/gremcos/interfaces"
 )
 
@@ -35,62 +36,102 @@ func (m *MockCosmos) EXPECT() *MockCosmosMockRecorder {
 }
 
 // Execute mocks base method.
-func (m *MockCosmos) Execute(query string) ([]interfaces.Response, error) {
+func (m *MockCosmos) Execute(query string, options ...gremcos.RequestOption) ([]interfaces.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Execute", query)
+	varargs := []interface{}{query}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Execute", varargs...)
 	ret0, _ := ret[0].([]interfaces.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Execute indicates an expected call of Execute.
-func (mr *MockCosmosMockRecorder) Execute(query interface{}) *gomock.Call {
+func (mr *MockCosmosMockRecorder) Execute(query interface{}, options ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Execute", reflect.TypeOf((*MockCosmos)(nil).Execute), query)
+	varargs := append([]interface{}{query}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Execute", reflect.TypeOf((*MockCosmos)(nil).Execute), varargs...)
 }
 
 // ExecuteAsync mocks base method.
-func (m *MockCosmos) ExecuteAsync(query string, responseChannel chan interfaces.AsyncResponse) error {
+func (m *MockCosmos) ExecuteAsync(query string, responseChannel chan interfaces.AsyncResponse, options ...gremcos.RequestOption) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ExecuteAsync", query, responseChannel)
+	varargs := []interface{}{query, responseChannel}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecuteAsync", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // ExecuteAsync indicates an expected call of ExecuteAsync.
-func (mr *MockCosmosMockRecorder) ExecuteAsync(query, responseChannel interface{}) *gomock.Call {
+func (mr *MockCosmosMockRecorder) ExecuteAsync(query, responseChannel interface{}, options ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteAsync", reflect.TypeOf((*MockCosmos)(nil).ExecuteAsync), query, responseChannel)
+	varargs := append([]interface{}{query, responseChannel}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteAsync", reflect.TypeOf((*MockCosmos)(nil).ExecuteAsync), varargs...)
 }
 
 // ExecuteQuery mocks base method.
-func (m *MockCosmos) ExecuteQuery(query interfaces.QueryBuilder) ([]interfaces.Response, error) {
+func (m *MockCosmos) ExecuteQuery(query interfaces.QueryBuilder, options ...gremcos.RequestOption) ([]interfaces.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ExecuteQuery", query)
+	varargs := []interface{}{query}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecuteQuery", varargs...)
 	ret0, _ := ret[0].([]interfaces.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ExecuteQuery indicates an expected call of ExecuteQuery.
-func (mr *MockCosmosMockRecorder) ExecuteQuery(query interface{}) *gomock.Call {
+func (mr *MockCosmosMockRecorder) ExecuteQuery(query interface{}, options ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteQuery", reflect.TypeOf((*MockCosmos)(nil).ExecuteQuery), query)
+	varargs := append([]interface{}{query}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteQuery", reflect.TypeOf((*MockCosmos)(nil).ExecuteQuery), varargs...)
+}
+
+// ExecuteQueryWithBindings mocks base method.
+func (m *MockCosmos) ExecuteQueryWithBindings(query interfaces.QueryBuilder, options ...gremcos.RequestOption) ([]interfaces.Response, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{query}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecuteQueryWithBindings", varargs...)
+	ret0, _ := ret[0].([]interfaces.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecuteQueryWithBindings indicates an expected call of ExecuteQueryWithBindings.
+func (mr *MockCosmosMockRecorder) ExecuteQueryWithBindings(query interface{}, options ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{query}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteQueryWithBindings", reflect.TypeOf((*MockCosmos)(nil).ExecuteQueryWithBindings), varargs...)
 }
 
 // ExecuteWithBindings mocks base method.
-func (m *MockCosmos) ExecuteWithBindings(path string, bindings, rebindings map[string]interface{}) ([]interfaces.Response, error) {
+func (m *MockCosmos) ExecuteWithBindings(path string, bindings, rebindings map[string]interface{}, options ...gremcos.RequestOption) ([]interfaces.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ExecuteWithBindings", path, bindings, rebindings)
+	varargs := []interface{}{path, bindings, rebindings}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecuteWithBindings", varargs...)
 	ret0, _ := ret[0].([]interfaces.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ExecuteWithBindings indicates an expected call of ExecuteWithBindings.
-func (mr *MockCosmosMockRecorder) ExecuteWithBindings(path, bindings, rebindings interface{}) *gomock.Call {
+func (mr *MockCosmosMockRecorder) ExecuteWithBindings(path, bindings, rebindings interface{}, options ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteWithBindings", reflect.TypeOf((*MockCosmos)(nil).ExecuteWithBindings), path, bindings, rebindings)
+	varargs := append([]interface{}{path, bindings, rebindings}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteWithBindings", reflect.TypeOf((*MockCosmos)(nil).ExecuteWithBindings), varargs...)
 }
 
 // IsConnected mocks base method.
@@ -121,6 +162,20 @@ func (mr *MockCosmosMockRecorder) IsHealthy() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsHealthy", reflect.TypeOf((*MockCosmos)(nil).IsHealthy))
 }
 
+// ServerInfo mocks base method.
+func (m *MockCosmos) ServerInfo() gremcos.ServerInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ServerInfo")
+	ret0, _ := ret[0].(gremcos.ServerInfo)
+	return ret0
+}
+
+// ServerInfo indicates an expected call of ServerInfo.
+func (mr *MockCosmosMockRecorder) ServerInfo() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ServerInfo", reflect.TypeOf((*MockCosmos)(nil).ServerInfo))
+}
+
 // Stop mocks base method.
 func (m *MockCosmos) Stop() error {
 	m.ctrl.T.Helper()
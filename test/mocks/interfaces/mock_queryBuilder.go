@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: interfaces/queryBuilder.go
+// Source: interfaces/querybuilder.go
 
 // Package mock_interfaces is a generated GoMock package.
 package mock_interfaces
@@ -49,6 +49,109 @@ func (mr *MockQueryBuilderMockRecorder) String() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockQueryBuilder)(nil).String))
 }
 
+// MockQueryBuilderWithBindings is a mock of QueryBuilderWithBindings interface.
+type MockQueryBuilderWithBindings struct {
+	ctrl     *gomock.Controller
+	recorder *MockQueryBuilderWithBindingsMockRecorder
+}
+
+// MockQueryBuilderWithBindingsMockRecorder is the mock recorder for MockQueryBuilderWithBindings.
+type MockQueryBuilderWithBindingsMockRecorder struct {
+	mock *MockQueryBuilderWithBindings
+}
+
+// NewMockQueryBuilderWithBindings creates a new mock instance.
+func NewMockQueryBuilderWithBindings(ctrl *gomock.Controller) *MockQueryBuilderWithBindings {
+	mock := &MockQueryBuilderWithBindings{ctrl: ctrl}
+	mock.recorder = &MockQueryBuilderWithBindingsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQueryBuilderWithBindings) EXPECT() *MockQueryBuilderWithBindingsMockRecorder {
+	return m.recorder
+}
+
+// String mocks base method.
+func (m *MockQueryBuilderWithBindings) String() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "String")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// String indicates an expected call of String.
+func (mr *MockQueryBuilderWithBindingsMockRecorder) String() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockQueryBuilderWithBindings)(nil).String))
+}
+
+// StringWithBindings mocks base method.
+func (m *MockQueryBuilderWithBindings) StringWithBindings() (string, map[string]interface{}) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StringWithBindings")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(map[string]interface{})
+	return ret0, ret1
+}
+
+// StringWithBindings indicates an expected call of StringWithBindings.
+func (mr *MockQueryBuilderWithBindingsMockRecorder) StringWithBindings() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StringWithBindings", reflect.TypeOf((*MockQueryBuilderWithBindings)(nil).StringWithBindings))
+}
+
+// MockPartitionFilterValidator is a mock of PartitionFilterValidator interface.
+type MockPartitionFilterValidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockPartitionFilterValidatorMockRecorder
+}
+
+// MockPartitionFilterValidatorMockRecorder is the mock recorder for MockPartitionFilterValidator.
+type MockPartitionFilterValidatorMockRecorder struct {
+	mock *MockPartitionFilterValidator
+}
+
+// NewMockPartitionFilterValidator creates a new mock instance.
+func NewMockPartitionFilterValidator(ctrl *gomock.Controller) *MockPartitionFilterValidator {
+	mock := &MockPartitionFilterValidator{ctrl: ctrl}
+	mock.recorder = &MockPartitionFilterValidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPartitionFilterValidator) EXPECT() *MockPartitionFilterValidatorMockRecorder {
+	return m.recorder
+}
+
+// String mocks base method.
+func (m *MockPartitionFilterValidator) String() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "String")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// String indicates an expected call of String.
+func (mr *MockPartitionFilterValidatorMockRecorder) String() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockPartitionFilterValidator)(nil).String))
+}
+
+// ValidatePartitionFilter mocks base method.
+func (m *MockPartitionFilterValidator) ValidatePartitionFilter() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidatePartitionFilter")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidatePartitionFilter indicates an expected call of ValidatePartitionFilter.
+func (mr *MockPartitionFilterValidatorMockRecorder) ValidatePartitionFilter() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidatePartitionFilter", reflect.TypeOf((*MockPartitionFilterValidator)(nil).ValidatePartitionFilter))
+}
+
 // MockGraph is a mock of Graph interface.
 type MockGraph struct {
 	ctrl     *gomock.Controller
@@ -72,6 +175,20 @@ func (m *MockGraph) EXPECT() *MockGraphMockRecorder {
 	return m.recorder
 }
 
+// AddEWithPartition mocks base method.
+func (m *MockGraph) AddEWithPartition(label, fromPartitionKey, fromID, toPartitionKey, toID string) interfaces.Edge {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddEWithPartition", label, fromPartitionKey, fromID, toPartitionKey, toID)
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// AddEWithPartition indicates an expected call of AddEWithPartition.
+func (mr *MockGraphMockRecorder) AddEWithPartition(label, fromPartitionKey, fromID, toPartitionKey, toID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddEWithPartition", reflect.TypeOf((*MockGraph)(nil).AddEWithPartition), label, fromPartitionKey, fromID, toPartitionKey, toID)
+}
+
 // AddV mocks base method.
 func (m *MockGraph) AddV(label string) interfaces.Vertex {
 	m.ctrl.T.Helper()
@@ -86,6 +203,21 @@ func (mr *MockGraphMockRecorder) AddV(label interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddV", reflect.TypeOf((*MockGraph)(nil).AddV), label)
 }
 
+// AddVWithPartition mocks base method.
+func (m *MockGraph) AddVWithPartition(label, partitionKey string) (interfaces.Vertex, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddVWithPartition", label, partitionKey)
+	ret0, _ := ret[0].(interfaces.Vertex)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddVWithPartition indicates an expected call of AddVWithPartition.
+func (mr *MockGraphMockRecorder) AddVWithPartition(label, partitionKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVWithPartition", reflect.TypeOf((*MockGraph)(nil).AddVWithPartition), label, partitionKey)
+}
+
 // E mocks base method.
 func (m *MockGraph) E() interfaces.Edge {
 	m.ctrl.T.Helper()
@@ -142,6 +274,20 @@ func (mr *MockGraphMockRecorder) VBy(id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VBy", reflect.TypeOf((*MockGraph)(nil).VBy), id)
 }
 
+// VByPartitionedID mocks base method.
+func (m *MockGraph) VByPartitionedID(partitionKey, id string) interfaces.Vertex {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VByPartitionedID", partitionKey, id)
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// VByPartitionedID indicates an expected call of VByPartitionedID.
+func (mr *MockGraphMockRecorder) VByPartitionedID(partitionKey, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VByPartitionedID", reflect.TypeOf((*MockGraph)(nil).VByPartitionedID), partitionKey, id)
+}
+
 // VByStr mocks base method.
 func (m *MockGraph) VByStr(id string) interfaces.Vertex {
 	m.ctrl.T.Helper()
@@ -170,6 +316,71 @@ func (mr *MockGraphMockRecorder) VByUUID(id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VByUUID", reflect.TypeOf((*MockGraph)(nil).VByUUID), id)
 }
 
+// MockValue is a mock of Value interface.
+type MockValue struct {
+	ctrl     *gomock.Controller
+	recorder *MockValueMockRecorder
+}
+
+// MockValueMockRecorder is the mock recorder for MockValue.
+type MockValueMockRecorder struct {
+	mock *MockValue
+}
+
+// NewMockValue creates a new mock instance.
+func NewMockValue(ctrl *gomock.Controller) *MockValue {
+	mock := &MockValue{ctrl: ctrl}
+	mock.recorder = &MockValueMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockValue) EXPECT() *MockValueMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockValue) Add(builder interfaces.QueryBuilder) interfaces.Value {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", builder)
+	ret0, _ := ret[0].(interfaces.Value)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockValueMockRecorder) Add(builder interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockValue)(nil).Add), builder)
+}
+
+// Fold mocks base method.
+func (m *MockValue) Fold() interfaces.Value {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fold")
+	ret0, _ := ret[0].(interfaces.Value)
+	return ret0
+}
+
+// Fold indicates an expected call of Fold.
+func (mr *MockValueMockRecorder) Fold() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fold", reflect.TypeOf((*MockValue)(nil).Fold))
+}
+
+// String mocks base method.
+func (m *MockValue) String() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "String")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// String indicates an expected call of String.
+func (mr *MockValueMockRecorder) String() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockValue)(nil).String))
+}
+
 // MockVertex is a mock of Vertex interface.
 type MockVertex struct {
 	ctrl     *gomock.Controller
@@ -249,6 +460,24 @@ func (mr *MockVertexMockRecorder) Aggregate(label interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Aggregate", reflect.TypeOf((*MockVertex)(nil).Aggregate), label)
 }
 
+// And mocks base method.
+func (m *MockVertex) And(builder ...interfaces.QueryBuilder) interfaces.Vertex {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range builder {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "And", varargs...)
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// And indicates an expected call of And.
+func (mr *MockVertexMockRecorder) And(builder ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "And", reflect.TypeOf((*MockVertex)(nil).And), builder...)
+}
+
 // As mocks base method.
 func (m *MockVertex) As(labels ...string) interfaces.Vertex {
 	m.ctrl.T.Helper()
@@ -281,6 +510,57 @@ func (mr *MockVertexMockRecorder) BothE() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BothE", reflect.TypeOf((*MockVertex)(nil).BothE))
 }
 
+// By mocks base method.
+func (m *MockVertex) By(builder ...interfaces.QueryBuilder) interfaces.Vertex {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range builder {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "By", varargs...)
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// By indicates an expected call of By.
+func (mr *MockVertexMockRecorder) By(builder ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "By", reflect.TypeOf((*MockVertex)(nil).By), builder...)
+}
+
+// ByOrder mocks base method.
+func (m *MockVertex) ByOrder(propertyName string, order ...interfaces.Order) interfaces.Vertex {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{propertyName}
+	for _, a := range order {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ByOrder", varargs...)
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// ByOrder indicates an expected call of ByOrder.
+func (mr *MockVertexMockRecorder) ByOrder(propertyName interface{}, order ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{propertyName}, order...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByOrder", reflect.TypeOf((*MockVertex)(nil).ByOrder), varargs...)
+}
+
+// Cap mocks base method.
+func (m *MockVertex) Cap(label string) interfaces.Vertex {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cap", label)
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// Cap indicates an expected call of Cap.
+func (mr *MockVertexMockRecorder) Cap(label interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cap", reflect.TypeOf((*MockVertex)(nil).Cap), label)
+}
+
 // Coalesce mocks base method.
 func (m *MockVertex) Coalesce(qb1, qb2 interfaces.QueryBuilder) interfaces.Vertex {
 	m.ctrl.T.Helper()
@@ -309,6 +589,20 @@ func (mr *MockVertexMockRecorder) Count() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockVertex)(nil).Count))
 }
 
+// Dedup mocks base method.
+func (m *MockVertex) Dedup() interfaces.Vertex {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dedup")
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// Dedup indicates an expected call of Dedup.
+func (mr *MockVertexMockRecorder) Dedup() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dedup", reflect.TypeOf((*MockVertex)(nil).Dedup))
+}
+
 // Drop mocks base method.
 func (m *MockVertex) Drop() interfaces.QueryBuilder {
 	m.ctrl.T.Helper()
@@ -416,6 +710,24 @@ func (mr *MockVertexMockRecorder) Id() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Id", reflect.TypeOf((*MockVertex)(nil).Id))
 }
 
+// In mocks base method.
+func (m *MockVertex) In(edgenames ...string) interfaces.Vertex {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range edgenames {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "In", varargs...)
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// In indicates an expected call of In.
+func (mr *MockVertexMockRecorder) In(edgenames ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "In", reflect.TypeOf((*MockVertex)(nil).In), edgenames...)
+}
+
 // InE mocks base method.
 func (m *MockVertex) InE(labels ...string) interfaces.Edge {
 	m.ctrl.T.Helper()
@@ -480,6 +792,38 @@ func (mr *MockVertexMockRecorder) Or(builder ...interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Or", reflect.TypeOf((*MockVertex)(nil).Or), builder...)
 }
 
+// Order mocks base method.
+func (m *MockVertex) Order() interfaces.Vertex {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Order")
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// Order indicates an expected call of Order.
+func (mr *MockVertexMockRecorder) Order() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Order", reflect.TypeOf((*MockVertex)(nil).Order))
+}
+
+// Out mocks base method.
+func (m *MockVertex) Out(edgenames ...string) interfaces.Vertex {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range edgenames {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Out", varargs...)
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// Out indicates an expected call of Out.
+func (mr *MockVertexMockRecorder) Out(edgenames ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Out", reflect.TypeOf((*MockVertex)(nil).Out), edgenames...)
+}
+
 // OutE mocks base method.
 func (m *MockVertex) OutE(labels ...string) interfaces.Edge {
 	m.ctrl.T.Helper()
@@ -512,6 +856,24 @@ func (mr *MockVertexMockRecorder) Profile() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Profile", reflect.TypeOf((*MockVertex)(nil).Profile))
 }
 
+// Project mocks base method.
+func (m *MockVertex) Project(labels ...string) interfaces.Vertex {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range labels {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Project", varargs...)
+	ret0, _ := ret[0].(interfaces.Vertex)
+	return ret0
+}
+
+// Project indicates an expected call of Project.
+func (mr *MockVertexMockRecorder) Project(labels ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Project", reflect.TypeOf((*MockVertex)(nil).Project), labels...)
+}
+
 // Properties mocks base method.
 func (m *MockVertex) Properties(key ...string) interfaces.Property {
 	m.ctrl.T.Helper()
@@ -647,10 +1009,10 @@ func (mr *MockVertexMockRecorder) Values() *gomock.Call {
 }
 
 // ValuesBy mocks base method.
-func (m *MockVertex) ValuesBy(label string) interfaces.QueryBuilder {
+func (m *MockVertex) ValuesBy(label string) interfaces.Value {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ValuesBy", label)
-	ret0, _ := ret[0].(interfaces.QueryBuilder)
+	ret0, _ := ret[0].(interfaces.Value)
 	return ret0
 }
 
@@ -725,6 +1087,24 @@ func (mr *MockEdgeMockRecorder) Aggregate(label interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Aggregate", reflect.TypeOf((*MockEdge)(nil).Aggregate), label)
 }
 
+// And mocks base method.
+func (m *MockEdge) And(builder ...interfaces.QueryBuilder) interfaces.Edge {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range builder {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "And", varargs...)
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// And indicates an expected call of And.
+func (mr *MockEdgeMockRecorder) And(builder ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "And", reflect.TypeOf((*MockEdge)(nil).And), builder...)
+}
+
 // As mocks base method.
 func (m *MockEdge) As(labels ...string) interfaces.Edge {
 	m.ctrl.T.Helper()
@@ -743,6 +1123,57 @@ func (mr *MockEdgeMockRecorder) As(labels ...interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "As", reflect.TypeOf((*MockEdge)(nil).As), labels...)
 }
 
+// By mocks base method.
+func (m *MockEdge) By(builder ...interfaces.QueryBuilder) interfaces.Edge {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range builder {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "By", varargs...)
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// By indicates an expected call of By.
+func (mr *MockEdgeMockRecorder) By(builder ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "By", reflect.TypeOf((*MockEdge)(nil).By), builder...)
+}
+
+// ByOrder mocks base method.
+func (m *MockEdge) ByOrder(propertyName string, order ...interfaces.Order) interfaces.Edge {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{propertyName}
+	for _, a := range order {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ByOrder", varargs...)
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// ByOrder indicates an expected call of ByOrder.
+func (mr *MockEdgeMockRecorder) ByOrder(propertyName interface{}, order ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{propertyName}, order...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByOrder", reflect.TypeOf((*MockEdge)(nil).ByOrder), varargs...)
+}
+
+// Cap mocks base method.
+func (m *MockEdge) Cap(label string) interfaces.Edge {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cap", label)
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// Cap indicates an expected call of Cap.
+func (mr *MockEdgeMockRecorder) Cap(label interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cap", reflect.TypeOf((*MockEdge)(nil).Cap), label)
+}
+
 // Coalesce mocks base method.
 func (m *MockEdge) Coalesce(qb1, qb2 interfaces.QueryBuilder) interfaces.Edge {
 	m.ctrl.T.Helper()
@@ -771,6 +1202,20 @@ func (mr *MockEdgeMockRecorder) Count() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockEdge)(nil).Count))
 }
 
+// Dedup mocks base method.
+func (m *MockEdge) Dedup() interfaces.Edge {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dedup")
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// Dedup indicates an expected call of Dedup.
+func (mr *MockEdgeMockRecorder) Dedup() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dedup", reflect.TypeOf((*MockEdge)(nil).Dedup))
+}
+
 // Drop mocks base method.
 func (m *MockEdge) Drop() interfaces.QueryBuilder {
 	m.ctrl.T.Helper()
@@ -827,6 +1272,25 @@ func (mr *MockEdgeMockRecorder) FromLbl(label interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FromLbl", reflect.TypeOf((*MockEdge)(nil).FromLbl), label)
 }
 
+// Has mocks base method.
+func (m *MockEdge) Has(key string, value ...interface{}) interfaces.Edge {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key}
+	for _, a := range value {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Has", varargs...)
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// Has indicates an expected call of Has.
+func (mr *MockEdgeMockRecorder) Has(key interface{}, value ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key}, value...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Has", reflect.TypeOf((*MockEdge)(nil).Has), varargs...)
+}
+
 // HasId mocks base method.
 func (m *MockEdge) HasId(id string) interfaces.Edge {
 	m.ctrl.T.Helper()
@@ -929,6 +1393,38 @@ func (mr *MockEdgeMockRecorder) Not(builder interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Not", reflect.TypeOf((*MockEdge)(nil).Not), builder)
 }
 
+// Or mocks base method.
+func (m *MockEdge) Or(builder ...interfaces.QueryBuilder) interfaces.Edge {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range builder {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Or", varargs...)
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// Or indicates an expected call of Or.
+func (mr *MockEdgeMockRecorder) Or(builder ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Or", reflect.TypeOf((*MockEdge)(nil).Or), builder...)
+}
+
+// Order mocks base method.
+func (m *MockEdge) Order() interfaces.Edge {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Order")
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// Order indicates an expected call of Order.
+func (mr *MockEdgeMockRecorder) Order() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Order", reflect.TypeOf((*MockEdge)(nil).Order))
+}
+
 // OutV mocks base method.
 func (m *MockEdge) OutV() interfaces.Vertex {
 	m.ctrl.T.Helper()
@@ -957,6 +1453,24 @@ func (mr *MockEdgeMockRecorder) Profile() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Profile", reflect.TypeOf((*MockEdge)(nil).Profile))
 }
 
+// Project mocks base method.
+func (m *MockEdge) Project(labels ...string) interfaces.Edge {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range labels {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Project", varargs...)
+	ret0, _ := ret[0].(interfaces.Edge)
+	return ret0
+}
+
+// Project indicates an expected call of Project.
+func (mr *MockEdgeMockRecorder) Project(labels ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Project", reflect.TypeOf((*MockEdge)(nil).Project), labels...)
+}
+
 // Property mocks base method.
 func (m *MockEdge) Property(key, value interface{}) interfaces.Edge {
 	m.ctrl.T.Helper()
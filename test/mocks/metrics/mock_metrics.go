@@ -221,3 +221,137 @@ func (mr *MockHistogramMockRecorder) Observe(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Observe", reflect.TypeOf((*MockHistogram)(nil).Observe), arg0)
 }
+
+// MockHistogramVec is a mock of HistogramVec interface.
+type MockHistogramVec struct {
+	ctrl     *gomock.Controller
+	recorder *MockHistogramVecMockRecorder
+}
+
+// MockHistogramVecMockRecorder is the mock recorder for MockHistogramVec.
+type MockHistogramVecMockRecorder struct {
+	mock *MockHistogramVec
+}
+
+// NewMockHistogramVec creates a new mock instance.
+func NewMockHistogramVec(ctrl *gomock.Controller) *MockHistogramVec {
+	mock := &MockHistogramVec{ctrl: ctrl}
+	mock.recorder = &MockHistogramVecMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHistogramVec) EXPECT() *MockHistogramVecMockRecorder {
+	return m.recorder
+}
+
+// WithLabelValues mocks base method.
+func (m *MockHistogramVec) WithLabelValues(lvs ...string) metrics.Histogram {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range lvs {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WithLabelValues", varargs...)
+	ret0, _ := ret[0].(metrics.Histogram)
+	return ret0
+}
+
+// WithLabelValues indicates an expected call of WithLabelValues.
+func (mr *MockHistogramVecMockRecorder) WithLabelValues(lvs ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithLabelValues", reflect.TypeOf((*MockHistogramVec)(nil).WithLabelValues), lvs...)
+}
+
+// MockFactory is a mock of Factory interface.
+type MockFactory struct {
+	ctrl     *gomock.Controller
+	recorder *MockFactoryMockRecorder
+}
+
+// MockFactoryMockRecorder is the mock recorder for MockFactory.
+type MockFactoryMockRecorder struct {
+	mock *MockFactory
+}
+
+// NewMockFactory creates a new mock instance.
+func NewMockFactory(ctrl *gomock.Controller) *MockFactory {
+	mock := &MockFactory{ctrl: ctrl}
+	mock.recorder = &MockFactoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFactory) EXPECT() *MockFactoryMockRecorder {
+	return m.recorder
+}
+
+// Counter mocks base method.
+func (m *MockFactory) Counter(name, help string) metrics.Counter {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Counter", name, help)
+	ret0, _ := ret[0].(metrics.Counter)
+	return ret0
+}
+
+// Counter indicates an expected call of Counter.
+func (mr *MockFactoryMockRecorder) Counter(name, help interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Counter", reflect.TypeOf((*MockFactory)(nil).Counter), name, help)
+}
+
+// CounterVec mocks base method.
+func (m *MockFactory) CounterVec(name, help string, labelNames []string) metrics.CounterVec {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CounterVec", name, help, labelNames)
+	ret0, _ := ret[0].(metrics.CounterVec)
+	return ret0
+}
+
+// CounterVec indicates an expected call of CounterVec.
+func (mr *MockFactoryMockRecorder) CounterVec(name, help, labelNames interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CounterVec", reflect.TypeOf((*MockFactory)(nil).CounterVec), name, help, labelNames)
+}
+
+// Gauge mocks base method.
+func (m *MockFactory) Gauge(name, help string) metrics.Gauge {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Gauge", name, help)
+	ret0, _ := ret[0].(metrics.Gauge)
+	return ret0
+}
+
+// Gauge indicates an expected call of Gauge.
+func (mr *MockFactoryMockRecorder) Gauge(name, help interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Gauge", reflect.TypeOf((*MockFactory)(nil).Gauge), name, help)
+}
+
+// Histogram mocks base method.
+func (m *MockFactory) Histogram(name, help string, buckets []float64) metrics.Histogram {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Histogram", name, help, buckets)
+	ret0, _ := ret[0].(metrics.Histogram)
+	return ret0
+}
+
+// Histogram indicates an expected call of Histogram.
+func (mr *MockFactoryMockRecorder) Histogram(name, help, buckets interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Histogram", reflect.TypeOf((*MockFactory)(nil).Histogram), name, help, buckets)
+}
+
+// HistogramVec mocks base method.
+func (m *MockFactory) HistogramVec(name, help string, buckets []float64, labelNames []string) metrics.HistogramVec {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HistogramVec", name, help, buckets, labelNames)
+	ret0, _ := ret[0].(metrics.HistogramVec)
+	return ret0
+}
+
+// HistogramVec indicates an expected call of HistogramVec.
+func (mr *MockFactoryMockRecorder) HistogramVec(name, help, buckets, labelNames interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HistogramVec", reflect.TypeOf((*MockFactory)(nil).HistogramVec), name, help, buckets, labelNames)
+}